@@ -0,0 +1,525 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"faucet-backend/auth"
+	"faucet-backend/bots"
+	"faucet-backend/config"
+	"faucet-backend/database"
+	"faucet-backend/events"
+	"faucet-backend/handlers"
+	"faucet-backend/metrics"
+	"faucet-backend/middleware"
+	"faucet-backend/notify"
+	"faucet-backend/services"
+	"faucet-backend/telemetry"
+	"faucet-backend/webhooks"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// Clients are the external connections the application runs against
+type Clients struct {
+	DB     *gorm.DB
+	Redis  *redis.Client
+	Wallet *services.Wallet
+	// Treasury signs refills; nil when TREASURY_PRIVATE_KEY isn't set
+	Treasury *services.Wallet
+}
+
+// Connect opens every client described by cfg, closing the ones already
+// opened if a later one fails
+func Connect(ctx context.Context, cfg *config.Config) (*Clients, error) {
+	wallet, err := services.NewWallet(ctx, cfg.RPCURL, cfg.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := database.Connect(cfg.DatabaseURL, cfg.DBLogLevel)
+	if err != nil {
+		wallet.Close()
+		return nil, err
+	}
+
+	rdb, err := database.ConnectRedis(ctx, cfg.RedisURL)
+	if err != nil {
+		wallet.Close()
+		closeDB(db)
+		return nil, err
+	}
+
+	clients := &Clients{DB: db, Redis: rdb, Wallet: wallet}
+	if cfg.TreasuryPrivateKey != "" {
+		if clients.Treasury, err = services.NewWallet(ctx, cfg.RPCURL, cfg.TreasuryPrivateKey); err != nil {
+			clients.Close()
+			return nil, fmt.Errorf("treasury: %w", err)
+		}
+	}
+	return clients, nil
+}
+
+// Close closes every client
+func (c *Clients) Close() error {
+	c.Wallet.Close()
+	if c.Treasury != nil {
+		c.Treasury.Close()
+	}
+	return errors.Join(c.Redis.Close(), closeDB(c.DB))
+}
+
+func closeDB(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// App is the wired faucet: clients, services, background workers and the
+// HTTP server
+type App struct {
+	Config  *config.Config
+	Clients *Clients
+	Events  *events.Bus
+
+	Drips    *services.DripService
+	Sender   *services.ChainSender
+	Limiter  *services.RedisLimiter
+	Captcha  *services.GotchaCaptcha
+	Amounts  *services.DripAmounts
+	Webhooks *webhooks.Dispatcher
+	Monitor  *services.BalanceMonitor
+	Metrics  *services.MetricsCollector
+	// TokenMetadata reads and caches what token contracts report
+	TokenMetadata *services.TokenMetadataService
+	// TokenListing caches GET /api/faucet/tokens
+	TokenListing *services.TokenListingCache
+	// Stats maintains the rollups behind timeseries stats
+	Stats *services.StatsRollup
+	// Treasury refills and sweeps the faucet wallet
+	Treasury *services.Treasury
+	// Bot is nil when no chat platform is configured
+	Bot *bots.Bot
+
+	HTTP *fiber.App
+}
+
+// New connects to Postgres, Redis and the RPC node, migrates and seeds the
+// database and wires the application
+func New(ctx context.Context, cfg *config.Config) (*App, error) {
+	clients, err := Connect(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := database.Migrate(ctx, clients.DB); err != nil {
+		clients.Close()
+		return nil, err
+	}
+
+	a := Build(cfg, clients)
+
+	// Tokens come from the config file when there is one, otherwise from
+	// the built-in seeds
+	if cfg.File != nil {
+		err = a.apply(ctx, cfg.File)
+	} else {
+		err = config.SeedTokens(clients.DB)
+	}
+	if err != nil {
+		clients.Close()
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Build wires the application on top of already-connected clients, so tests
+// can run it against stand-ins
+func Build(cfg *config.Config, clients *Clients) *App {
+	bus := events.NewBus()
+	policy := auth.Policy{
+		Required:      cfg.OAuth.Required,
+		MinAccountAge: cfg.OAuth.MinAccountAge,
+	}
+
+	limiter := &services.RedisLimiter{DB: clients.DB, Redis: clients.Redis}
+	captcha := &services.GotchaCaptcha{
+		SecretKey: cfg.CaptchaSecretKey,
+		VerifyURL: cfg.CaptchaVerifyURL,
+	}
+	sender := &services.ChainSender{DB: clients.DB, Wallet: clients.Wallet, Events: bus}
+	amounts := &services.DripAmounts{DB: clients.DB, Wallet: clients.Wallet}
+	drips := &services.DripService{
+		Store:   &services.GormDripStore{DB: clients.DB},
+		Limiter: limiter,
+		Captcha: captcha,
+		Sender:  sender,
+		Auth:    policy,
+		Events:  bus,
+		Metrics: services.PrometheusDripMetrics{},
+		Amounts: amounts,
+	}
+	dispatcher := &webhooks.Dispatcher{DB: clients.DB}
+	listing := &services.TokenListingCache{
+		DB:       clients.DB,
+		Redis:    clients.Redis,
+		Wallet:   clients.Wallet,
+		Amounts:  amounts,
+		Interval: cfg.TokenListingInterval,
+		TTL:      cfg.TokenListingTTL,
+	}
+	stats := &services.StatsRollup{DB: clients.DB, Interval: cfg.StatsRollupInterval}
+	treasury := &services.Treasury{DB: clients.DB, Wallet: clients.Wallet, Signer: clients.Treasury}
+
+	a := &App{
+		Config:   cfg,
+		Clients:  clients,
+		Events:   bus,
+		Drips:    drips,
+		Sender:   sender,
+		Limiter:  limiter,
+		Captcha:  captcha,
+		Amounts:  amounts,
+		Webhooks: dispatcher,
+		// Pause tokens that run dry and alert operators
+		Monitor: &services.BalanceMonitor{
+			DB:       clients.DB,
+			Wallet:   clients.Wallet,
+			Amounts:  amounts,
+			Interval: cfg.BalanceCheckInterval,
+			MinDrips: cfg.BalanceMinDrips,
+			Notifier: notify.Multi{notify.New(cfg.AlertWebhookURL), webhooks.AlertNotifier{Events: bus}},
+		},
+		Metrics: &services.MetricsCollector{
+			DB:       clients.DB,
+			Wallet:   clients.Wallet,
+			Interval: 30 * time.Second,
+		},
+		TokenMetadata: &services.TokenMetadataService{DB: clients.DB, Wallet: clients.Wallet},
+		TokenListing:  listing,
+		Stats:         stats,
+		Treasury:      treasury,
+	}
+
+	// Chat bot front-ends
+	var platforms []bots.Platform
+	if cfg.Bots.DiscordToken != "" {
+		platforms = append(platforms, bots.NewDiscord(bots.DiscordConfig{
+			Token:      cfg.Bots.DiscordToken,
+			GatewayURL: cfg.Bots.DiscordGatewayURL,
+			APIURL:     cfg.Bots.DiscordAPIURL,
+		}))
+	}
+	if cfg.Bots.TelegramToken != "" {
+		if cfg.OAuth.MinAccountAge > 0 {
+			slog.Warn("Telegram doesn't report account age, so the Telegram bot refuses drips while a minimum account age is set")
+		}
+		platforms = append(platforms, bots.NewTelegram(bots.TelegramConfig{
+			Token:  cfg.Bots.TelegramToken,
+			APIURL: cfg.Bots.TelegramAPIURL,
+		}))
+	}
+	if len(platforms) > 0 {
+		a.Bot = bots.New(drips, bus, platforms...)
+	}
+
+	var providers []auth.Provider
+	if cfg.OAuth.GitHubClientID != "" {
+		providers = append(providers, auth.NewGitHubProvider(auth.GitHubConfig{
+			ClientID:     cfg.OAuth.GitHubClientID,
+			ClientSecret: cfg.OAuth.GitHubClientSecret,
+			RedirectURL:  cfg.OAuth.GitHubRedirectURL,
+			OAuthBaseURL: cfg.OAuth.GitHubOAuthBaseURL,
+			APIBaseURL:   cfg.OAuth.GitHubAPIBaseURL,
+		}))
+	}
+	sessions := &auth.Sessions{DB: clients.DB, Redis: clients.Redis}
+
+	a.HTTP = newServer(cfg, sessions, routes{
+		faucet: &handlers.FaucetHandler{
+			DB:      clients.DB,
+			Wallet:  clients.Wallet,
+			Drips:   drips,
+			Limiter: limiter,
+			Listing: listing,
+			Stats:   stats,
+		},
+		auth: &handlers.AuthHandler{
+			Providers:          auth.NewProviders(providers...),
+			Policy:             policy,
+			Sessions:           sessions,
+			SuccessRedirectURL: cfg.OAuth.SuccessRedirectURL,
+		},
+		health: &handlers.HealthHandler{
+			Checker: &services.HealthChecker{
+				DB:         clients.DB,
+				Redis:      clients.Redis,
+				Wallet:     clients.Wallet,
+				ChainID:    cfg.ChainID,
+				MinBalance: cfg.MinFaucetBalance,
+			},
+			Wallet: clients.Wallet,
+		},
+		webhooks: &handlers.WebhookHandler{DB: clients.DB, Dispatcher: dispatcher},
+		export:   &handlers.ExportHandler{DB: clients.DB},
+		reports:  &handlers.ReportHandler{Stats: stats},
+		treasury: &handlers.TreasuryHandler{DB: clients.DB, Treasury: treasury},
+	})
+
+	return a
+}
+
+type routes struct {
+	faucet   *handlers.FaucetHandler
+	auth     *handlers.AuthHandler
+	health   *handlers.HealthHandler
+	webhooks *handlers.WebhookHandler
+	export   *handlers.ExportHandler
+	reports  *handlers.ReportHandler
+	treasury *handlers.TreasuryHandler
+}
+
+func newServer(cfg *config.Config, sessions *auth.Sessions, r routes) *fiber.App {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			code := fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				code = e.Code
+			}
+			return c.Status(code).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		},
+	})
+
+	// Middleware
+	app.Use(recover.New())
+	app.Use(middleware.RequestID())
+	app.Use(telemetry.Middleware())
+	app.Use(metrics.Middleware())
+	app.Use(middleware.AccessLog())
+	app.Use(middleware.CORS())
+
+	// Health checks
+	app.Get("/health", r.health.Health)
+	app.Get("/livez", r.health.Livez)
+	app.Get("/readyz", r.health.Readyz)
+
+	// API routes
+	api := app.Group("/api", middleware.Identity(sessions))
+
+	authRoutes := api.Group("/auth")
+	authRoutes.Get("/me", r.auth.GetIdentity)
+	authRoutes.Get("/:provider/login", r.auth.OAuthLogin)
+	authRoutes.Get("/:provider/callback", r.auth.OAuthCallback)
+
+	faucet := api.Group("/faucet")
+	faucet.Post("/drip", r.faucet.RequestDrip)
+	faucet.Get("/status/:address", r.faucet.GetStatus)
+	faucet.Get("/tokens", r.faucet.GetTokens)
+	faucet.Get("/bundles", r.faucet.GetBundles)
+	faucet.Get("/stats", r.faucet.GetStats)
+	faucet.Get("/stats/timeseries", r.faucet.GetStatsTimeseries)
+
+	admin := api.Group("/admin", middleware.AdminAuth(cfg.AdminAPIKey))
+	admin.Get("/webhooks", r.webhooks.ListWebhooks)
+	admin.Post("/webhooks", r.webhooks.CreateWebhook)
+	admin.Delete("/webhooks/:id", r.webhooks.DeleteWebhook)
+	admin.Get("/webhooks/deliveries", r.webhooks.ListWebhookDeliveries)
+	admin.Post("/webhooks/deliveries/replay", r.webhooks.ReplayFailedWebhookDeliveries)
+	admin.Post("/webhooks/deliveries/:id/replay", r.webhooks.ReplayWebhookDelivery)
+	admin.Get("/drips/export", r.export.ExportDrips)
+	admin.Get("/gas", r.reports.GetGasSpend)
+	admin.Get("/treasury/transfers", r.treasury.ListTransfers)
+	admin.Post("/treasury/check", r.treasury.Check)
+
+	return app
+}
+
+// Start runs the background workers until ctx is cancelled
+func (a *App) Start(ctx context.Context) {
+	// Pick up receipts left pending by the previous shutdown
+	if err := a.Sender.Resume(ctx); err != nil {
+		slog.Error("failed to resume receipt tracking", "error", err)
+	}
+
+	// Check configured tokens against their contracts in the background so a
+	// slow or flaky RPC doesn't hold up startup. Mismatches are logged and
+	// mint tokens the wallet can't mint are paused.
+	go func() {
+		mismatched, err := a.TokenMetadata.ValidateTokens(ctx)
+		if err != nil {
+			slog.Error("failed to validate token metadata", "error", err)
+		} else if mismatched > 0 {
+			slog.Warn("configured tokens don't match their contracts", "tokens", mismatched)
+		}
+	}()
+
+	a.Metrics.Start(ctx)
+	a.TokenListing.Start(ctx)
+	a.Stats.Start(ctx)
+
+	// Deliver drip lifecycle events to partner webhooks
+	a.Webhooks.Start(ctx, a.Events)
+
+	a.Monitor.Start(ctx)
+	a.Treasury.Start(ctx)
+
+	if a.Bot != nil {
+		a.Bot.Start(ctx)
+	}
+}
+
+// Listen serves the public API and, on its own port, Prometheus metrics.
+// It blocks until the HTTP server stops.
+func (a *App) Listen() error {
+	go metrics.Serve(a.Config.MetricsPort)
+
+	slog.Info("multi-token faucet backend starting", "port", a.Config.Port)
+	return a.HTTP.Listen(":" + a.Config.Port)
+}
+
+// Reload re-reads the config file and applies its tokens, limits and
+// CAPTCHA settings without a restart. An invalid file changes nothing.
+func (a *App) Reload(ctx context.Context) error {
+	if a.Config.ConfigFile == "" {
+		return errors.New("no config file to reload; FAUCET_CONFIG is not set")
+	}
+
+	file, err := config.LoadFile(a.Config.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	if file.Chain.ID != "" && file.Chain.ID != a.Config.ChainID {
+		slog.Warn("chain ID changed in config file; restart to apply", "running", a.Config.ChainID, "file", file.Chain.ID)
+	}
+	if err := a.apply(ctx, file); err != nil {
+		return err
+	}
+
+	go func() {
+		if err := a.TokenListing.Refresh(ctx); err != nil {
+			slog.Warn("failed to refresh token listing", "error", err)
+		}
+		if _, err := a.TokenMetadata.ValidateTokens(ctx); err != nil {
+			slog.Error("failed to validate token metadata", "error", err)
+		}
+	}()
+	return nil
+}
+
+// apply reconciles file's tokens into the database and swaps in its limits
+// and CAPTCHA settings
+func (a *App) apply(ctx context.Context, file *config.File) error {
+	policies, err := a.refillPolicies(file)
+	if err != nil {
+		return err
+	}
+
+	if _, err := config.ReconcileTokens(ctx, a.Clients.DB, file.TokenModels()); err != nil {
+		return err
+	}
+	if err := config.ReconcileInventory(ctx, a.Clients.DB, file.Inventories()); err != nil {
+		return err
+	}
+
+	a.Drips.SetBundles(file.Bundles)
+	a.Amounts.Configure(amountPolicies(file))
+	a.Limiter.SetLimits(services.Limits{
+		IPDaily:          file.Limits.IPDaily,
+		FingerprintDaily: file.Limits.FingerprintDaily,
+		IdentityDaily:    file.Limits.IdentityDaily,
+	})
+
+	verifyURL := file.Captcha.VerifyURL
+	if verifyURL == "" {
+		verifyURL = a.Config.CaptchaVerifyURL
+	}
+	a.Captcha.Configure(verifyURL, !file.CaptchaRequired())
+
+	var sweepTo common.Address
+	if file.Treasury.Address != "" {
+		sweepTo = common.HexToAddress(file.Treasury.Address)
+	}
+	a.Treasury.Configure(sweepTo, file.TreasuryInterval(), policies)
+	return nil
+}
+
+// refillPolicies converts file's refill settings, checking that the accounts
+// they need are configured
+func (a *App) refillPolicies(file *config.File) ([]services.RefillPolicy, error) {
+	var policies []services.RefillPolicy
+	for _, token := range file.Tokens {
+		refill := token.Refill
+		if refill == nil {
+			continue
+		}
+
+		source := refill.SourceOrDefault()
+		if source == config.RefillFromTreasury && a.Clients.Treasury == nil {
+			return nil, fmt.Errorf("token %s refills from the treasury but TREASURY_PRIVATE_KEY is not set", token.ID)
+		}
+		if refill.SweepAbove != "" && file.Treasury.Address == "" && a.Clients.Treasury == nil {
+			return nil, fmt.Errorf("token %s sweeps to the treasury but neither treasury.address nor TREASURY_PRIVATE_KEY is set", token.ID)
+		}
+
+		policy := services.RefillPolicy{TokenID: token.ID, Mint: source == config.RefillByMint}
+		policy.LowWater, policy.HighWater, policy.SweepAbove = token.RefillMarks()
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// amountPolicies converts file's drip amount settings
+func amountPolicies(file *config.File) []services.AmountPolicy {
+	var policies []services.AmountPolicy
+	for _, token := range file.Tokens {
+		if token.Amount.PolicyOrDefault() == config.AmountFixed {
+			continue
+		}
+
+		policy := services.AmountPolicy{
+			TokenID:       token.ID,
+			Kind:          token.Amount.Policy,
+			TargetPerHour: token.Amount.TargetPerHour,
+		}
+		policy.PartsPerMillion, policy.Min, policy.Max = token.AmountBounds()
+		policies = append(policies, policy)
+	}
+	return policies
+}
+
+// Shutdown stops accepting requests and waits, until ctx is done, for
+// in-flight requests, drip sends, treasury transfers and webhook deliveries
+// to finish. The ctx passed to Start must be cancelled first so the
+// background workers stop. Receipt tracking is checkpointed and resumed by
+// the next Start.
+func (a *App) Shutdown(ctx context.Context) error {
+	if err := a.HTTP.ShutdownWithContext(ctx); err != nil {
+		return fmt.Errorf("failed to stop HTTP server: %w", err)
+	}
+	if err := a.Sender.Drain(ctx); err != nil {
+		return err
+	}
+	if err := a.Treasury.Wait(ctx); err != nil {
+		return err
+	}
+	return a.Webhooks.Wait(ctx)
+}
+
+// Close releases the application's clients
+func (a *App) Close() error {
+	return a.Clients.Close()
+}
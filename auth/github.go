@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// GitHubConfig configures the GitHub OAuth app. The base URLs default to
+// github.com and can be pointed at a mock server.
+type GitHubConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	OAuthBaseURL string
+	APIBaseURL   string
+}
+
+type GitHubProvider struct {
+	cfg    GitHubConfig
+	client *http.Client
+}
+
+func NewGitHubProvider(cfg GitHubConfig) *GitHubProvider {
+	if cfg.OAuthBaseURL == "" {
+		cfg.OAuthBaseURL = "https://github.com"
+	}
+	if cfg.APIBaseURL == "" {
+		cfg.APIBaseURL = "https://api.github.com"
+	}
+	cfg.OAuthBaseURL = strings.TrimRight(cfg.OAuthBaseURL, "/")
+	cfg.APIBaseURL = strings.TrimRight(cfg.APIBaseURL, "/")
+
+	return &GitHubProvider{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+	}
+}
+
+func (g *GitHubProvider) Name() string {
+	return "github"
+}
+
+func (g *GitHubProvider) AuthCodeURL(state string) string {
+	params := url.Values{
+		"client_id": {g.cfg.ClientID},
+		"state":     {state},
+		"scope":     {"read:user"},
+	}
+	if g.cfg.RedirectURL != "" {
+		params.Set("redirect_uri", g.cfg.RedirectURL)
+	}
+
+	return g.cfg.OAuthBaseURL + "/login/oauth/authorize?" + params.Encode()
+}
+
+func (g *GitHubProvider) Exchange(ctx context.Context, code string) (*Identity, error) {
+	accessToken, err := g.exchangeCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.cfg.APIBaseURL+"/user", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("github user request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("github user request returned %d", resp.StatusCode)
+	}
+
+	var user struct {
+		ID        int64     `json:"id"`
+		Login     string    `json:"login"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, fmt.Errorf("failed to parse github user: %w", err)
+	}
+
+	if user.ID == 0 {
+		return nil, errors.New("github user response has no id")
+	}
+
+	return &Identity{
+		Provider:         g.Name(),
+		ProviderUserID:   strconv.FormatInt(user.ID, 10),
+		Login:            user.Login,
+		AccountCreatedAt: user.CreatedAt,
+	}, nil
+}
+
+func (g *GitHubProvider) exchangeCode(ctx context.Context, code string) (string, error) {
+	form := url.Values{
+		"client_id":     {g.cfg.ClientID},
+		"client_secret": {g.cfg.ClientSecret},
+		"code":          {code},
+	}
+	if g.cfg.RedirectURL != "" {
+		form.Set("redirect_uri", g.cfg.RedirectURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		g.cfg.OAuthBaseURL+"/login/oauth/access_token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("github token exchange failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("github token exchange returned %d", resp.StatusCode)
+	}
+
+	var tokenResp struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", fmt.Errorf("failed to parse github token response: %w", err)
+	}
+
+	if tokenResp.Error != "" {
+		return "", fmt.Errorf("github token exchange failed: %s", tokenResp.ErrorDescription)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", errors.New("github token exchange returned no access token")
+	}
+
+	return tokenResp.AccessToken, nil
+}
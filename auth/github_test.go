@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newGitHubServer fakes GitHub's token endpoint and user API. Each code
+// maps to the access token it's exchanged for; "broken" makes the token
+// endpoint fail and "user-down" hands out a token the user API fails for.
+func newGitHubServer(t *testing.T, created time.Time) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.FormValue("client_id") != "client" || r.FormValue("client_secret") != "secret" {
+			http.Error(w, "bad client", http.StatusUnauthorized)
+			return
+		}
+		switch r.FormValue("code") {
+		case "good":
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "token-good"})
+		case "user-down":
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "token-down"})
+		case "broken":
+			http.Error(w, "<html>unicorn</html>", http.StatusBadGateway)
+		default:
+			json.NewEncoder(w).Encode(map[string]string{
+				"error":             "bad_verification_code",
+				"error_description": "The code passed is incorrect or expired.",
+			})
+		}
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer token-good":
+			json.NewEncoder(w).Encode(map[string]any{"id": 42, "login": "octocat", "created_at": created})
+		case "Bearer token-down":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			http.Error(w, "bad credentials", http.StatusUnauthorized)
+		}
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGitHubExchange(t *testing.T) {
+	created := time.Date(2015, 3, 1, 12, 0, 0, 0, time.UTC)
+	srv := newGitHubServer(t, created)
+	provider := NewGitHubProvider(GitHubConfig{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		OAuthBaseURL: srv.URL,
+		APIBaseURL:   srv.URL,
+	})
+
+	identity, err := provider.Exchange(context.Background(), "good")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Identity{Provider: "github", ProviderUserID: "42", Login: "octocat", AccountCreatedAt: created}
+	if *identity != want {
+		t.Errorf("identity = %+v, want %+v", *identity, want)
+	}
+	if identity.Key() != "github:42" {
+		t.Errorf("key = %q, want github:42", identity.Key())
+	}
+
+	tests := []struct {
+		code    string
+		wantErr string
+	}{
+		{"expired", "code passed is incorrect"},
+		{"broken", "returned 502"},
+		{"user-down", "returned 503"},
+	}
+	for _, tt := range tests {
+		if _, err := provider.Exchange(context.Background(), tt.code); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Exchange(%q) error = %v, want %q", tt.code, err, tt.wantErr)
+		}
+	}
+}
+
+func TestGitHubAuthCodeURL(t *testing.T) {
+	provider := NewGitHubProvider(GitHubConfig{
+		ClientID:     "client",
+		RedirectURL:  "https://faucet.example/callback",
+		OAuthBaseURL: "https://github.example/",
+	})
+
+	u, err := url.Parse(provider.AuthCodeURL("xyz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Host != "github.example" || u.Path != "/login/oauth/authorize" {
+		t.Errorf("authorize URL = %s", u)
+	}
+	query := u.Query()
+	if query.Get("client_id") != "client" || query.Get("state") != "xyz" || query.Get("redirect_uri") != "https://faucet.example/callback" {
+		t.Errorf("authorize query = %v", query)
+	}
+}
+
+func TestCheckAccountAge(t *testing.T) {
+	policy := Policy{MinAccountAge: 30 * 24 * time.Hour}
+
+	old := &Identity{Provider: "github", AccountCreatedAt: time.Now().Add(-31 * 24 * time.Hour)}
+	if err := policy.CheckAccountAge(old); err != nil {
+		t.Errorf("31-day-old account: %v", err)
+	}
+
+	young := &Identity{Provider: "github", AccountCreatedAt: time.Now().Add(-24 * time.Hour)}
+	if err := policy.CheckAccountAge(young); !errors.Is(err, ErrAccountTooYoung) {
+		t.Errorf("1-day-old account: err = %v, want ErrAccountTooYoung", err)
+	}
+
+	unknown := &Identity{Provider: "telegram"}
+	if err := policy.CheckAccountAge(unknown); !errors.Is(err, ErrAccountAgeUnknown) {
+		t.Errorf("unknown account age: err = %v, want ErrAccountAgeUnknown", err)
+	}
+
+	for _, identity := range []*Identity{young, unknown} {
+		if err := (Policy{}).CheckAccountAge(identity); err != nil {
+			t.Errorf("no minimum: %v", err)
+		}
+	}
+}
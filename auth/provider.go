@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Identity is the account information an OAuth provider returns after login
+type Identity struct {
+	Provider         string    `json:"provider"`
+	ProviderUserID   string    `json:"providerUserId"`
+	Login            string    `json:"login"`
+	AccountCreatedAt time.Time `json:"accountCreatedAt"`
+}
+
+// Key is the provider-qualified identifier used for drips and quotas
+func (i Identity) Key() string {
+	return i.Provider + ":" + i.ProviderUserID
+}
+
+// Provider is an OAuth2 authorization-code provider
+type Provider interface {
+	Name() string
+	AuthCodeURL(state string) string
+	Exchange(ctx context.Context, code string) (*Identity, error)
+}
+
+var (
+	ErrUnknownProvider = errors.New("unknown OAuth provider")
+	ErrAccountTooYoung = errors.New("account is too new")
+	// ErrAccountAgeUnknown rejects identities from platforms that don't
+	// report account age while a minimum age is required
+	ErrAccountAgeUnknown = errors.New("account age is unknown")
+)
+
+// Providers holds the enabled OAuth providers by name
+type Providers map[string]Provider
+
+// NewProviders registers each provider under its name
+func NewProviders(list ...Provider) Providers {
+	providers := make(Providers, len(list))
+	for _, p := range list {
+		providers[p.Name()] = p
+		slog.Info("OAuth provider enabled", "provider", p.Name())
+	}
+	return providers
+}
+
+// Get looks up an enabled provider
+func (p Providers) Get(name string) (Provider, error) {
+	provider, ok := p[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return provider, nil
+}
+
+// Policy decides which identities may request drips
+type Policy struct {
+	// Required makes drips require a logged-in identity
+	Required bool
+	// MinAccountAge rejects younger accounts; zero disables the check
+	MinAccountAge time.Duration
+}
+
+// CheckAccountAge rejects identities younger than the configured minimum
+// age, and identities whose age is unknown while there is a minimum
+func (p Policy) CheckAccountAge(identity *Identity) error {
+	if p.MinAccountAge <= 0 {
+		return nil
+	}
+
+	if identity.AccountCreatedAt.IsZero() {
+		return fmt.Errorf("%w: %s accounts must be at least %d days old and %s doesn't report account age",
+			ErrAccountAgeUnknown, identity.Provider, int(p.MinAccountAge.Hours()/24), identity.Provider)
+	}
+
+	age := time.Since(identity.AccountCreatedAt)
+	if age < p.MinAccountAge {
+		return fmt.Errorf("%w: %s accounts must be at least %d days old",
+			ErrAccountTooYoung, identity.Provider, int(p.MinAccountAge.Hours()/24))
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"faucet-backend/models"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	stateTTL   = 10 * time.Minute
+	sessionTTL = 7 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidState   = errors.New("invalid or expired OAuth state")
+	ErrInvalidSession = errors.New("invalid or expired session")
+)
+
+// Sessions stores OAuth state in Redis, identities in Postgres and bearer
+// sessions in Redis
+type Sessions struct {
+	DB    *gorm.DB
+	Redis *redis.Client
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// NewState creates a single-use CSRF state bound to a provider
+func (s *Sessions) NewState(ctx context.Context, provider string) (string, error) {
+	state, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	key := fmt.Sprintf("faucet:oauth:state:%s", state)
+	if err := s.Redis.Set(ctx, key, provider, stateTTL).Err(); err != nil {
+		return "", err
+	}
+
+	return state, nil
+}
+
+// ConsumeState validates and deletes a state created by NewState
+func (s *Sessions) ConsumeState(ctx context.Context, provider, state string) error {
+	key := fmt.Sprintf("faucet:oauth:state:%s", state)
+	stored, err := s.Redis.GetDel(ctx, key).Result()
+	if err == redis.Nil || (err == nil && stored != provider) {
+		return ErrInvalidState
+	}
+	return err
+}
+
+// Create persists the identity and returns a bearer token for it
+func (s *Sessions) Create(ctx context.Context, identity *Identity) (string, error) {
+	record := models.Identity{
+		ID:               identity.Key(),
+		Provider:         identity.Provider,
+		ProviderUserID:   identity.ProviderUserID,
+		Login:            identity.Login,
+		AccountCreatedAt: identity.AccountCreatedAt,
+		LastLoginAt:      time.Now(),
+	}
+
+	if err := s.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"login", "account_created_at", "last_login_at", "updated_at"}),
+	}).Create(&record).Error; err != nil {
+		return "", fmt.Errorf("failed to save identity: %w", err)
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(identity)
+	if err != nil {
+		return "", err
+	}
+
+	key := fmt.Sprintf("faucet:session:%s", token)
+	if err := s.Redis.Set(ctx, key, payload, sessionTTL).Err(); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Get resolves a bearer token to the identity it was issued for
+func (s *Sessions) Get(ctx context.Context, token string) (*Identity, error) {
+	key := fmt.Sprintf("faucet:session:%s", token)
+	payload, err := s.Redis.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrInvalidSession
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var identity Identity
+	if err := json.Unmarshal(payload, &identity); err != nil {
+		return nil, ErrInvalidSession
+	}
+
+	return &identity, nil
+}
@@ -0,0 +1,271 @@
+package bots
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"faucet-backend/auth"
+	"faucet-backend/events"
+	"faucet-backend/models"
+	"faucet-backend/services"
+)
+
+// Message is an incoming chat message from any platform
+type Message struct {
+	Platform  string
+	UserID    string
+	ChatID    string
+	MessageID string
+	Text      string
+	// AccountCreatedAt is when the sender's account was created, if the
+	// platform exposes it
+	AccountCreatedAt time.Time
+}
+
+// Platform is a chat front-end the faucet bot runs on
+type Platform interface {
+	Name() string
+	// Run receives messages and passes them to handle until ctx is cancelled
+	Run(ctx context.Context, handle func(context.Context, Message)) error
+	// Reply sends text to the chat, threaded under replyTo when supported
+	Reply(ctx context.Context, chatID, replyTo, text string) error
+}
+
+const usage = "Usage: /faucet <address> <token>"
+
+// pendingTTL is how long the bot waits for a drip's outcome before it stops
+// tracking the reply. Drips that time out, are drained or settle on another
+// replica never publish an outcome here.
+const pendingTTL = time.Hour
+
+// Bot turns /faucet commands into drips, applying the same eligibility and
+// rate-limit checks as the HTTP API keyed by the platform user, and replies
+// with the tx hash once the receipt confirms
+type Bot struct {
+	drips     *services.DripService
+	events    *events.Bus
+	platforms []Platform
+
+	mu      sync.Mutex
+	pending map[uint]replyTarget
+}
+
+type replyTarget struct {
+	platform  Platform
+	chatID    string
+	messageID string
+	symbol    string
+	createdAt time.Time
+}
+
+func New(drips *services.DripService, bus *events.Bus, platforms ...Platform) *Bot {
+	return &Bot{
+		drips:     drips,
+		events:    bus,
+		platforms: platforms,
+		pending:   make(map[uint]replyTarget),
+	}
+}
+
+// Start runs every platform in the background and subscribes to drip
+// outcomes so confirmations can be reported back
+func (b *Bot) Start(ctx context.Context) {
+	b.events.Subscribe(b.onEvent)
+	go b.sweep(ctx)
+
+	for _, p := range b.platforms {
+		go func(p Platform) {
+			for {
+				err := p.Run(ctx, func(ctx context.Context, msg Message) {
+					b.handle(ctx, p, msg)
+				})
+				if ctx.Err() != nil {
+					return
+				}
+
+				slog.Error("bot platform disconnected, reconnecting", "platform", p.Name(), "error", err)
+				time.Sleep(5 * time.Second)
+			}
+		}(p)
+	}
+}
+
+func (b *Bot) handle(ctx context.Context, p Platform, msg Message) {
+	address, tokenID, ok := parseCommand(msg.Text)
+	if !ok {
+		return
+	}
+
+	reply := func(text string) {
+		if err := p.Reply(ctx, msg.ChatID, msg.MessageID, text); err != nil {
+			slog.Error("bot reply failed", "platform", p.Name(), "error", err)
+		}
+	}
+
+	if address == "" || tokenID == "" {
+		reply(usage)
+		return
+	}
+
+	drip, token, err := b.requestDrip(ctx, msg, address, tokenID)
+	if err != nil {
+		reply(err.Error())
+		return
+	}
+
+	b.mu.Lock()
+	b.pending[drip.ID] = replyTarget{platform: p, chatID: msg.ChatID, messageID: msg.MessageID, symbol: token.Symbol, createdAt: time.Now()}
+	b.mu.Unlock()
+
+	reply(fmt.Sprintf("Sending %s %s to %s, I'll reply here when it confirms.", drip.Amount, token.Symbol, drip.Recipient))
+}
+
+// replyError is an error whose text is meant for the chat user
+type replyError string
+
+func (e replyError) Error() string {
+	return string(e)
+}
+
+const (
+	errInvalidAddress replyError = "That isn't a valid address. " + usage
+	errUnknownToken   replyError = "Unknown or inactive token."
+	errLoginRequired  replyError = "Please log in on the faucet website to request tokens."
+	errInternal       replyError = "Something went wrong, please try again later."
+)
+
+// requestDrip runs the shared drip pipeline, with the platform user ID
+// standing in for the IP, fingerprint and CAPTCHA. Chat users may name the
+// token by symbol.
+func (b *Bot) requestDrip(ctx context.Context, msg Message, address, tokenID string) (*models.Drip, *models.Token, error) {
+	// Platforms that don't expose account age leave AccountCreatedAt zero,
+	// which fails the age check whenever a minimum age is required
+	result, err := b.drips.Request(ctx, services.DripRequest{
+		Address: address,
+		TokenID: tokenID,
+		Identity: &auth.Identity{
+			Provider:         msg.Platform,
+			ProviderUserID:   msg.UserID,
+			AccountCreatedAt: msg.AccountCreatedAt,
+		},
+		SkipCaptcha: true,
+		AllowSymbol: true,
+	})
+	if err != nil {
+		var paused *services.TokenPausedError
+		var ambiguous *services.AmbiguousTokenError
+		var limited *services.RateLimitError
+
+		switch {
+		case errors.Is(err, services.ErrInvalidAddress):
+			return nil, nil, errInvalidAddress
+		case errors.Is(err, services.ErrTokenUnavailable):
+			return nil, nil, errUnknownToken
+		case errors.As(err, &paused):
+			return nil, nil, replyError(paused.Error() + ".")
+		case errors.As(err, &ambiguous):
+			return nil, nil, replyError(ambiguous.Error() + ".")
+		case errors.Is(err, auth.ErrAccountTooYoung):
+			return nil, nil, replyError("Sorry, your " + msg.Platform + " account is too new to use the faucet.")
+		case errors.Is(err, auth.ErrAccountAgeUnknown):
+			return nil, nil, replyError("Sorry, " + msg.Platform + " doesn't share account age, which the faucet requires. Please use the faucet website.")
+		case errors.Is(err, services.ErrLoginRequired):
+			return nil, nil, errLoginRequired
+		case errors.As(err, &limited):
+			return nil, nil, replyError(limited.Reason)
+		default:
+			slog.Error("bot drip request failed", "platform", msg.Platform, "token", tokenID, "recipient", address, "error", err)
+			return nil, nil, errInternal
+		}
+	}
+
+	return result.Drip, result.Token, nil
+}
+
+// sweep drops pending replies older than pendingTTL until ctx is cancelled
+func (b *Bot) sweep(ctx context.Context) {
+	ticker := time.NewTicker(pendingTTL / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			b.expire(now)
+		}
+	}
+}
+
+func (b *Bot) expire(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id, target := range b.pending {
+		if now.Sub(target.createdAt) > pendingTTL {
+			delete(b.pending, id)
+		}
+	}
+}
+
+func (b *Bot) onEvent(ctx context.Context, event events.Event) {
+	data, ok := event.Data.(events.DripData)
+	if !ok {
+		return
+	}
+	// Any event carrying a finished drip ends tracking, whichever path
+	// finished it
+	terminal := data.Status == "completed" || data.Status == "failed"
+	if event.Type != events.DripConfirmed && event.Type != events.DripFailed && !terminal {
+		return
+	}
+
+	b.mu.Lock()
+	target, ok := b.pending[data.ID]
+	delete(b.pending, data.ID)
+	b.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	text := fmt.Sprintf("Your drip of %s %s confirmed: %s", data.Amount, target.symbol, data.TxHash)
+	if event.Type == events.DripFailed || data.Status == "failed" {
+		text = "Sorry, your drip failed. Please try again later."
+		if data.TxHash != "" {
+			text = fmt.Sprintf("Sorry, your drip transaction failed: %s", data.TxHash)
+		}
+	}
+
+	// Reply off the publisher's goroutine so a slow platform can't stall it
+	go func() {
+		if err := target.platform.Reply(context.WithoutCancel(ctx), target.chatID, target.messageID, text); err != nil {
+			slog.Error("bot reply failed", "platform", target.platform.Name(), "drip_id", data.ID, "error", err)
+		}
+	}()
+}
+
+// parseCommand recognises "/faucet <address> <token>" (and Telegram's
+// "/faucet@BotName" form). ok is false for messages that aren't the command.
+func parseCommand(text string) (address, tokenID string, ok bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", "", false
+	}
+
+	command, _, _ := strings.Cut(fields[0], "@")
+	if !strings.EqualFold(command, "/faucet") {
+		return "", "", false
+	}
+
+	if len(fields) > 1 {
+		address = fields[1]
+	}
+	if len(fields) > 2 {
+		tokenID = fields[2]
+	}
+	return address, tokenID, true
+}
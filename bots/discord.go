@@ -0,0 +1,232 @@
+package bots
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Gateway opcodes and intents used by the bot
+const (
+	discordOpDispatch       = 0
+	discordOpHeartbeat      = 1
+	discordOpIdentify       = 2
+	discordOpReconnect      = 7
+	discordOpInvalidSession = 9
+	discordOpHello          = 10
+
+	discordIntents = 1<<9 | 1<<12 | 1<<15 // GUILD_MESSAGES, DIRECT_MESSAGES, MESSAGE_CONTENT
+
+	// Discord snowflakes count milliseconds from the start of 2015
+	discordEpochMs = 1420070400000
+)
+
+// DiscordConfig configures the Discord bot. The URLs default to Discord's
+// and can be pointed at a fake gateway/API server.
+type DiscordConfig struct {
+	Token      string
+	GatewayURL string
+	APIURL     string
+}
+
+type Discord struct {
+	cfg    DiscordConfig
+	client *http.Client
+}
+
+func NewDiscord(cfg DiscordConfig) *Discord {
+	if cfg.GatewayURL == "" {
+		cfg.GatewayURL = "wss://gateway.discord.gg/?v=10&encoding=json"
+	}
+	if cfg.APIURL == "" {
+		cfg.APIURL = "https://discord.com/api/v10"
+	}
+	cfg.APIURL = strings.TrimRight(cfg.APIURL, "/")
+
+	return &Discord{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (d *Discord) Name() string {
+	return "discord"
+}
+
+type discordPayload struct {
+	Op       int             `json:"op"`
+	Data     json.RawMessage `json:"d,omitempty"`
+	Sequence *int64          `json:"s,omitempty"`
+	Type     string          `json:"t,omitempty"`
+}
+
+type discordMessage struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	Content   string `json:"content"`
+	Author    struct {
+		ID  string `json:"id"`
+		Bot bool   `json:"bot"`
+	} `json:"author"`
+}
+
+// Run connects to the gateway, identifies and dispatches MESSAGE_CREATE
+// events until the connection drops or ctx is cancelled
+func (d *Discord) Run(ctx context.Context, handle func(context.Context, Message)) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, d.cfg.GatewayURL, nil)
+	if err != nil {
+		return fmt.Errorf("discord gateway dial failed: %w", err)
+	}
+	defer conn.Close()
+
+	// Close the connection when ctx is cancelled to unblock ReadJSON
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	var writeMu sync.Mutex
+	send := func(payload any) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteJSON(payload)
+	}
+
+	var sequence *int64
+	var seqMu sync.Mutex
+	heartbeatDone := make(chan struct{})
+	defer close(heartbeatDone)
+
+	for {
+		var payload discordPayload
+		if err := conn.ReadJSON(&payload); err != nil {
+			return fmt.Errorf("discord gateway read failed: %w", err)
+		}
+
+		if payload.Sequence != nil {
+			seqMu.Lock()
+			sequence = payload.Sequence
+			seqMu.Unlock()
+		}
+
+		switch payload.Op {
+		case discordOpHello:
+			var hello struct {
+				HeartbeatInterval int64 `json:"heartbeat_interval"`
+			}
+			if err := json.Unmarshal(payload.Data, &hello); err != nil {
+				return err
+			}
+
+			go func() {
+				ticker := time.NewTicker(time.Duration(hello.HeartbeatInterval) * time.Millisecond)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-heartbeatDone:
+						return
+					case <-ticker.C:
+						seqMu.Lock()
+						seq := sequence
+						seqMu.Unlock()
+						if err := send(map[string]any{"op": discordOpHeartbeat, "d": seq}); err != nil {
+							return
+						}
+					}
+				}
+			}()
+
+			if err := send(map[string]any{
+				"op": discordOpIdentify,
+				"d": map[string]any{
+					"token":   d.cfg.Token,
+					"intents": discordIntents,
+					"properties": map[string]string{
+						"os":      "linux",
+						"browser": "faucet-backend",
+						"device":  "faucet-backend",
+					},
+				},
+			}); err != nil {
+				return err
+			}
+
+		case discordOpHeartbeat:
+			seqMu.Lock()
+			seq := sequence
+			seqMu.Unlock()
+			if err := send(map[string]any{"op": discordOpHeartbeat, "d": seq}); err != nil {
+				return err
+			}
+
+		case discordOpReconnect, discordOpInvalidSession:
+			return errors.New("discord gateway requested reconnect")
+
+		case discordOpDispatch:
+			if payload.Type != "MESSAGE_CREATE" {
+				continue
+			}
+
+			var msg discordMessage
+			if err := json.Unmarshal(payload.Data, &msg); err != nil || msg.Author.Bot {
+				continue
+			}
+
+			handle(ctx, Message{
+				Platform:         d.Name(),
+				UserID:           msg.Author.ID,
+				ChatID:           msg.ChannelID,
+				MessageID:        msg.ID,
+				Text:             msg.Content,
+				AccountCreatedAt: snowflakeTime(msg.Author.ID),
+			})
+		}
+	}
+}
+
+func (d *Discord) Reply(ctx context.Context, chatID, replyTo, text string) error {
+	body := map[string]any{"content": text}
+	if replyTo != "" {
+		body["message_reference"] = map[string]string{"message_id": replyTo}
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		d.cfg.APIURL+"/channels/"+chatID+"/messages", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+d.cfg.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("discord create message returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// snowflakeTime extracts the creation time encoded in a Discord ID
+func snowflakeTime(id string) time.Time {
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(n>>22) + discordEpochMs)
+}
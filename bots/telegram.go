@@ -0,0 +1,143 @@
+package bots
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TelegramConfig configures the Telegram bot. APIURL defaults to
+// api.telegram.org and can be pointed at a fake Bot API server.
+type TelegramConfig struct {
+	Token  string
+	APIURL string
+}
+
+type Telegram struct {
+	cfg    TelegramConfig
+	client *http.Client
+	offset int64
+}
+
+const telegramPollTimeout = 30 // seconds
+
+func NewTelegram(cfg TelegramConfig) *Telegram {
+	if cfg.APIURL == "" {
+		cfg.APIURL = "https://api.telegram.org"
+	}
+	cfg.APIURL = strings.TrimRight(cfg.APIURL, "/")
+
+	return &Telegram{
+		cfg:    cfg,
+		client: &http.Client{Timeout: (telegramPollTimeout + 10) * time.Second},
+	}
+}
+
+func (t *Telegram) Name() string {
+	return "telegram"
+}
+
+type telegramResponse struct {
+	OK          bool            `json:"ok"`
+	Description string          `json:"description"`
+	Result      json.RawMessage `json:"result"`
+}
+
+type telegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		MessageID int64  `json:"message_id"`
+		Text      string `json:"text"`
+		From      struct {
+			ID    int64 `json:"id"`
+			IsBot bool  `json:"is_bot"`
+		} `json:"from"`
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+	} `json:"message"`
+}
+
+// Run long-polls getUpdates and dispatches text messages until an API call
+// fails or ctx is cancelled
+func (t *Telegram) Run(ctx context.Context, handle func(context.Context, Message)) error {
+	for {
+		var updates []telegramUpdate
+		err := t.call(ctx, "getUpdates", map[string]any{
+			"offset":          t.offset,
+			"timeout":         telegramPollTimeout,
+			"allowed_updates": []string{"message"},
+		}, &updates)
+		if err != nil {
+			return err
+		}
+
+		for _, update := range updates {
+			t.offset = update.UpdateID + 1
+
+			msg := update.Message
+			if msg == nil || msg.From.IsBot || msg.Text == "" {
+				continue
+			}
+
+			// Telegram doesn't expose account age, so these users are
+			// refused while a minimum age is required
+			handle(ctx, Message{
+				Platform:  t.Name(),
+				UserID:    strconv.FormatInt(msg.From.ID, 10),
+				ChatID:    strconv.FormatInt(msg.Chat.ID, 10),
+				MessageID: strconv.FormatInt(msg.MessageID, 10),
+				Text:      msg.Text,
+			})
+		}
+	}
+}
+
+func (t *Telegram) Reply(ctx context.Context, chatID, replyTo, text string) error {
+	params := map[string]any{
+		"chat_id": chatID,
+		"text":    text,
+	}
+	if replyTo != "" {
+		params["reply_to_message_id"] = replyTo
+	}
+	return t.call(ctx, "sendMessage", params, nil)
+}
+
+func (t *Telegram) call(ctx context.Context, method string, params any, result any) error {
+	payload, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		t.cfg.APIURL+"/bot"+t.cfg.Token+"/"+method, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("telegram %s failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var apiResp telegramResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return fmt.Errorf("failed to parse telegram %s response: %w", method, err)
+	}
+	if !apiResp.OK {
+		return fmt.Errorf("telegram %s failed: %s", method, apiResp.Description)
+	}
+
+	if result != nil {
+		return json.Unmarshal(apiResp.Result, result)
+	}
+	return nil
+}
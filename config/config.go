@@ -0,0 +1,208 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"faucet-backend/units"
+)
+
+// Config is everything the application reads from the environment
+type Config struct {
+	Port        string
+	MetricsPort string
+	// ShutdownTimeout bounds how long SIGTERM waits for in-flight drips
+	ShutdownTimeout time.Duration
+
+	DatabaseURL string
+	DBLogLevel  string
+	RedisURL    string
+
+	RPCURL     string
+	PrivateKey string
+	// TreasuryPrivateKey signs refills from the treasury; refills from the
+	// treasury are unavailable when it's empty
+	TreasuryPrivateKey string
+	// ChainID and MinFaucetBalance (ETH) drive the readiness checks
+	ChainID          string
+	MinFaucetBalance string
+
+	CaptchaSecretKey string
+	CaptchaVerifyURL string
+
+	AdminAPIKey string
+
+	OAuth OAuthConfig
+
+	BalanceCheckInterval time.Duration
+	BalanceMinDrips      int64
+	AlertWebhookURL      string
+
+	// TokenListingInterval is how often the cached token listing is rebuilt,
+	// and TokenListingTTL how long it can be served while rebuilds fail
+	TokenListingInterval time.Duration
+	TokenListingTTL      time.Duration
+
+	// StatsRollupInterval is how often timeseries rollups are rebuilt
+	StatsRollupInterval time.Duration
+
+	Bots BotConfig
+
+	// ConfigFile is the path of the declarative token and limits file, and
+	// File its contents; both are empty when FAUCET_CONFIG isn't set
+	ConfigFile string
+	File       *File
+}
+
+type OAuthConfig struct {
+	Required           bool
+	MinAccountAge      time.Duration
+	SuccessRedirectURL string
+
+	GitHubClientID     string
+	GitHubClientSecret string
+	GitHubRedirectURL  string
+	GitHubOAuthBaseURL string
+	GitHubAPIBaseURL   string
+}
+
+type BotConfig struct {
+	DiscordToken      string
+	DiscordGatewayURL string
+	DiscordAPIURL     string
+
+	TelegramToken  string
+	TelegramAPIURL string
+}
+
+// Load reads the configuration from the environment, returning an error for
+// missing required variables or malformed values
+func Load() (*Config, error) {
+	required := []string{"RPC_URL", "FAUCET_PRIVATE_KEY", "DATABASE_URL", "REDIS_URL"}
+	for _, env := range required {
+		if os.Getenv(env) == "" {
+			return nil, fmt.Errorf("required environment variable %s is not set", env)
+		}
+	}
+
+	cfg := &Config{
+		Port:               envString("PORT", "3000"),
+		MetricsPort:        envString("METRICS_PORT", "9090"),
+		DatabaseURL:        os.Getenv("DATABASE_URL"),
+		DBLogLevel:         os.Getenv("DB_LOG_LEVEL"),
+		RedisURL:           os.Getenv("REDIS_URL"),
+		RPCURL:             os.Getenv("RPC_URL"),
+		PrivateKey:         os.Getenv("FAUCET_PRIVATE_KEY"),
+		TreasuryPrivateKey: os.Getenv("TREASURY_PRIVATE_KEY"),
+		ChainID:            os.Getenv("CHAIN_ID"),
+		MinFaucetBalance:   envString("MIN_FAUCET_BALANCE", "0.1"),
+		CaptchaSecretKey:   os.Getenv("GOTCHA_SECRET_KEY"),
+		CaptchaVerifyURL:   envString("GOTCHA_VERIFY_URL", "http://api.gotcha.land/api/siteverify"),
+		AdminAPIKey:        os.Getenv("ADMIN_API_KEY"),
+		AlertWebhookURL:    os.Getenv("ALERT_WEBHOOK_URL"),
+		OAuth: OAuthConfig{
+			Required:           strings.EqualFold(os.Getenv("OAUTH_REQUIRED"), "true"),
+			SuccessRedirectURL: os.Getenv("OAUTH_SUCCESS_REDIRECT_URL"),
+			GitHubClientID:     os.Getenv("GITHUB_CLIENT_ID"),
+			GitHubClientSecret: os.Getenv("GITHUB_CLIENT_SECRET"),
+			GitHubRedirectURL:  os.Getenv("GITHUB_REDIRECT_URL"),
+			GitHubOAuthBaseURL: os.Getenv("GITHUB_OAUTH_BASE_URL"),
+			GitHubAPIBaseURL:   os.Getenv("GITHUB_API_BASE_URL"),
+		},
+		Bots: BotConfig{
+			DiscordToken:      os.Getenv("DISCORD_BOT_TOKEN"),
+			DiscordGatewayURL: os.Getenv("DISCORD_GATEWAY_URL"),
+			DiscordAPIURL:     os.Getenv("DISCORD_API_URL"),
+			TelegramToken:     os.Getenv("TELEGRAM_BOT_TOKEN"),
+			TelegramAPIURL:    os.Getenv("TELEGRAM_API_URL"),
+		},
+	}
+
+	days, err := envInt64("OAUTH_MIN_ACCOUNT_AGE_DAYS", 30)
+	if err != nil {
+		return nil, err
+	}
+	if days < 0 {
+		return nil, fmt.Errorf("invalid OAUTH_MIN_ACCOUNT_AGE_DAYS %d", days)
+	}
+	cfg.OAuth.MinAccountAge = time.Duration(days) * 24 * time.Hour
+
+	// The config file's chain and CAPTCHA settings override the environment
+	if cfg.ConfigFile = os.Getenv("FAUCET_CONFIG"); cfg.ConfigFile != "" {
+		if cfg.File, err = LoadFile(cfg.ConfigFile); err != nil {
+			return nil, err
+		}
+		if cfg.File.Chain.ID != "" {
+			cfg.ChainID = cfg.File.Chain.ID
+		}
+		if cfg.File.Chain.MinFaucetBalance != "" {
+			cfg.MinFaucetBalance = cfg.File.Chain.MinFaucetBalance
+		}
+		if cfg.File.Captcha.VerifyURL != "" {
+			cfg.CaptchaVerifyURL = cfg.File.Captcha.VerifyURL
+		}
+	}
+
+	if _, err := units.Parse(cfg.MinFaucetBalance, 18); err != nil {
+		return nil, fmt.Errorf("invalid MIN_FAUCET_BALANCE: %w", err)
+	}
+
+	if cfg.ShutdownTimeout, err = envDuration("SHUTDOWN_TIMEOUT", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.BalanceCheckInterval, err = envDuration("BALANCE_CHECK_INTERVAL", time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.BalanceMinDrips, err = envInt64("BALANCE_MIN_DRIPS", 10); err != nil {
+		return nil, err
+	}
+	if cfg.TokenListingInterval, err = envDuration("TOKEN_LISTING_INTERVAL", 15*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.TokenListingTTL, err = envDuration("TOKEN_LISTING_TTL", 10*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.StatsRollupInterval, err = envDuration("STATS_ROLLUP_INTERVAL", time.Minute); err != nil {
+		return nil, err
+	}
+
+	if cfg.OAuth.Required && cfg.OAuth.GitHubClientID == "" {
+		return nil, fmt.Errorf("OAUTH_REQUIRED is set but no OAuth provider is configured")
+	}
+
+	return cfg, nil
+}
+
+func envString(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func envDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %s=%q", key, value)
+	}
+	return d, nil
+}
+
+func envInt64(key string, fallback int64) (int64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer %s=%q", key, value)
+	}
+	return n, nil
+}
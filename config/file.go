@@ -0,0 +1,708 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"faucet-backend/models"
+	"faucet-backend/units"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"gopkg.in/yaml.v3"
+)
+
+// File is the declarative faucet configuration read from FAUCET_CONFIG. See
+// faucet.example.yaml for a commented example.
+type File struct {
+	Chain    ChainFile    `yaml:"chain"`
+	Captcha  CaptchaFile  `yaml:"captcha"`
+	Limits   LimitsFile   `yaml:"limits"`
+	Treasury TreasuryFile `yaml:"treasury"`
+	Tokens   []TokenFile  `yaml:"tokens"`
+	// Bundles name groups of token IDs that can be requested together,
+	// e.g. stablecoins: [usdc, dai]
+	Bundles map[string][]string `yaml:"bundles"`
+}
+
+// ChainFile describes the chain the faucet wallet drips on. Changing it
+// needs a restart.
+type ChainFile struct {
+	ID   string `yaml:"id"`
+	Name string `yaml:"name"`
+	// MinFaucetBalance is the native balance (ETH) below which readiness
+	// reports degraded
+	MinFaucetBalance string `yaml:"minFaucetBalance"`
+}
+
+// CaptchaFile configures CAPTCHA checks on HTTP drip requests. The secret
+// stays in GOTCHA_SECRET_KEY.
+type CaptchaFile struct {
+	// Required defaults to true
+	Required  *bool  `yaml:"required"`
+	VerifyURL string `yaml:"verifyUrl"`
+}
+
+// LimitsFile holds the daily quotas shared across tokens. Zero keeps the
+// built-in default.
+type LimitsFile struct {
+	IPDaily          int `yaml:"ipDaily"`
+	FingerprintDaily int `yaml:"fingerprintDaily"`
+	IdentityDaily    int `yaml:"identityDaily"`
+}
+
+// TreasuryFile configures automatic refills and sweeps of the faucet wallet.
+// The treasury signer's key stays in TREASURY_PRIVATE_KEY.
+type TreasuryFile struct {
+	// Address receives sweeps. Defaults to the treasury signer's address.
+	Address string `yaml:"address"`
+	// Interval between balance checks, e.g. "5m". Defaults to 5 minutes.
+	Interval string `yaml:"interval"`
+}
+
+// Refill sources
+const (
+	RefillFromTreasury = "treasury"
+	RefillByMint       = "mint"
+)
+
+// RefillFile keeps one token's faucet balance between two marks, in whole
+// tokens
+type RefillFile struct {
+	// Source is "treasury", a transfer from the treasury signer, or "mint",
+	// a mint(address,uint256) call by the faucet wallet. Defaults to
+	// treasury.
+	Source string `yaml:"source"`
+	// LowWater is the balance below which the wallet is topped up to
+	// HighWater
+	LowWater  string `yaml:"lowWater"`
+	HighWater string `yaml:"highWater"`
+	// SweepAbove, when set, is the balance above which everything over
+	// HighWater is sent back to the treasury
+	SweepAbove string `yaml:"sweepAbove"`
+}
+
+// Amount policies
+const (
+	AmountFixed   = "fixed"
+	AmountBalance = "balance"
+	AmountDemand  = "demand"
+)
+
+// AmountFile sizes one token's drips when they're requested instead of
+// always dripping dripAmount. Min and Max are in whole tokens.
+type AmountFile struct {
+	// Policy is "fixed", dripAmount every time, "balance", a share of the
+	// faucet's balance, or "demand", dripAmount scaled by how far the
+	// request rate is from a target. Defaults to fixed.
+	Policy string `yaml:"policy"`
+	// Percent of the faucet balance a balance drip sends, e.g. "0.1"
+	Percent string `yaml:"percent"`
+	// TargetPerHour is the hourly request rate at which a demand drip is
+	// dripAmount; busier hours get proportionally less and quieter ones
+	// more, up to Max
+	TargetPerHour int `yaml:"targetPerHour"`
+	// Min is the floor. Max is the ceiling, which defaults to dripAmount
+	// for demand drips and is capped at the balance for balance drips; a
+	// balance under Min pauses the token.
+	Min string `yaml:"min"`
+	Max string `yaml:"max"`
+}
+
+// TokenFile is one token the faucet drips
+type TokenFile struct {
+	ID     string `yaml:"id"`
+	Name   string `yaml:"name"`
+	Symbol string `yaml:"symbol"`
+	// Address is empty for the chain's native token
+	Address string `yaml:"address"`
+	// Distribution is how drips are paid: "native", "transfer" from the
+	// faucet's balance or "mint" by the faucet wallet. Defaults to native
+	// without an address and transfer with one.
+	Distribution string `yaml:"distribution"`
+	// MintSelector is the mint function of a mint token, as a 4-byte
+	// selector ("0x40c10f19") or a signature with the standard's arguments:
+	// mint(address,uint256) for ERC20, safeMint(address) for ERC-721 and
+	// mint(address,uint256,uint256,bytes) for ERC-1155, which are also the
+	// defaults.
+	MintSelector string `yaml:"mintSelector"`
+	// Standard is "erc721" or "erc1155" for NFT collections. Defaults to
+	// erc20.
+	Standard string `yaml:"standard"`
+	// NFTID is the ERC-1155 token ID dripped
+	NFTID string `yaml:"nftId"`
+	// Inventory lists the IDs the faucet wallet holds for an ERC-721
+	// collection distributed by transfer, as single IDs or inclusive ranges
+	// such as "100-199"
+	Inventory []string `yaml:"inventory"`
+	// Decimals defaults to 18, or 0 for NFTs
+	Decimals      *int   `yaml:"decimals"`
+	DripAmount    string `yaml:"dripAmount"`
+	CooldownHours int    `yaml:"cooldownHours"`
+	LogoURL       string `yaml:"logoUrl"`
+	// Active defaults to true; inactive tokens are kept but not served
+	Active *bool `yaml:"active"`
+	// Refill is unset when the wallet is topped up by hand
+	Refill *RefillFile `yaml:"refill"`
+	// Amount is unset when every drip is dripAmount
+	Amount *AmountFile `yaml:"amount"`
+}
+
+var (
+	tokenIDPattern   = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	selectorPattern  = regexp.MustCompile(`^0x[0-9a-fA-F]{8}$`)
+	signaturePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*\(([a-z0-9,]*)\)$`)
+	nftIDPattern     = regexp.MustCompile(`^[0-9]{1,78}$`)
+)
+
+// mintArguments are the arguments of each standard's mint function
+var mintArguments = map[string]string{
+	"":                     "address,uint256",
+	models.StandardERC721:  "address",
+	models.StandardERC1155: "address,uint256,uint256,bytes",
+}
+
+// maxInventory caps how many IDs one collection's inventory lists
+const maxInventory = 10000
+
+// percentDecimals is the precision of amount percentages, so parsed they're
+// in millionths of the balance
+const (
+	percentDecimals = 4
+	partsPerPercent = 10000
+)
+
+// maxBundleTokens caps how many tokens one bundle names
+const maxBundleTokens = 10
+
+// LoadFile reads and validates a config file. Unknown keys are errors so a
+// typo doesn't silently fall back to a default.
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var file File
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	if err := file.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s:\n%w", path, err)
+	}
+	return &file, nil
+}
+
+// Validate checks every field and reports all problems at once, each
+// prefixed with its path in the file
+func (f *File) Validate() error {
+	var errs []error
+	fail := func(path, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	if f.Chain.ID != "" {
+		if _, err := strconv.ParseUint(f.Chain.ID, 10, 64); err != nil {
+			fail("chain.id", "%q is not a chain ID", f.Chain.ID)
+		}
+	}
+	if f.Chain.MinFaucetBalance != "" {
+		if _, err := units.Parse(f.Chain.MinFaucetBalance, 18); err != nil {
+			fail("chain.minFaucetBalance", "%v", err)
+		}
+	}
+
+	if f.Captcha.VerifyURL != "" {
+		if u, err := url.Parse(f.Captcha.VerifyURL); err != nil || u.Scheme == "" || u.Host == "" {
+			fail("captcha.verifyUrl", "%q is not an absolute URL", f.Captcha.VerifyURL)
+		}
+	}
+
+	limits := []struct {
+		path  string
+		value int
+	}{
+		{"limits.ipDaily", f.Limits.IPDaily},
+		{"limits.fingerprintDaily", f.Limits.FingerprintDaily},
+		{"limits.identityDaily", f.Limits.IdentityDaily},
+	}
+	for _, limit := range limits {
+		if limit.value < 0 {
+			fail(limit.path, "must not be negative, got %d", limit.value)
+		}
+	}
+
+	if f.Treasury.Address != "" && !common.IsHexAddress(f.Treasury.Address) {
+		fail("treasury.address", "%q is not an address", f.Treasury.Address)
+	}
+	if f.Treasury.Interval != "" {
+		if interval, err := time.ParseDuration(f.Treasury.Interval); err != nil || interval <= 0 {
+			fail("treasury.interval", "%q is not a positive duration", f.Treasury.Interval)
+		}
+	}
+
+	if len(f.Tokens) == 0 {
+		fail("tokens", "at least one token is required")
+	}
+
+	seen := make(map[string]int)
+	for i, token := range f.Tokens {
+		path := fmt.Sprintf("tokens[%d]", i)
+		if token.ID != "" {
+			path = fmt.Sprintf("tokens[%d] (%s)", i, token.ID)
+		}
+
+		switch {
+		case token.ID == "":
+			fail(path+".id", "is required")
+		case len(token.ID) > 20 || !tokenIDPattern.MatchString(token.ID):
+			fail(path+".id", "%q must be at most 20 lowercase letters, digits, '-' or '_'", token.ID)
+		}
+		if first, ok := seen[token.ID]; ok && token.ID != "" {
+			fail(path+".id", "duplicates tokens[%d]", first)
+		} else {
+			seen[token.ID] = i
+		}
+
+		requireLength(fail, path+".name", token.Name, 50, true)
+		requireLength(fail, path+".symbol", token.Symbol, 10, true)
+		requireLength(fail, path+".logoUrl", token.LogoURL, 200, false)
+
+		if token.Address != "" && !common.IsHexAddress(token.Address) {
+			fail(path+".address", "%q is not an address", token.Address)
+		}
+		validateDistribution(fail, path, token)
+		validateStandard(fail, path, token)
+
+		decimals := token.decimals()
+		if decimals < 0 || decimals > units.MaxDecimals {
+			fail(path+".decimals", "must be between 0 and %d, got %d", units.MaxDecimals, decimals)
+			decimals = units.MaxDecimals
+		}
+
+		switch amount, err := units.Parse(token.DripAmount, decimals); {
+		case token.DripAmount == "":
+			fail(path+".dripAmount", "is required")
+		case err != nil:
+			fail(path+".dripAmount", "%v", err)
+		case amount.Sign() == 0:
+			fail(path+".dripAmount", "must be greater than zero")
+		case len(token.DripAmount) > 30:
+			fail(path+".dripAmount", "must be at most 30 characters")
+		}
+
+		if token.CooldownHours < 1 {
+			fail(path+".cooldownHours", "must be at least 1, got %d", token.CooldownHours)
+		}
+
+		if token.Refill != nil {
+			validateRefill(fail, path+".refill", token, decimals)
+		}
+		if token.Amount != nil {
+			validateAmount(fail, path+".amount", token, decimals)
+		}
+	}
+
+	names := make([]string, 0, len(f.Bundles))
+	for name := range f.Bundles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		validateBundle(fail, "bundles."+name, name, f.Bundles[name], seen)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateBundle checks a bundle names distinct tokens of the file; seen maps
+// the file's token IDs to their index
+func validateBundle(fail func(string, string, ...any), path, name string, tokenIDs []string, seen map[string]int) {
+	if len(name) > 20 || !tokenIDPattern.MatchString(name) {
+		fail(path, "name must be at most 20 lowercase letters, digits, '-' or '_'")
+	}
+	if len(tokenIDs) == 0 || len(tokenIDs) > maxBundleTokens {
+		fail(path, "must list between 1 and %d tokens, got %d", maxBundleTokens, len(tokenIDs))
+	}
+
+	listed := make(map[string]bool)
+	for _, id := range tokenIDs {
+		if _, ok := seen[id]; !ok {
+			fail(path, "%q is not a token", id)
+		}
+		if listed[id] {
+			fail(path, "lists %q twice", id)
+		}
+		listed[id] = true
+	}
+}
+
+func validateDistribution(fail func(string, string, ...any), path string, token TokenFile) {
+	switch token.Distribution {
+	case "":
+	case models.DistributeNative:
+		if token.Address != "" {
+			fail(path+".distribution", "native tokens have no address")
+		}
+	case models.DistributeTransfer, models.DistributeMint:
+		if token.Address == "" {
+			fail(path+".distribution", "%s needs a token address", token.Distribution)
+		}
+	default:
+		fail(path+".distribution", "%q must be native, transfer or mint", token.Distribution)
+	}
+
+	if token.MintSelector == "" {
+		return
+	}
+	args := mintArguments[token.standard()]
+	if token.distribution() != models.DistributeMint {
+		fail(path+".mintSelector", "only applies to mint tokens")
+	} else if !selectorPattern.MatchString(token.MintSelector) {
+		match := signaturePattern.FindStringSubmatch(token.MintSelector)
+		if match == nil || match[1] != args {
+			fail(path+".mintSelector", "%q must be a 4-byte selector like 0x40c10f19 or a signature like mint(%s)", token.MintSelector, args)
+		}
+	}
+}
+
+func validateStandard(fail func(string, string, ...any), path string, token TokenFile) {
+	nft := token.isNFT()
+	switch {
+	case token.Standard == "" || token.Standard == "erc20":
+	case !nft:
+		fail(path+".standard", "%q must be erc20, erc721 or erc1155", token.Standard)
+	case token.Address == "":
+		fail(path+".standard", "%s needs a collection address", token.Standard)
+	}
+	if nft && token.decimals() != 0 {
+		fail(path+".decimals", "must be 0 for NFTs")
+	}
+	if token.Standard == models.StandardERC721 && token.DripAmount != "" && token.DripAmount != "1" {
+		fail(path+".dripAmount", "must be 1 for ERC-721 collections")
+	}
+
+	switch {
+	case token.Standard == models.StandardERC1155 && token.NFTID == "":
+		fail(path+".nftId", "is required for ERC-1155 collections")
+	case token.NFTID == "":
+	case token.Standard != models.StandardERC1155:
+		fail(path+".nftId", "only applies to ERC-1155 collections")
+	case !nftIDPattern.MatchString(token.NFTID):
+		fail(path+".nftId", "%q is not a token ID", token.NFTID)
+	}
+
+	holds := token.Standard == models.StandardERC721 && token.distribution() == models.DistributeTransfer
+	switch {
+	case holds && len(token.Inventory) == 0:
+		fail(path+".inventory", "is required for ERC-721 collections distributed by transfer")
+	case len(token.Inventory) == 0:
+	case !holds:
+		fail(path+".inventory", "only applies to ERC-721 collections distributed by transfer")
+	default:
+		if _, err := token.InventoryIDs(); err != nil {
+			fail(path+".inventory", "%v", err)
+		}
+	}
+}
+
+func validateRefill(fail func(string, string, ...any), path string, token TokenFile, decimals int) {
+	refill := token.Refill
+
+	if token.distribution() == models.DistributeMint {
+		fail(path, "mint tokens hold no balance to refill")
+	}
+	if token.isNFT() {
+		fail(path, "NFT collections can't be refilled")
+	}
+
+	switch refill.Source {
+	case "", RefillFromTreasury:
+	case RefillByMint:
+		if token.Address == "" {
+			fail(path+".source", "the native token can't be minted")
+		}
+	default:
+		fail(path+".source", "%q must be treasury or mint", refill.Source)
+	}
+
+	marks := make(map[string]*big.Int)
+	for _, mark := range []struct {
+		name, value string
+		required    bool
+	}{
+		{"lowWater", refill.LowWater, true},
+		{"highWater", refill.HighWater, true},
+		{"sweepAbove", refill.SweepAbove, false},
+	} {
+		if mark.value == "" {
+			if mark.required {
+				fail(path+"."+mark.name, "is required")
+			}
+			continue
+		}
+		value, err := units.Parse(mark.value, decimals)
+		if err != nil {
+			fail(path+"."+mark.name, "%v", err)
+			continue
+		}
+		marks[mark.name] = value
+	}
+
+	low, high, sweep := marks["lowWater"], marks["highWater"], marks["sweepAbove"]
+	if low != nil && high != nil && low.Cmp(high) >= 0 {
+		fail(path+".highWater", "must be greater than lowWater")
+	}
+	if high != nil && sweep != nil && sweep.Cmp(high) <= 0 {
+		fail(path+".sweepAbove", "must be greater than highWater")
+	}
+}
+
+func validateAmount(fail func(string, string, ...any), path string, token TokenFile, decimals int) {
+	amount := token.Amount
+
+	switch amount.Policy {
+	case "", AmountFixed, AmountDemand:
+	case AmountBalance:
+		if token.distribution() == models.DistributeMint {
+			fail(path+".policy", "mint tokens hold no balance to drip a share of")
+		}
+	default:
+		fail(path+".policy", "%q must be fixed, balance or demand", amount.Policy)
+	}
+	if amount.Policy != "" && amount.Policy != AmountFixed && token.isNFT() {
+		fail(path+".policy", "NFT collections drip a fixed amount")
+	}
+
+	switch percent, err := units.Parse(amount.Percent, percentDecimals); {
+	case amount.Policy == AmountBalance && amount.Percent == "":
+		fail(path+".percent", "is required for the balance policy")
+	case amount.Percent == "":
+	case amount.Policy != AmountBalance:
+		fail(path+".percent", "only applies to the balance policy")
+	case err != nil:
+		fail(path+".percent", "%v", err)
+	case percent.Sign() == 0 || percent.Cmp(big.NewInt(100*partsPerPercent)) > 0:
+		fail(path+".percent", "must be greater than 0 and at most 100")
+	}
+
+	switch {
+	case amount.Policy == AmountDemand && amount.TargetPerHour < 1:
+		fail(path+".targetPerHour", "must be at least 1 for the demand policy")
+	case amount.TargetPerHour != 0 && amount.Policy != AmountDemand:
+		fail(path+".targetPerHour", "only applies to the demand policy")
+	}
+
+	bounds := make(map[string]*big.Int)
+	for _, bound := range []struct{ name, value string }{
+		{"min", amount.Min},
+		{"max", amount.Max},
+	} {
+		if bound.value == "" {
+			continue
+		}
+		if amount.Policy == "" || amount.Policy == AmountFixed {
+			fail(path+"."+bound.name, "doesn't apply to fixed amounts")
+			continue
+		}
+		value, err := units.Parse(bound.value, decimals)
+		if err != nil {
+			fail(path+"."+bound.name, "%v", err)
+			continue
+		}
+		bounds[bound.name] = value
+	}
+	if min, max := bounds["min"], bounds["max"]; min != nil && max != nil && min.Cmp(max) > 0 {
+		fail(path+".max", "must not be less than min")
+	}
+}
+
+func requireLength(fail func(string, string, ...any), path, value string, max int, required bool) {
+	switch {
+	case value == "" && required:
+		fail(path, "is required")
+	case len(value) > max:
+		fail(path, "must be at most %d characters", max)
+	}
+}
+
+func (t TokenFile) distribution() string {
+	if t.Distribution != "" {
+		return t.Distribution
+	}
+	if t.Address == "" {
+		return models.DistributeNative
+	}
+	return models.DistributeTransfer
+}
+
+// mintSelector returns the configured mint function as a 0x-prefixed
+// selector, "" for the default
+func (t TokenFile) mintSelector() string {
+	if t.MintSelector == "" || selectorPattern.MatchString(t.MintSelector) {
+		return strings.ToLower(t.MintSelector)
+	}
+	return hexutil.Encode(crypto.Keccak256([]byte(t.MintSelector))[:4])
+}
+
+func (t TokenFile) standard() string {
+	if t.Standard == "erc20" {
+		return ""
+	}
+	return t.Standard
+}
+
+func (t TokenFile) isNFT() bool {
+	return t.Standard == models.StandardERC721 || t.Standard == models.StandardERC1155
+}
+
+// InventoryIDs expands the token's inventory into distinct decimal IDs
+func (t TokenFile) InventoryIDs() ([]string, error) {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, entry := range t.Inventory {
+		first, last, isRange := strings.Cut(entry, "-")
+		if !isRange {
+			last = first
+		}
+		from, ok1 := new(big.Int).SetString(strings.TrimSpace(first), 10)
+		to, ok2 := new(big.Int).SetString(strings.TrimSpace(last), 10)
+		if !ok1 || !ok2 || from.Sign() < 0 || from.Cmp(to) > 0 {
+			return nil, fmt.Errorf("%q is not an ID or a range of IDs", entry)
+		}
+
+		for id := from; id.Cmp(to) <= 0; id = new(big.Int).Add(id, big.NewInt(1)) {
+			if len(ids) == maxInventory {
+				return nil, fmt.Errorf("lists more than %d IDs", maxInventory)
+			}
+			if key := id.String(); !seen[key] {
+				seen[key] = true
+				ids = append(ids, key)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func (t TokenFile) decimals() int {
+	if t.Decimals == nil {
+		if t.isNFT() {
+			return 0
+		}
+		return 18
+	}
+	return *t.Decimals
+}
+
+// CaptchaRequired reports whether HTTP drip requests need a CAPTCHA
+func (f *File) CaptchaRequired() bool {
+	return f.Captcha.Required == nil || *f.Captcha.Required
+}
+
+// SourceOrDefault returns the refill source, treasury when unset
+func (r *RefillFile) SourceOrDefault() string {
+	if r.Source == "" {
+		return RefillFromTreasury
+	}
+	return r.Source
+}
+
+// RefillMarks returns the token's refill marks in base units, with sweep nil
+// when unset. The file must be valid.
+func (t TokenFile) RefillMarks() (low, high, sweep *big.Int) {
+	low, _ = units.Parse(t.Refill.LowWater, t.decimals())
+	high, _ = units.Parse(t.Refill.HighWater, t.decimals())
+	if t.Refill.SweepAbove != "" {
+		sweep, _ = units.Parse(t.Refill.SweepAbove, t.decimals())
+	}
+	return low, high, sweep
+}
+
+// PolicyOrDefault returns the amount policy, fixed when unset
+func (a *AmountFile) PolicyOrDefault() string {
+	if a == nil || a.Policy == "" {
+		return AmountFixed
+	}
+	return a.Policy
+}
+
+// AmountBounds returns the token's drip amount settings in base units: the
+// share of the balance in millionths and the floor and ceiling, nil when
+// unset. The file must be valid.
+func (t TokenFile) AmountBounds() (partsPerMillion int64, min, max *big.Int) {
+	if percent, err := units.Parse(t.Amount.Percent, percentDecimals); err == nil {
+		partsPerMillion = percent.Int64()
+	}
+	if t.Amount.Min != "" {
+		min, _ = units.Parse(t.Amount.Min, t.decimals())
+	}
+	if t.Amount.Max != "" {
+		max, _ = units.Parse(t.Amount.Max, t.decimals())
+	}
+	return partsPerMillion, min, max
+}
+
+// TreasuryInterval returns how often refills are checked
+func (f *File) TreasuryInterval() time.Duration {
+	interval, err := time.ParseDuration(f.Treasury.Interval)
+	if err != nil || interval <= 0 {
+		return 5 * time.Minute
+	}
+	return interval
+}
+
+// Inventories returns the IDs listed for each ERC-721 collection distributed
+// by transfer. The file must be valid.
+func (f *File) Inventories() map[string][]string {
+	inventories := make(map[string][]string)
+	for _, t := range f.Tokens {
+		if t.Standard == models.StandardERC721 && t.distribution() == models.DistributeTransfer {
+			inventories[t.ID], _ = t.InventoryIDs()
+		}
+	}
+	return inventories
+}
+
+// TokenModels returns the file's tokens as database rows
+func (f *File) TokenModels() []models.Token {
+	tokens := make([]models.Token, 0, len(f.Tokens))
+	for _, t := range f.Tokens {
+		address := t.Address
+		if address != "" {
+			address = common.HexToAddress(address).Hex()
+		}
+		nftID := t.NFTID
+		if id, ok := new(big.Int).SetString(nftID, 10); ok {
+			nftID = id.String()
+		}
+		tokens = append(tokens, models.Token{
+			ID:            t.ID,
+			Name:          t.Name,
+			Symbol:        t.Symbol,
+			Address:       address,
+			Distribution:  t.distribution(),
+			MintSelector:  t.mintSelector(),
+			Standard:      t.standard(),
+			NFTID:         nftID,
+			DripAmount:    t.DripAmount,
+			CooldownHours: t.CooldownHours,
+			Decimals:      t.decimals(),
+			LogoURL:       t.LogoURL,
+			IsActive:      t.Active == nil || *t.Active,
+		})
+	}
+	return tokens
+}
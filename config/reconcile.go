@@ -0,0 +1,202 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"faucet-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// ReconcileResult lists the token IDs a reconcile changed
+type ReconcileResult struct {
+	Created     []string
+	Updated     []string
+	Deactivated []string
+}
+
+// ReconcileTokens makes the tokens table match tokens: missing tokens are
+// inserted, changed ones updated and active tokens not listed deactivated.
+// Pause state set by the balance monitor is left alone. It runs in one
+// transaction.
+func ReconcileTokens(ctx context.Context, db *gorm.DB, tokens []models.Token) (*ReconcileResult, error) {
+	result := &ReconcileResult{}
+
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		listed := make([]string, 0, len(tokens))
+
+		for _, token := range tokens {
+			listed = append(listed, token.ID)
+
+			var existing models.Token
+			found := tx.Unscoped().Where("id = ?", token.ID).Limit(1).Find(&existing)
+			if found.Error != nil {
+				return fmt.Errorf("failed to look up token %s: %w", token.ID, found.Error)
+			}
+
+			if found.RowsAffected == 0 {
+				// is_active defaults to true and decimals to 18, and Create
+				// reads the defaults back, so an inactive token or an NFT
+				// needs a second write
+				zeroed := make(map[string]interface{})
+				if !token.IsActive {
+					zeroed["is_active"] = false
+				}
+				if token.Decimals == 0 {
+					zeroed["decimals"] = 0
+				}
+				if err := tx.Create(&token).Error; err != nil {
+					return fmt.Errorf("failed to create token %s: %w", token.ID, err)
+				}
+				if len(zeroed) > 0 {
+					if err := tx.Model(&token).Updates(zeroed).Error; err != nil {
+						return fmt.Errorf("failed to create token %s: %w", token.ID, err)
+					}
+				}
+				result.Created = append(result.Created, token.ID)
+				continue
+			}
+
+			changes := tokenChanges(&existing, &token)
+			if len(changes) == 0 {
+				continue
+			}
+			if err := tx.Unscoped().Model(&existing).Updates(changes).Error; err != nil {
+				return fmt.Errorf("failed to update token %s: %w", token.ID, err)
+			}
+			result.Updated = append(result.Updated, token.ID)
+		}
+
+		var stale []string
+		query := tx.Model(&models.Token{}).Where("is_active = true")
+		if len(listed) > 0 {
+			query = query.Where("id NOT IN ?", listed)
+		}
+		if err := query.Pluck("id", &stale).Error; err != nil {
+			return fmt.Errorf("failed to find unlisted tokens: %w", err)
+		}
+		if len(stale) > 0 {
+			if err := tx.Model(&models.Token{}).Where("id IN ?", stale).Update("is_active", false).Error; err != nil {
+				return fmt.Errorf("failed to deactivate tokens: %w", err)
+			}
+			result.Deactivated = stale
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("tokens reconciled",
+		"created", result.Created,
+		"updated", result.Updated,
+		"deactivated", result.Deactivated,
+	)
+	return result, nil
+}
+
+// tokenChanges returns the columns where existing differs from want
+func tokenChanges(existing, want *models.Token) map[string]interface{} {
+	changes := make(map[string]interface{})
+	if existing.Name != want.Name {
+		changes["name"] = want.Name
+	}
+	if existing.Symbol != want.Symbol {
+		changes["symbol"] = want.Symbol
+	}
+	if existing.Address != want.Address {
+		changes["address"] = want.Address
+	}
+	if existing.Distribution != want.Distribution {
+		changes["distribution"] = want.Distribution
+	}
+	if existing.MintSelector != want.MintSelector {
+		changes["mint_selector"] = want.MintSelector
+	}
+	if existing.Standard != want.Standard {
+		changes["standard"] = want.Standard
+	}
+	if existing.NFTID != want.NFTID {
+		if want.NFTID == "" {
+			changes["nft_id"] = nil
+		} else {
+			changes["nft_id"] = want.NFTID
+		}
+	}
+	if existing.DripAmount != want.DripAmount {
+		changes["drip_amount"] = want.DripAmount
+	}
+	if existing.CooldownHours != want.CooldownHours {
+		changes["cooldown_hours"] = want.CooldownHours
+	}
+	if existing.Decimals != want.Decimals {
+		changes["decimals"] = want.Decimals
+	}
+	if existing.LogoURL != want.LogoURL {
+		changes["logo_url"] = want.LogoURL
+	}
+	if existing.IsActive != want.IsActive {
+		changes["is_active"] = want.IsActive
+	}
+	// A token removed by hand comes back when it's listed again
+	if existing.DeletedAt.Valid {
+		changes["deleted_at"] = nil
+	}
+	return changes
+}
+
+// ReconcileInventory makes each listed ERC-721 collection's inventory match
+// its IDs: new IDs are added as available, listed IDs marked missing are
+// made available again for the sender to recheck, and available IDs no
+// longer listed are removed. Reserved and sent IDs are kept. It runs in one
+// transaction.
+func ReconcileInventory(ctx context.Context, db *gorm.DB, inventories map[string][]string) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for tokenID, ids := range inventories {
+			var existing []string
+			if err := tx.Model(&models.InventoryItem{}).Where("token_id = ?", tokenID).Pluck("nft_id", &existing).Error; err != nil {
+				return fmt.Errorf("failed to load inventory of %s: %w", tokenID, err)
+			}
+			known := make(map[string]bool, len(existing))
+			for _, id := range existing {
+				known[id] = true
+			}
+
+			var added []models.InventoryItem
+			for _, id := range ids {
+				if !known[id] {
+					added = append(added, models.InventoryItem{TokenID: tokenID, NFTID: id, Status: models.InventoryAvailable})
+				}
+			}
+			if len(added) > 0 {
+				if err := tx.CreateInBatches(&added, 500).Error; err != nil {
+					return fmt.Errorf("failed to add inventory of %s: %w", tokenID, err)
+				}
+			}
+
+			if len(ids) > 0 {
+				if err := tx.Model(&models.InventoryItem{}).
+					Where("token_id = ? AND status = ? AND nft_id IN ?", tokenID, models.InventoryMissing, ids).
+					Update("status", models.InventoryAvailable).Error; err != nil {
+					return fmt.Errorf("failed to restore inventory of %s: %w", tokenID, err)
+				}
+			}
+
+			unlisted := tx.Where("token_id = ? AND status = ?", tokenID, models.InventoryAvailable)
+			if len(ids) > 0 {
+				unlisted = unlisted.Where("nft_id NOT IN ?", ids)
+			}
+			removed := unlisted.Delete(&models.InventoryItem{})
+			if removed.Error != nil {
+				return fmt.Errorf("failed to prune inventory of %s: %w", tokenID, removed.Error)
+			}
+
+			if len(added) > 0 || removed.RowsAffected > 0 {
+				slog.Info("inventory reconciled", "token", tokenID, "added", len(added), "removed", removed.RowsAffected)
+			}
+		}
+		return nil
+	})
+}
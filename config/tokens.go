@@ -1,19 +1,22 @@
 package config
 
 import (
-	"faucet-backend/database"
 	"faucet-backend/models"
-	"log"
+	"fmt"
+	"log/slog"
+
+	"gorm.io/gorm"
 )
 
 // SeedTokens initializes default tokens in the database
-func SeedTokens() {
+func SeedTokens(db *gorm.DB) error {
 	tokens := []models.Token{
 		{
 			ID:            "eth",
 			Name:          "Ethereum",
 			Symbol:        "ETH",
 			Address:       "", // Native token
+			Distribution:  models.DistributeNative,
 			DripAmount:    "0.5",
 			CooldownHours: 24,
 			Decimals:      18,
@@ -25,6 +28,7 @@ func SeedTokens() {
 			Name:          "USD Coin",
 			Symbol:        "USDC",
 			Address:       "0x1c7D4B196Cb0C7B01d743Fbc6116a902379C7238", // Sepolia USDC
+			Distribution:  models.DistributeTransfer,
 			DripAmount:    "100",
 			CooldownHours: 24,
 			Decimals:      6,
@@ -36,6 +40,7 @@ func SeedTokens() {
 			Name:          "Tether USD",
 			Symbol:        "USDT",
 			Address:       "0xaA8E23Fb1079EA71e0a56F48a2aA51851D8433D0", // Sepolia USDT (example)
+			Distribution:  models.DistributeTransfer,
 			DripAmount:    "100",
 			CooldownHours: 24,
 			Decimals:      6,
@@ -47,6 +52,7 @@ func SeedTokens() {
 			Name:          "Dai Stablecoin",
 			Symbol:        "DAI",
 			Address:       "0x68194a729C2450ad26072b3D33ADaCbcef39D574", // Sepolia DAI (example)
+			Distribution:  models.DistributeTransfer,
 			DripAmount:    "100",
 			CooldownHours: 24,
 			Decimals:      18,
@@ -58,6 +64,7 @@ func SeedTokens() {
 			Name:          "Chainlink",
 			Symbol:        "LINK",
 			Address:       "0x779877A7B0D9E8603169DdbD7836e478b4624789", // Sepolia LINK
+			Distribution:  models.DistributeTransfer,
 			DripAmount:    "10",
 			CooldownHours: 24,
 			Decimals:      18,
@@ -68,15 +75,19 @@ func SeedTokens() {
 
 	for _, token := range tokens {
 		var existing models.Token
-		result := database.DB.Where("id = ?", token.ID).First(&existing)
-
+		result := db.Where("id = ?", token.ID).Limit(1).Find(&existing)
 		if result.Error != nil {
+			return fmt.Errorf("failed to look up token %s: %w", token.ID, result.Error)
+		}
+
+		if result.RowsAffected == 0 {
 			// Token doesn't exist, create it
-			if err := database.DB.Create(&token).Error; err != nil {
-				log.Printf("Failed to seed token %s: %v", token.ID, err)
-			} else {
-				log.Printf("✅ Seeded token: %s", token.Symbol)
+			if err := db.Create(&token).Error; err != nil {
+				return fmt.Errorf("failed to seed token %s: %w", token.ID, err)
 			}
+			slog.Info("seeded token", "token", token.ID, "symbol", token.Symbol)
 		}
 	}
+
+	return nil
 }
@@ -0,0 +1,223 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"faucet-backend/models"
+)
+
+// legacyToken and legacyDrip are the models as they were when the schema was
+// still created by AutoMigrate, before the baseline migration
+type legacyToken struct {
+	ID            string `gorm:"primaryKey;size:20"`
+	Name          string `gorm:"size:50;not null"`
+	Symbol        string `gorm:"size:10;not null"`
+	Address       string `gorm:"size:42"`
+	DripAmount    string `gorm:"size:30;not null"`
+	CooldownHours int    `gorm:"not null;default:24"`
+	Decimals      int    `gorm:"not null;default:18"`
+	LogoURL       string `gorm:"size:200"`
+	IsActive      bool   `gorm:"default:true"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt `gorm:"index"`
+}
+
+func (legacyToken) TableName() string { return "tokens" }
+
+type legacyDrip struct {
+	ID          uint   `gorm:"primaryKey"`
+	Recipient   string `gorm:"size:42;not null;index:idx_recipient_token"`
+	TokenID     string `gorm:"size:20;not null;index:idx_recipient_token"`
+	Amount      string `gorm:"size:30;not null"`
+	TxHash      string `gorm:"size:66;index"`
+	IPAddress   string `gorm:"type:inet;index"`
+	Fingerprint string `gorm:"size:64;index"`
+	Status      string `gorm:"size:20;default:pending;index"`
+	Error       string `gorm:"type:text"`
+	CreatedAt   time.Time
+	CompletedAt *time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (legacyDrip) TableName() string { return "drips" }
+
+// TestMigrations applies every migration to a pre-baseline database and to an
+// empty one, checks the result against the current models, then rolls
+// everything back. It needs a Postgres server, given as TEST_DATABASE_URL;
+// each run works in its own schema.
+func TestMigrations(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	t.Run("legacy", func(t *testing.T) {
+		db := openTestSchema(t, dsn)
+		ctx := context.Background()
+
+		if err := db.AutoMigrate(&legacyToken{}, &legacyDrip{}); err != nil {
+			t.Fatalf("legacy schema: %v", err)
+		}
+		if err := db.Create(&legacyToken{ID: "tst", Name: "Test", Symbol: "TST", Address: "0x0000000000000000000000000000000000000001", DripAmount: "1.5", Decimals: 18, IsActive: true}).Error; err != nil {
+			t.Fatal(err)
+		}
+		completed := time.Now()
+		if err := db.Create(&legacyDrip{Recipient: "0x0000000000000000000000000000000000000002", TokenID: "tst", Amount: "1.5", IPAddress: "192.0.2.1", Status: "completed", CompletedAt: &completed}).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		migrateAndCheck(t, db)
+
+		var drip models.Drip
+		if err := db.First(&drip).Error; err != nil {
+			t.Fatal(err)
+		}
+		if drip.AmountBaseUnits != "1500000000000000000" {
+			t.Errorf("amount_base_units = %q, want 1500000000000000000", drip.AmountBaseUnits)
+		}
+		if drip.UpdatedAt.IsZero() {
+			t.Error("updated_at was not backfilled")
+		}
+		var token models.Token
+		if err := db.First(&token, "id = ?", "tst").Error; err != nil {
+			t.Fatal(err)
+		}
+		if token.Distribution != models.DistributeTransfer {
+			t.Errorf("distribution = %q, want %q", token.Distribution, models.DistributeTransfer)
+		}
+
+		rollbackAndCheck(t, ctx, db)
+	})
+
+	t.Run("fresh", func(t *testing.T) {
+		db := openTestSchema(t, dsn)
+
+		migrateAndCheck(t, db)
+		rollbackAndCheck(t, context.Background(), db)
+	})
+}
+
+// openTestSchema connects to dsn with a new, empty schema first on the
+// search path and drops it when the test ends
+func openTestSchema(t *testing.T, dsn string) *gorm.DB {
+	t.Helper()
+	config := &gorm.Config{Logger: logger.Discard}
+
+	admin, err := gorm.Open(postgres.Open(dsn), config)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	if err := admin.Exec(`CREATE SCHEMA "` + schema + `"`).Error; err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(`DROP SCHEMA "` + schema + `" CASCADE`)
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	db, err := gorm.Open(postgres.Open(withSearchPath(dsn, schema)), config)
+	if err != nil {
+		t.Fatalf("connect to schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// withSearchPath sets search_path on a URL or key=value DSN
+func withSearchPath(dsn, schema string) string {
+	if u, err := url.Parse(dsn); err == nil && strings.HasPrefix(u.Scheme, "postgres") {
+		query := u.Query()
+		query.Set("search_path", schema)
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+	return dsn + " search_path=" + schema
+}
+
+func migrateAndCheck(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := Migrate(ctx, db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	// A second run has nothing left to apply
+	if err := Migrate(ctx, db); err != nil {
+		t.Fatalf("migrate again: %v", err)
+	}
+
+	statuses, err := Status(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			t.Errorf("migration %d_%s not applied", s.Version, s.Name)
+		}
+	}
+
+	// Every column the models read and write must exist
+	all := []interface{}{
+		&models.Token{}, &models.Drip{}, &models.DripBatch{}, &models.Identity{},
+		&models.InventoryItem{}, &models.DripRollup{}, &models.TreasuryTransfer{},
+		&models.WebhookEndpoint{}, &models.WebhookDelivery{}, &models.WebhookAttempt{},
+	}
+	for _, model := range all {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatal(err)
+		}
+		if !db.Migrator().HasTable(model) {
+			t.Errorf("table %s missing", stmt.Schema.Table)
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !db.Migrator().HasColumn(model, field.DBName) {
+				t.Errorf("column %s.%s missing", stmt.Schema.Table, field.DBName)
+			}
+		}
+	}
+}
+
+func rollbackAndCheck(t *testing.T, ctx context.Context, db *gorm.DB) {
+	t.Helper()
+
+	statuses, err := Status(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Rollback(ctx, db, len(statuses)); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+
+	var applied int64
+	if err := db.Table("schema_migrations").Count(&applied).Error; err != nil {
+		t.Fatal(err)
+	}
+	if applied != 0 {
+		t.Errorf("%d migrations still recorded after rollback", applied)
+	}
+	for _, table := range []string{"tokens", "drips", "identities", "webhook_endpoints", "webhook_deliveries", "webhook_attempts", "drip_batches", "treasury_transfers"} {
+		if db.Migrator().HasTable(table) {
+			t.Errorf("table %s left after rollback", table)
+		}
+	}
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "drips";
+DROP TABLE IF EXISTS "tokens";
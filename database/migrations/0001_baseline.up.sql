@@ -0,0 +1,42 @@
+-- Baseline: the tokens and drips tables GORM AutoMigrate created before
+-- versioned migrations. Every statement is IF NOT EXISTS so those databases
+-- adopt it unchanged; everything added since is a later migration.
+
+CREATE TABLE IF NOT EXISTS "tokens" (
+    "id" varchar(20),
+    "name" varchar(50) NOT NULL,
+    "symbol" varchar(10) NOT NULL,
+    "address" varchar(42),
+    "drip_amount" varchar(30) NOT NULL,
+    "cooldown_hours" bigint NOT NULL DEFAULT 24,
+    "decimals" bigint NOT NULL DEFAULT 18,
+    "logo_url" varchar(200),
+    "is_active" boolean DEFAULT true,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_tokens_deleted_at" ON "tokens" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "drips" (
+    "id" bigserial,
+    "recipient" varchar(42) NOT NULL,
+    "token_id" varchar(20) NOT NULL,
+    "amount" varchar(30) NOT NULL,
+    "tx_hash" varchar(66),
+    "ip_address" inet,
+    "fingerprint" varchar(64),
+    "status" varchar(20) DEFAULT 'pending',
+    "error" text,
+    "created_at" timestamptz,
+    "completed_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_drips_status" ON "drips" ("status");
+CREATE INDEX IF NOT EXISTS "idx_drips_fingerprint" ON "drips" ("fingerprint");
+CREATE INDEX IF NOT EXISTS "idx_drips_ip_address" ON "drips" ("ip_address");
+CREATE INDEX IF NOT EXISTS "idx_drips_tx_hash" ON "drips" ("tx_hash");
+CREATE INDEX IF NOT EXISTS "idx_recipient_token" ON "drips" ("recipient", "token_id");
+CREATE INDEX IF NOT EXISTS "idx_drips_deleted_at" ON "drips" ("deleted_at");
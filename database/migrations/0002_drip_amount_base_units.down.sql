@@ -0,0 +1 @@
+ALTER TABLE "drips" DROP COLUMN IF EXISTS "amount_base_units";
@@ -0,0 +1,10 @@
+ALTER TABLE "drips" ADD COLUMN IF NOT EXISTS "amount_base_units" numeric(78,0);
+
+-- Backfill from the human-readable amount and the token's decimals; numeric
+-- arithmetic is exact
+UPDATE "drips"
+SET "amount_base_units" = ("drips"."amount"::numeric * power(10::numeric, "tokens"."decimals"))::numeric(78,0)
+FROM "tokens"
+WHERE "tokens"."id" = "drips"."token_id"
+  AND "drips"."amount_base_units" IS NULL
+  AND "drips"."amount" ~ '^[0-9]+(\.[0-9]+)?$';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "idx_drips_created_at";
+DROP TABLE IF EXISTS "drip_rollups";
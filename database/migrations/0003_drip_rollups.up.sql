@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS "drip_rollups" (
+    "granularity" varchar(10),
+    "bucket_start" timestamptz,
+    "token_id" varchar(20),
+    "drips" bigint NOT NULL DEFAULT 0,
+    "completed" bigint NOT NULL DEFAULT 0,
+    "failed" bigint NOT NULL DEFAULT 0,
+    "unique_users" bigint NOT NULL DEFAULT 0,
+    "amount_base_units" numeric(78,0) NOT NULL DEFAULT 0,
+    "median_confirmation_ms" bigint,
+    "refreshed_at" timestamptz NOT NULL,
+    PRIMARY KEY ("granularity", "bucket_start", "token_id")
+);
+
+-- The rollup job scans drips by request time
+CREATE INDEX IF NOT EXISTS "idx_drips_created_at" ON "drips" ("created_at");
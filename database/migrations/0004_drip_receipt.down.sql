@@ -0,0 +1,2 @@
+ALTER TABLE "drips" DROP COLUMN IF EXISTS "gas_cost";
+ALTER TABLE "drips" DROP COLUMN IF EXISTS "block_number";
@@ -0,0 +1,2 @@
+ALTER TABLE "drips" ADD COLUMN IF NOT EXISTS "block_number" bigint;
+ALTER TABLE "drips" ADD COLUMN IF NOT EXISTS "gas_cost" numeric(78,0);
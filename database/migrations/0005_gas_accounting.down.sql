@@ -0,0 +1,6 @@
+ALTER TABLE "drip_rollups" DROP COLUMN IF EXISTS "gas_cost";
+ALTER TABLE "drip_rollups" DROP COLUMN IF EXISTS "gas_used";
+ALTER TABLE "drip_rollups" DROP COLUMN IF EXISTS "receipts";
+
+ALTER TABLE "drips" DROP COLUMN IF EXISTS "effective_gas_price";
+ALTER TABLE "drips" DROP COLUMN IF EXISTS "gas_used";
@@ -0,0 +1,9 @@
+ALTER TABLE "drips" ADD COLUMN IF NOT EXISTS "gas_used" bigint;
+ALTER TABLE "drips" ADD COLUMN IF NOT EXISTS "effective_gas_price" numeric(78,0);
+
+ALTER TABLE "drip_rollups" ADD COLUMN IF NOT EXISTS "receipts" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "drip_rollups" ADD COLUMN IF NOT EXISTS "gas_used" bigint NOT NULL DEFAULT 0;
+ALTER TABLE "drip_rollups" ADD COLUMN IF NOT EXISTS "gas_cost" numeric(78,0) NOT NULL DEFAULT 0;
+
+-- Rebuild every rollup so drips mined before this migration are counted
+TRUNCATE "drip_rollups";
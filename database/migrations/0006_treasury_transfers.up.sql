@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS "treasury_transfers" (
+    "id" bigserial,
+    "token_id" varchar(20) NOT NULL,
+    "kind" varchar(10) NOT NULL,
+    "from_address" varchar(42) NOT NULL,
+    "to_address" varchar(42) NOT NULL,
+    "amount_base_units" numeric(78,0) NOT NULL,
+    "tx_hash" varchar(66),
+    "status" varchar(20) DEFAULT 'pending',
+    "error" text,
+    "block_number" bigint,
+    "gas_cost" numeric(78,0),
+    "created_at" timestamptz,
+    "completed_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_treasury_transfers_token_id" ON "treasury_transfers" ("token_id");
+CREATE INDEX IF NOT EXISTS "idx_treasury_transfers_tx_hash" ON "treasury_transfers" ("tx_hash");
+CREATE INDEX IF NOT EXISTS "idx_treasury_transfers_status" ON "treasury_transfers" ("status");
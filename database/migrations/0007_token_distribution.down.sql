@@ -0,0 +1,2 @@
+ALTER TABLE "tokens" DROP COLUMN IF EXISTS "mint_selector";
+ALTER TABLE "tokens" DROP COLUMN IF EXISTS "distribution";
@@ -0,0 +1,4 @@
+ALTER TABLE "tokens" ADD COLUMN IF NOT EXISTS "distribution" varchar(10);
+ALTER TABLE "tokens" ADD COLUMN IF NOT EXISTS "mint_selector" varchar(10);
+UPDATE "tokens" SET "distribution" = CASE WHEN "address" IS NULL OR "address" = '' THEN 'native' ELSE 'transfer' END
+    WHERE "distribution" IS NULL OR "distribution" = '';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "inventory_items";
+ALTER TABLE "drips" DROP COLUMN IF EXISTS "nft_ids";
+ALTER TABLE "tokens" DROP COLUMN IF EXISTS "nft_id";
+ALTER TABLE "tokens" DROP COLUMN IF EXISTS "standard";
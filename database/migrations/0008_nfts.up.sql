@@ -0,0 +1,16 @@
+ALTER TABLE "tokens" ADD COLUMN IF NOT EXISTS "standard" varchar(10);
+ALTER TABLE "tokens" ADD COLUMN IF NOT EXISTS "nft_id" numeric(78,0);
+ALTER TABLE "drips" ADD COLUMN IF NOT EXISTS "nft_ids" text;
+CREATE TABLE IF NOT EXISTS "inventory_items" (
+    "id" bigserial,
+    "token_id" varchar(20) NOT NULL,
+    "nft_id" numeric(78,0) NOT NULL,
+    "status" varchar(20) NOT NULL DEFAULT 'available',
+    "drip_id" bigint,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_inventory_token_nft" ON "inventory_items" ("token_id", "nft_id");
+CREATE INDEX IF NOT EXISTS "idx_inventory_items_status" ON "inventory_items" ("status");
+CREATE INDEX IF NOT EXISTS "idx_inventory_items_drip_id" ON "inventory_items" ("drip_id");
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "idx_drips_batch_id";
+ALTER TABLE "drips" DROP COLUMN IF EXISTS "batch_id";
+DROP TABLE IF EXISTS "drip_batches";
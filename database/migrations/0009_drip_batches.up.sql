@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS "drip_batches" (
+    "id" bigserial,
+    "recipient" varchar(42) NOT NULL,
+    "bundle" varchar(20),
+    "token_ids" text NOT NULL,
+    "request_id" varchar(64),
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_drip_batches_recipient" ON "drip_batches" ("recipient");
+CREATE INDEX IF NOT EXISTS "idx_drip_batches_request_id" ON "drip_batches" ("request_id");
+CREATE INDEX IF NOT EXISTS "idx_drip_batches_created_at" ON "drip_batches" ("created_at");
+ALTER TABLE "drips" ADD COLUMN IF NOT EXISTS "batch_id" bigint;
+CREATE INDEX IF NOT EXISTS "idx_drips_batch_id" ON "drips" ("batch_id");
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "idx_drips_updated_at";
+ALTER TABLE "drips" DROP COLUMN IF EXISTS "updated_at";
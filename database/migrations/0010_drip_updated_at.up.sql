@@ -0,0 +1,3 @@
+ALTER TABLE "drips" ADD COLUMN IF NOT EXISTS "updated_at" timestamptz;
+UPDATE "drips" SET "updated_at" = COALESCE("completed_at", "created_at") WHERE "updated_at" IS NULL;
+CREATE INDEX IF NOT EXISTS "idx_drips_updated_at" ON "drips" ("updated_at");
@@ -0,0 +1 @@
+ALTER TABLE "drips" ALTER COLUMN "amount" TYPE varchar(30) USING left("amount", 30);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "idx_drips_identity_id";
+ALTER TABLE "drips" DROP COLUMN IF EXISTS "identity_id";
+DROP TABLE IF EXISTS "identities";
@@ -0,0 +1,20 @@
+-- Added to the schema by AutoMigrate after the baseline. IF NOT EXISTS lets
+-- databases that already have them, from AutoMigrate or an earlier
+-- baseline, adopt them unchanged.
+CREATE TABLE IF NOT EXISTS "identities" (
+    "id" varchar(64),
+    "provider" varchar(20) NOT NULL,
+    "provider_user_id" varchar(40) NOT NULL,
+    "login" varchar(100),
+    "account_created_at" timestamptz,
+    "last_login_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_identities_deleted_at" ON "identities" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_identities_provider" ON "identities" ("provider");
+
+ALTER TABLE "drips" ADD COLUMN IF NOT EXISTS "identity_id" varchar(64);
+CREATE INDEX IF NOT EXISTS "idx_drips_identity_id" ON "drips" ("identity_id");
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "idx_drips_request_id";
+ALTER TABLE "drips" DROP COLUMN IF EXISTS "request_id";
@@ -0,0 +1,2 @@
+ALTER TABLE "drips" ADD COLUMN IF NOT EXISTS "request_id" varchar(64);
+CREATE INDEX IF NOT EXISTS "idx_drips_request_id" ON "drips" ("request_id");
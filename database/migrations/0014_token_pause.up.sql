@@ -0,0 +1,2 @@
+ALTER TABLE "tokens" ADD COLUMN IF NOT EXISTS "paused_at" timestamptz;
+ALTER TABLE "tokens" ADD COLUMN IF NOT EXISTS "paused_reason" varchar(200);
@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS "webhook_endpoints" (
+    "id" bigserial,
+    "url" varchar(500) NOT NULL,
+    "secret" varchar(100) NOT NULL,
+    "events" varchar(200),
+    "token_ids" varchar(200),
+    "description" varchar(200),
+    "is_active" boolean DEFAULT true,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_webhook_endpoints_deleted_at" ON "webhook_endpoints" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "webhook_deliveries" (
+    "id" bigserial,
+    "endpoint_id" bigint NOT NULL,
+    "event_type" varchar(40) NOT NULL,
+    "payload" text NOT NULL,
+    "status" varchar(20) DEFAULT 'pending',
+    "attempts" bigint NOT NULL DEFAULT 0,
+    "next_attempt_at" timestamptz,
+    "last_error" text,
+    "delivered_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_delivery_due" ON "webhook_deliveries" ("status", "next_attempt_at");
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_endpoint_id" ON "webhook_deliveries" ("endpoint_id");
+
+CREATE TABLE IF NOT EXISTS "webhook_attempts" (
+    "id" bigserial,
+    "delivery_id" bigint NOT NULL,
+    "response_code" bigint,
+    "error" text,
+    "duration_ms" bigint,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_webhook_attempts_delivery_id" ON "webhook_attempts" ("delivery_id");
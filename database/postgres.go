@@ -1,39 +1,27 @@
 package database
 
 import (
-	"faucet-backend/models"
-	"log"
-	"os"
+	"fmt"
+	"log/slog"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
-var DB *gorm.DB
-
-func Connect() {
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		log.Fatal("DATABASE_URL not set")
-	}
-
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+// Connect opens a traced PostgreSQL connection. logLevel is a GORM log level
+// name (silent, error, warn, info).
+func Connect(dsn, logLevel string) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger: &gormLogger{level: parseGormLogLevel(logLevel)},
 	})
-
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	log.Println("✅ Connected to PostgreSQL")
-}
-
-func Migrate() {
-	err := DB.AutoMigrate(&models.Token{}, &models.Drip{})
-	if err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+	if err := db.Use(tracingPlugin{}); err != nil {
+		return nil, fmt.Errorf("failed to enable database tracing: %w", err)
 	}
-	log.Println("✅ Database migrated")
+
+	slog.Info("connected to PostgreSQL")
+	return db, nil
 }
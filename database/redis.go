@@ -2,31 +2,31 @@ package database
 
 import (
 	"context"
-	"log"
-	"os"
+	"fmt"
+	"log/slog"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 )
 
-var Redis *redis.Client
-
-func ConnectRedis() {
-	redisURL := os.Getenv("REDIS_URL")
-	if redisURL == "" {
-		log.Fatal("REDIS_URL not set")
-	}
-
+// ConnectRedis opens a traced Redis client and checks it responds
+func ConnectRedis(ctx context.Context, redisURL string) (*redis.Client, error) {
 	opt, err := redis.ParseURL(redisURL)
 	if err != nil {
-		log.Fatalf("Failed to parse Redis URL: %v", err)
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
 	}
 
-	Redis = redis.NewClient(opt)
+	client := redis.NewClient(opt)
+	if err := redisotel.InstrumentTracing(client); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to enable Redis tracing: %w", err)
+	}
 
-	ctx := context.Background()
-	if err := Redis.Ping(ctx).Err(); err != nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	log.Println("✅ Connected to Redis")
+	slog.Info("connected to Redis")
+	return client, nil
 }
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"faucet-backend/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "telemetry:span"
+
+// tracingPlugin opens a client span around every GORM operation, parented to
+// the span in the statement's context
+type tracingPlugin struct{}
+
+func (tracingPlugin) Name() string {
+	return "telemetry"
+}
+
+func (tracingPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register("telemetry:before_create", startSpan("create")),
+		cb.Create().After("gorm:create").Register("telemetry:after_create", endSpan),
+		cb.Query().Before("gorm:query").Register("telemetry:before_query", startSpan("select")),
+		cb.Query().After("gorm:query").Register("telemetry:after_query", endSpan),
+		cb.Update().Before("gorm:update").Register("telemetry:before_update", startSpan("update")),
+		cb.Update().After("gorm:update").Register("telemetry:after_update", endSpan),
+		cb.Delete().Before("gorm:delete").Register("telemetry:before_delete", startSpan("delete")),
+		cb.Delete().After("gorm:delete").Register("telemetry:after_delete", endSpan),
+		cb.Row().Before("gorm:row").Register("telemetry:before_row", startSpan("row")),
+		cb.Row().After("gorm:row").Register("telemetry:after_row", endSpan),
+		cb.Raw().Before("gorm:raw").Register("telemetry:before_raw", startSpan("raw")),
+		cb.Raw().After("gorm:raw").Register("telemetry:after_raw", endSpan),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func startSpan(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		ctx := tx.Statement.Context
+		if !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			// Don't start root traces for background queries
+			return
+		}
+
+		_, span := telemetry.Tracer().Start(ctx, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemPostgreSQL,
+				semconv.DBOperation(operation),
+			),
+		)
+		tx.InstanceSet(spanKey, span)
+	}
+}
+
+func endSpan(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+
+	span.SetAttributes(
+		semconv.DBSQLTable(tx.Statement.Table),
+		semconv.DBStatement(tx.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+	)
+
+	err := tx.Error
+	if err == gorm.ErrRecordNotFound {
+		err = nil
+	}
+	telemetry.End(span, err)
+}
@@ -0,0 +1,170 @@
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"faucet-backend/config"
+	"faucet-backend/models"
+	"faucet-backend/services"
+	"faucet-backend/units"
+)
+
+func TestDripAmountPolicies(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "faucet.yaml")
+	write := func(tstAmount string) {
+		t.Helper()
+		file := fmt.Sprintf(`
+limits:
+  ipDaily: 100
+  fingerprintDaily: 100
+tokens:
+  - id: eth
+    name: Ether
+    symbol: ETH
+    dripAmount: "0.5"
+    cooldownHours: 24
+    amount:
+      policy: demand
+      targetPerHour: 2
+      min: "0.1"
+      max: "1"
+  - id: tst
+    name: Test Token
+    symbol: TST
+    address: %q
+    dripAmount: "100"
+    cooldownHours: 24
+    amount:
+%s
+  - id: whale
+    name: Whale Token
+    symbol: WHALE
+    address: %q
+    dripAmount: "2000000"
+    cooldownHours: 24
+`, h.token.Hex(), tstAmount, h.token.Hex())
+		if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`      policy: balance
+      min: "1"`)
+	if _, err := config.LoadFile(path); err == nil || !strings.Contains(err.Error(), "percent: is required for the balance policy") {
+		t.Fatalf("balance policy without a percent: err = %v", err)
+	}
+
+	// 0.1% of the faucet's balance of a million TST
+	write(`      policy: balance
+      percent: "0.1"
+      min: "1"`)
+	h.app.Config.ConfigFile = path
+	if err := h.app.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		token      string
+		wantAmount string
+	}{
+		{"tst", "1000"},
+		{"tst", "999"},
+		// Under the target of 2 an hour the drip grows to max, then shrinks
+		// as requests pass it
+		{"eth", "1"},
+		{"eth", "1"},
+		{"eth", "0.5"},
+		{"eth", "0.333333333333333333"},
+	}
+	for i, tt := range tests {
+		recipient := testAddress(i + 1)
+		status, body := h.drip(dripCall{Address: recipient.Hex(), TokenID: tt.token, Captcha: captchaPass, Fingerprint: "fp-1"})
+		if status != http.StatusOK {
+			t.Fatalf("%s drip %d: status %d: %s", tt.token, i, status, body.Error)
+		}
+		drip := h.waitForDrip(body.DripID)
+		if drip.Status != "completed" || drip.Amount != tt.wantAmount {
+			t.Errorf("%s drip %d is %s for %s (%s), want completed for %s", tt.token, i, drip.Status, drip.Amount, drip.Error, tt.wantAmount)
+		}
+		if sent, err := units.Parse(drip.Amount, 18); err != nil || h.balanceOf(tt.token, recipient).Cmp(sent) != 0 {
+			t.Errorf("%s drip %d: recipient holds %s, drip recorded %s", tt.token, i, h.balanceOf(tt.token, recipient), drip.Amount)
+		}
+	}
+
+	// Failed drips sent nothing and don't count towards demand
+	for i := 0; i < 2; i++ {
+		if err := h.db.Create(&models.Drip{Recipient: testAddress(20 + i).Hex(), TokenID: "eth", Amount: "0.5", Status: "failed"}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The listing shows what the next drip sends
+	if err := h.app.TokenListing.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	_, listing := h.tokens("")
+	effective := make(map[string]services.TokenListingEntry)
+	for _, entry := range listing.Tokens {
+		effective[entry.ID] = entry
+	}
+	want := map[string]string{
+		"eth":   "demand/0.25",
+		"tst":   "balance/998.001",
+		"whale": "fixed/2000000",
+	}
+	for id, w := range want {
+		entry := effective[id]
+		if got := entry.AmountPolicy + "/" + entry.EffectiveAmount; got != w {
+			t.Errorf("%s listed as %s, want %s", id, got, w)
+		}
+	}
+
+	// The monitor holds a balance policy to its min, not the fixed amount:
+	// 20000 drips of 100 TST is more than the faucet holds, of 1 TST isn't
+	h.app.Monitor.MinDrips = 20000
+	h.app.Monitor.CheckAll(ctx)
+	if paused := pausedTokens(t, h); paused["tst"] || !paused["whale"] {
+		t.Errorf("paused tokens = %v, want whale but not tst", paused)
+	}
+
+	// A min the balance can't cover pauses the token rather than sending
+	// more than the faucet holds
+	write(`      policy: balance
+      percent: "0.1"
+      min: "2000000"`)
+	if err := h.app.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	status, body := h.drip(dripCall{Address: testAddress(9).Hex(), TokenID: "tst", Captcha: captchaPass, Fingerprint: "fp-2"})
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("tst drip under min: status %d (%s), want 503", status, body.Error)
+	}
+	h.app.Monitor.CheckAll(ctx)
+	if paused := pausedTokens(t, h); !paused["tst"] {
+		t.Errorf("paused tokens = %v, want tst paused under its min", paused)
+	}
+}
+
+// pausedTokens returns the IDs of the paused tokens
+func pausedTokens(t *testing.T, h *harness) map[string]bool {
+	t.Helper()
+
+	var tokens []models.Token
+	if err := h.db.Where("paused_at IS NOT NULL").Find(&tokens).Error; err != nil {
+		t.Fatal(err)
+	}
+	paused := make(map[string]bool, len(tokens))
+	for _, token := range tokens {
+		paused[token.ID] = true
+	}
+	return paused
+}
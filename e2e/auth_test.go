@@ -0,0 +1,205 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"faucet-backend/config"
+	"faucet-backend/models"
+	"faucet-backend/services"
+)
+
+// githubUser is an account the fake GitHub logs in for one code
+type githubUser struct {
+	ID        int64     `json:"id"`
+	Login     string    `json:"login"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// newFakeGitHub serves GitHub's token endpoint and user API, logging users
+// in by code; unknown codes are rejected the way GitHub rejects expired ones
+func newFakeGitHub(t *testing.T, users map[string]githubUser) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		code := r.FormValue("code")
+		if _, ok := users[code]; !ok {
+			json.NewEncoder(w).Encode(map[string]string{
+				"error":             "bad_verification_code",
+				"error_description": "The code passed is incorrect or expired.",
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "token-" + code})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer token-")
+		user, ok := users[code]
+		if !ok {
+			http.Error(w, "bad credentials", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(user)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOAuthLogin(t *testing.T) {
+	github := newFakeGitHub(t, map[string]githubUser{
+		"veteran": {ID: 1, Login: "veteran", CreatedAt: time.Now().AddDate(-2, 0, 0)},
+		"newbie":  {ID: 2, Login: "newbie", CreatedAt: time.Now().AddDate(0, 0, -1)},
+	})
+	h := newHarness(t, func(cfg *config.Config) {
+		cfg.OAuth = config.OAuthConfig{
+			MinAccountAge:      30 * 24 * time.Hour,
+			GitHubClientID:     "client",
+			GitHubClientSecret: "secret",
+			GitHubOAuthBaseURL: github.URL,
+			GitHubAPIBaseURL:   github.URL,
+		}
+	})
+	h.app.Limiter.SetLimits(services.Limits{IdentityDaily: 1})
+
+	status, body := h.login("veteran")
+	if status != http.StatusOK {
+		t.Fatalf("login: status %d: %v", status, body)
+	}
+	session, _ := body["token"].(string)
+
+	t.Run("session", func(t *testing.T) {
+		status, body := h.authGet("/api/auth/me", session)
+		identity, _ := body["identity"].(map[string]any)
+		if status != http.StatusOK || identity["login"] != "veteran" || identity["providerUserId"] != "1" {
+			t.Errorf("me: status %d: %v", status, body)
+		}
+
+		if status, _ := h.authGet("/api/auth/me", ""); status != http.StatusUnauthorized {
+			t.Errorf("me without a session: status %d, want 401", status)
+		}
+		if status, _ := h.authGet("/api/auth/me", "forged"); status != http.StatusUnauthorized {
+			t.Errorf("me with an unknown session: status %d, want 401", status)
+		}
+
+		var identities int64
+		h.db.Model(&models.Identity{}).Where("id = ?", "github:1").Count(&identities)
+		if identities != 1 {
+			t.Errorf("%d identities stored for github:1, want 1", identities)
+		}
+	})
+
+	t.Run("invalid state", func(t *testing.T) {
+		status, _ := h.authGet("/api/auth/github/callback?state=forged&code=veteran", "")
+		if status != http.StatusBadRequest {
+			t.Errorf("status %d, want 400", status)
+		}
+	})
+
+	t.Run("bad code", func(t *testing.T) {
+		if status, body := h.login("expired"); status != http.StatusBadGateway {
+			t.Errorf("status %d: %v, want 502", status, body)
+		}
+	})
+
+	t.Run("account too young", func(t *testing.T) {
+		status, body := h.login("newbie")
+		if status != http.StatusForbidden || !strings.Contains(fmt.Sprint(body["error"]), "at least 30 days") {
+			t.Errorf("status %d: %v, want 403 for account age", status, body)
+		}
+		if _, ok := body["token"]; ok {
+			t.Error("a session was issued for a too-young account")
+		}
+	})
+
+	t.Run("identity quota", func(t *testing.T) {
+		status, resp := h.drip(dripCall{Address: testAddress(1).Hex(), TokenID: "tst", Captcha: captchaPass, Fingerprint: "fp-1", Session: session})
+		if status != http.StatusOK {
+			t.Fatalf("first drip: status %d: %s", status, resp.Error)
+		}
+		if drip := h.waitForDrip(resp.DripID); drip.IdentityID != "github:1" {
+			t.Errorf("drip identity = %q, want github:1", drip.IdentityID)
+		}
+
+		// Another wallet and device on the same account
+		tests := []struct {
+			tokenID   string
+			wantError string
+		}{
+			{"tst", "Account cooldown"},
+			{"eth", "Account daily limit"},
+		}
+		for i, tt := range tests {
+			status, resp := h.drip(dripCall{Address: testAddress(2 + i).Hex(), TokenID: tt.tokenID, Captcha: captchaPass, Fingerprint: "fp-2", Session: session})
+			if status != http.StatusTooManyRequests || !strings.Contains(resp.Error, tt.wantError) {
+				t.Errorf("%s drip: status %d: %q, want 429 %q", tt.tokenID, status, resp.Error, tt.wantError)
+			}
+		}
+
+		// Logged out, the same wallet and device are still within their quotas
+		if status, resp := h.drip(dripCall{Address: testAddress(2).Hex(), TokenID: "eth", Captcha: captchaPass, Fingerprint: "fp-2"}); status != http.StatusOK {
+			t.Errorf("anonymous drip: status %d: %s", status, resp.Error)
+		}
+	})
+}
+
+// login runs the OAuth flow with the fake GitHub, returning the callback's
+// status and body
+func (h *harness) login(code string) (int, map[string]any) {
+	h.t.Helper()
+
+	resp := h.authRequest("/api/auth/github/login", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		h.t.Fatalf("login: status %d, want a redirect", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		h.t.Fatal(err)
+	}
+
+	query := url.Values{"state": {location.Query().Get("state")}, "code": {code}}
+	return h.authGet("/api/auth/github/callback?"+query.Encode(), "")
+}
+
+// authGet requests path with an optional session and decodes the JSON body
+func (h *harness) authGet(path, session string) (int, map[string]any) {
+	h.t.Helper()
+
+	resp := h.authRequest(path, session)
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(raw, &body); err != nil {
+		h.t.Fatalf("%s: %v: %s", path, err, raw)
+	}
+	return resp.StatusCode, body
+}
+
+func (h *harness) authRequest(path, session string) *http.Response {
+	h.t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if session != "" {
+		req.Header.Set("Authorization", "Bearer "+session)
+	}
+
+	resp, err := h.app.HTTP.Test(req, -1)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return resp
+}
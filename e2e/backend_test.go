@@ -0,0 +1,276 @@
+package e2e
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// simChainID is the chain ID go-ethereum's simulated backend always uses
+const simChainID = 1337
+
+// errNodeDown is returned by every call while the chain is down
+var errNodeDown = errors.New("node unavailable")
+
+// simChain is go-ethereum's simulated backend, mining every transaction into
+// its own block as soon as it's sent. It implements services.ChainClient.
+type simChain struct {
+	t       *testing.T
+	backend *backends.SimulatedBackend
+	// bank is a genesis account that Fund pays out of
+	bank *ecdsa.PrivateKey
+
+	// mu serialises sends so each is committed before the next, and keeps
+	// calls from background work still running at the end of the test off
+	// the stopped backend
+	mu     sync.RWMutex
+	closed bool
+
+	// down makes every call fail, as when the node is unreachable
+	down atomic.Bool
+}
+
+func newSimChain(t *testing.T) *simChain {
+	bank, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	supply := new(big.Int).Lsh(big.NewInt(1), 128)
+	backend := backends.NewSimulatedBackend(core.GenesisAlloc{
+		crypto.PubkeyToAddress(bank.PublicKey): {Balance: supply},
+	}, 30_000_000)
+	c := &simChain{t: t, backend: backend, bank: bank}
+	t.Cleanup(func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.closed = true
+		backend.Close()
+	})
+	return c
+}
+
+// acquire read-locks the chain for a call, failing while it's down or after
+// the test has ended
+func (c *simChain) acquire() error {
+	if c.down.Load() {
+		return errNodeDown
+	}
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return errNodeDown
+	}
+	return nil
+}
+
+// Fund sends wei to addr from the genesis bank
+func (c *simChain) Fund(addr common.Address, wei *big.Int) {
+	c.t.Helper()
+
+	if receipt := c.send(c.bank, &addr, wei, nil); receipt.Status != types.ReceiptStatusSuccessful {
+		c.t.Fatalf("funding %s failed", addr.Hex())
+	}
+}
+
+func (c *simChain) Balance(addr common.Address) *big.Int {
+	c.t.Helper()
+
+	balance, err := c.backend.BalanceAt(context.Background(), addr, nil)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return balance
+}
+
+// Deploy sends a contract creation from key and returns the contract address
+func (c *simChain) Deploy(key *ecdsa.PrivateKey, code []byte) common.Address {
+	c.t.Helper()
+
+	receipt := c.send(key, nil, nil, code)
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		c.t.Fatal("deploy reverted")
+	}
+	return receipt.ContractAddress
+}
+
+// Transact sends a contract call from key and fails the test if it reverts
+func (c *simChain) Transact(key *ecdsa.PrivateKey, to common.Address, data []byte) {
+	c.t.Helper()
+
+	if receipt := c.send(key, &to, nil, data); receipt.Status != types.ReceiptStatusSuccessful {
+		c.t.Fatalf("call to %s reverted", to.Hex())
+	}
+}
+
+// send signs and mines a transaction from key; to is nil for a contract
+// creation
+func (c *simChain) send(key *ecdsa.PrivateKey, to *common.Address, value *big.Int, data []byte) *types.Receipt {
+	c.t.Helper()
+	ctx := context.Background()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	nonce, err := c.backend.PendingNonceAt(ctx, from)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	gasPrice, err := c.backend.SuggestGasPrice(ctx)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	tx := types.MustSignNewTx(key, types.LatestSignerForChainID(big.NewInt(simChainID)), &types.LegacyTx{
+		Nonce:    nonce,
+		To:       to,
+		Value:    value,
+		Gas:      5_000_000,
+		GasPrice: gasPrice,
+		Data:     data,
+	})
+	if err := c.SendTransaction(ctx, tx); err != nil {
+		c.t.Fatalf("transaction failed: %v", err)
+	}
+
+	receipt, err := c.backend.TransactionReceipt(ctx, tx.Hash())
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return receipt
+}
+
+// Call runs a read-only call against the latest block
+func (c *simChain) Call(to common.Address, data []byte) ([]byte, error) {
+	return c.CallFrom(common.Address{}, to, data)
+}
+
+// CallFrom runs a read-only call from the given account
+func (c *simChain) CallFrom(from, to common.Address, data []byte) ([]byte, error) {
+	return c.backend.CallContract(context.Background(), ethereum.CallMsg{From: from, To: &to, Data: data}, nil)
+}
+
+func (c *simChain) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.mu.RUnlock()
+	return c.backend.BalanceAt(ctx, account, blockNumber)
+}
+
+func (c *simChain) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.mu.RUnlock()
+	return c.backend.CodeAt(ctx, account, blockNumber)
+}
+
+func (c *simChain) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	if err := c.acquire(); err != nil {
+		return 0, err
+	}
+	defer c.mu.RUnlock()
+	return c.backend.NonceAt(ctx, account, blockNumber)
+}
+
+func (c *simChain) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	if err := c.acquire(); err != nil {
+		return 0, err
+	}
+	defer c.mu.RUnlock()
+	return c.backend.PendingNonceAt(ctx, account)
+}
+
+func (c *simChain) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.mu.RUnlock()
+	return c.backend.CallContract(ctx, msg, blockNumber)
+}
+
+func (c *simChain) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.mu.RUnlock()
+	return c.backend.SuggestGasPrice(ctx)
+}
+
+// SendTransaction validates tx and mines it into its own block
+func (c *simChain) SendTransaction(ctx context.Context, tx *types.Transaction) (err error) {
+	if c.down.Load() {
+		return errNodeDown
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errNodeDown
+	}
+
+	// The backend panics on transactions a node's pool would reject, such
+	// as one the sender can't pay for
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid transaction: %v", r)
+		}
+	}()
+
+	if err := c.backend.SendTransaction(ctx, tx); err != nil {
+		return err
+	}
+	c.backend.Commit()
+	return nil
+}
+
+func (c *simChain) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.mu.RUnlock()
+	return c.backend.TransactionReceipt(ctx, txHash)
+}
+
+func (c *simChain) BlockNumber(ctx context.Context) (uint64, error) {
+	if err := c.acquire(); err != nil {
+		return 0, err
+	}
+	defer c.mu.RUnlock()
+	return c.backend.Blockchain().CurrentBlock().Number.Uint64(), nil
+}
+
+func (c *simChain) ChainID(ctx context.Context) (*big.Int, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.mu.RUnlock()
+	return big.NewInt(simChainID), nil
+}
+
+func (c *simChain) NetworkID(ctx context.Context) (*big.Int, error) {
+	return c.ChainID(ctx)
+}
+
+func (c *simChain) SyncProgress(ctx context.Context) (*ethereum.SyncProgress, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.mu.RUnlock()
+	return nil, nil
+}
+
+// Close is a no-op; the backend is stopped when the test ends, after every
+// wallet using it is closed
+func (c *simChain) Close() {}
@@ -0,0 +1,407 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"faucet-backend/config"
+	"faucet-backend/models"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	telegramToken = "tg-token"
+	discordToken  = "dc-token"
+)
+
+// chatReply is a message the bot sent, threaded under ReplyTo
+type chatReply struct {
+	ChatID  string
+	ReplyTo string
+	Text    string
+}
+
+// chatLog collects the bot's replies
+type chatLog struct {
+	mu      sync.Mutex
+	replies []chatReply
+}
+
+func (l *chatLog) add(reply chatReply) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.replies = append(l.replies, reply)
+}
+
+// wait returns the replies once there are n of them, grouped by the
+// message they answer
+func (l *chatLog) wait(t *testing.T, n int) map[string][]string {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		l.mu.Lock()
+		replies := append([]chatReply(nil), l.replies...)
+		l.mu.Unlock()
+
+		if len(replies) >= n {
+			byMessage := make(map[string][]string)
+			for _, reply := range replies {
+				byMessage[reply.ReplyTo] = append(byMessage[reply.ReplyTo], reply.Text)
+			}
+			return byMessage
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d bot replies, want %d: %+v", len(replies), n, replies)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// fakeTelegram serves the Bot API methods the bot uses. Messages queued
+// with send are returned by getUpdates in order.
+type fakeTelegram struct {
+	*httptest.Server
+	chatLog
+
+	mu      sync.Mutex
+	updates []map[string]any
+}
+
+func newFakeTelegram(t *testing.T) *fakeTelegram {
+	t.Helper()
+
+	f := &fakeTelegram{}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// send queues a text message from userID and returns its message ID
+func (f *fakeTelegram) send(userID int64, text string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := int64(len(f.updates) + 1)
+	f.updates = append(f.updates, map[string]any{
+		"update_id": id,
+		"message": map[string]any{
+			"message_id": id,
+			"text":       text,
+			"from":       map[string]any{"id": userID, "is_bot": false},
+			"chat":       map[string]any{"id": userID},
+		},
+	})
+	return strconv.FormatInt(id, 10)
+}
+
+func (f *fakeTelegram) serve(w http.ResponseWriter, r *http.Request) {
+	method, ok := strings.CutPrefix(r.URL.Path, "/bot"+telegramToken+"/")
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]any{"ok": false, "description": "Unauthorized"})
+		return
+	}
+
+	var params map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]any{"ok": false, "description": err.Error()})
+		return
+	}
+
+	switch method {
+	case "getUpdates":
+		offset, _ := params["offset"].(float64)
+
+		f.mu.Lock()
+		var updates []map[string]any
+		for _, update := range f.updates {
+			if float64(update["update_id"].(int64)) >= offset {
+				updates = append(updates, update)
+			}
+		}
+		f.mu.Unlock()
+
+		// Long-poll briefly when there's nothing new
+		if len(updates) == 0 {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": updates})
+
+	case "sendMessage":
+		reply := chatReply{}
+		reply.ChatID, _ = params["chat_id"].(string)
+		reply.ReplyTo, _ = params["reply_to_message_id"].(string)
+		reply.Text, _ = params["text"].(string)
+		f.add(reply)
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": map[string]any{}})
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]any{"ok": false, "description": "Not Found"})
+	}
+}
+
+// fakeDiscord serves a gateway that dispatches the queued messages once the
+// bot identifies, and the create message endpoint replies go to
+type fakeDiscord struct {
+	*httptest.Server
+	chatLog
+
+	t        *testing.T
+	messages []map[string]any
+}
+
+func newFakeDiscord(t *testing.T) *fakeDiscord {
+	t.Helper()
+
+	f := &fakeDiscord{t: t}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/gateway", f.gateway)
+	mux.HandleFunc("/api/channels/", f.createMessage)
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+// send queues a message from the account with the given snowflake ID in
+// channel 100 and returns its message ID. Messages must be queued before
+// the bot connects.
+func (f *fakeDiscord) send(authorID string, bot bool, text string) string {
+	id := strconv.Itoa(len(f.messages) + 1)
+	f.messages = append(f.messages, map[string]any{
+		"id":         id,
+		"channel_id": "100",
+		"content":    text,
+		"author":     map[string]any{"id": authorID, "bot": bot},
+	})
+	return id
+}
+
+func (f *fakeDiscord) gateway(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		f.t.Error(err)
+		return
+	}
+	defer conn.Close()
+
+	hello := map[string]any{"op": 10, "d": map[string]any{"heartbeat_interval": 60000}}
+	if err := conn.WriteJSON(hello); err != nil {
+		return
+	}
+
+	var identify struct {
+		Op   int `json:"op"`
+		Data struct {
+			Token string `json:"token"`
+		} `json:"d"`
+	}
+	if err := conn.ReadJSON(&identify); err != nil {
+		return
+	}
+	if identify.Op != 2 || identify.Data.Token != discordToken {
+		f.t.Errorf("gateway got op %d with token %q, want an identify", identify.Op, identify.Data.Token)
+		return
+	}
+
+	for i, msg := range f.messages {
+		dispatch := map[string]any{"op": 0, "s": i + 1, "t": "MESSAGE_CREATE", "d": msg}
+		if err := conn.WriteJSON(dispatch); err != nil {
+			return
+		}
+	}
+
+	// Hold the connection open until the bot closes it
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeDiscord) createMessage(w http.ResponseWriter, r *http.Request) {
+	channel, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/channels/"), "/messages")
+	if !ok || r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bot "+discordToken {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		Content   string `json:"content"`
+		Reference struct {
+			MessageID string `json:"message_id"`
+		} `json:"message_reference"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.add(chatReply{ChatID: channel, ReplyTo: body.Reference.MessageID, Text: body.Content})
+	w.Write([]byte("{}"))
+}
+
+// discordID returns a Discord snowflake for an account created at t
+func discordID(t time.Time) string {
+	return strconv.FormatInt((t.UnixMilli()-1420070400000)<<22, 10)
+}
+
+// startBot runs the app's bot until the test ends
+func (h *harness) startBot() {
+	h.t.Helper()
+
+	if h.app.Bot == nil {
+		h.t.Fatal("no bot platform configured")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	h.t.Cleanup(cancel)
+	h.app.Bot.Start(ctx)
+}
+
+// wantReplies checks the replies to one message, each against a prefix
+func wantReplies(t *testing.T, got map[string][]string, messageID, name string, want ...string) {
+	t.Helper()
+
+	replies := got[messageID]
+	if len(replies) != len(want) {
+		t.Errorf("%s: replies %q, want %d", name, replies, len(want))
+		return
+	}
+	for i := range want {
+		if !strings.HasPrefix(replies[i], want[i]) {
+			t.Errorf("%s: reply %q, want it to start with %q", name, replies[i], want[i])
+		}
+	}
+}
+
+func TestTelegramBot(t *testing.T) {
+	telegram := newFakeTelegram(t)
+	h := newHarness(t, func(cfg *config.Config) {
+		cfg.Bots = config.BotConfig{TelegramToken: telegramToken, TelegramAPIURL: telegram.URL}
+	})
+	recipient := testAddress(1).Hex()
+
+	usageMsg := telegram.send(7, "/faucet")
+	chatter := telegram.send(7, "gm")
+	dripMsg := telegram.send(7, "/faucet@FaucetBot "+recipient+" tst")
+	cooldownMsg := telegram.send(7, "/faucet "+testAddress(2).Hex()+" tst")
+	badAddress := telegram.send(8, "/faucet 0xnope tst")
+	badToken := telegram.send(8, "/faucet "+testAddress(3).Hex()+" nope")
+	h.startBot()
+
+	got := telegram.wait(t, 6)
+	wantReplies(t, got, usageMsg, "bare command", "Usage: /faucet <address> <token>")
+	wantReplies(t, got, chatter, "chatter")
+	wantReplies(t, got, dripMsg, "drip", "Sending 100 TST to "+recipient, "Your drip of 100 TST confirmed: 0x")
+	wantReplies(t, got, cooldownMsg, "second drip", "Account cooldown for this token")
+	wantReplies(t, got, badAddress, "invalid address", "That isn't a valid address")
+	wantReplies(t, got, badToken, "unknown token", "Unknown or inactive token")
+
+	if balance := h.balanceOf("tst", testAddress(1)); balance.Cmp(new(big.Int).Mul(big.NewInt(100), ether)) != 0 {
+		t.Errorf("recipient tst = %s, want 100", balance)
+	}
+	if balance := h.balanceOf("tst", testAddress(2)); balance.Sign() != 0 {
+		t.Errorf("cooled-down recipient tst = %s, want 0", balance)
+	}
+
+	t.Run("minimum account age", func(t *testing.T) {
+		telegram := newFakeTelegram(t)
+		h := newHarness(t, func(cfg *config.Config) {
+			cfg.Bots = config.BotConfig{TelegramToken: telegramToken, TelegramAPIURL: telegram.URL}
+			cfg.OAuth.MinAccountAge = 30 * 24 * time.Hour
+		})
+
+		msg := telegram.send(7, "/faucet "+recipient+" tst")
+		h.startBot()
+
+		got := telegram.wait(t, 1)
+		wantReplies(t, got, msg, "unknown account age", "Sorry, telegram doesn't share account age")
+		if balance := h.balanceOf("tst", testAddress(1)); balance.Sign() != 0 {
+			t.Errorf("recipient tst = %s, want 0", balance)
+		}
+	})
+
+	t.Run("symbols", func(t *testing.T) {
+		telegram := newFakeTelegram(t)
+		h := newHarness(t, func(cfg *config.Config) {
+			cfg.Bots = config.BotConfig{TelegramToken: telegramToken, TelegramAPIURL: telegram.URL}
+		})
+
+		var tst models.Token
+		if err := h.db.First(&tst, "id = ?", "tst").Error; err != nil {
+			t.Fatal(err)
+		}
+		for _, token := range []models.Token{
+			{ID: "tst-b", Name: "Test Token B", Symbol: "TSTB", Address: tst.Address, DripAmount: "1", CooldownHours: 24, Decimals: 18, IsActive: true},
+			{ID: "dup-a", Name: "Duplicate A", Symbol: "DUP", Address: tst.Address, DripAmount: "1", CooldownHours: 24, Decimals: 18, IsActive: true},
+			{ID: "dup-b", Name: "Duplicate B", Symbol: "DUP", Address: tst.Address, DripAmount: "1", CooldownHours: 24, Decimals: 18, IsActive: true},
+		} {
+			if err := h.db.Create(&token).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		bySymbol := telegram.send(7, "/faucet "+recipient+" tstb")
+		ambiguous := telegram.send(7, "/faucet "+recipient+" DUP")
+		h.startBot()
+
+		got := telegram.wait(t, 3)
+		wantReplies(t, got, bySymbol, "symbol", "Sending 1 TSTB to "+recipient, "Your drip of 1 TSTB confirmed: 0x")
+		wantReplies(t, got, ambiguous, "ambiguous symbol", "DUP matches several tokens (dup-a, dup-b), use the token ID.")
+	})
+}
+
+func TestDiscordBot(t *testing.T) {
+	discord := newFakeDiscord(t)
+	h := newHarness(t, func(cfg *config.Config) {
+		cfg.Bots = config.BotConfig{
+			DiscordToken:      discordToken,
+			DiscordGatewayURL: "ws" + strings.TrimPrefix(discord.URL, "http") + "/gateway",
+			DiscordAPIURL:     discord.URL + "/api",
+		}
+		cfg.OAuth.MinAccountAge = 30 * 24 * time.Hour
+	})
+
+	veteran := discordID(time.Now().AddDate(-3, 0, 0))
+	newcomer := discordID(time.Now().Add(-time.Hour))
+	recipient := testAddress(1).Hex()
+
+	dripMsg := discord.send(veteran, false, "/faucet "+recipient+" eth")
+	cooldownMsg := discord.send(veteran, false, "/FAUCET "+testAddress(2).Hex()+" eth")
+	botMsg := discord.send(veteran, true, "/faucet "+testAddress(3).Hex()+" eth")
+	youngMsg := discord.send(newcomer, false, "/faucet "+testAddress(4).Hex()+" eth")
+	h.startBot()
+
+	got := discord.wait(t, 4)
+	wantReplies(t, got, dripMsg, "drip", "Sending 0.5 ETH to "+recipient, "Your drip of 0.5 ETH confirmed: 0x")
+	wantReplies(t, got, cooldownMsg, "second drip", "Account cooldown for this token")
+	wantReplies(t, got, botMsg, "bot message")
+	wantReplies(t, got, youngMsg, "new account", "Sorry, your discord account is too new")
+
+	if balance := h.balanceOf("eth", testAddress(1)); balance.Cmp(new(big.Int).Div(ether, big.NewInt(2))) != 0 {
+		t.Errorf("recipient eth = %s, want 0.5 ETH", balance)
+	}
+	for _, n := range []int{2, 3, 4} {
+		if balance := h.balanceOf("eth", testAddress(n)); balance.Sign() != 0 {
+			t.Errorf("rejected recipient %d eth = %s, want 0", n, balance)
+		}
+	}
+}
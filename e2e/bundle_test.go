@@ -0,0 +1,222 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"faucet-backend/config"
+	"faucet-backend/models"
+)
+
+func TestBundleDrips(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "faucet.yaml")
+	write := func(bundle string) {
+		t.Helper()
+		file := fmt.Sprintf(`
+limits:
+  ipDaily: 100
+  fingerprintDaily: 100
+tokens:
+  - id: eth
+    name: Ether
+    symbol: ETH
+    dripAmount: "0.5"
+    cooldownHours: 24
+  - id: tst
+    name: Test Token
+    symbol: TST
+    address: %q
+    dripAmount: "100"
+    cooldownHours: 24
+bundles:
+  starter: %s
+`, h.token.Hex(), bundle)
+		if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("[eth, usdc]")
+	if _, err := config.LoadFile(path); err == nil || !strings.Contains(err.Error(), `"usdc" is not a token`) {
+		t.Fatalf("bundle of an unknown token: err = %v", err)
+	}
+
+	write("[eth, tst]")
+	h.app.Config.ConfigFile = path
+	if err := h.app.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := h.app.HTTP.Test(httptest.NewRequest(http.MethodGet, "/api/faucet/bundles", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listed struct {
+		Bundles map[string][]string `json:"bundles"`
+	}
+	json.NewDecoder(resp.Body).Decode(&listed)
+	resp.Body.Close()
+	if got := strings.Join(listed.Bundles["starter"], ","); got != "eth,tst" {
+		t.Errorf("starter bundle listed as %q, want eth,tst", got)
+	}
+
+	recipient := testAddress(1)
+	rejected := []struct {
+		name string
+		call bundleCall
+		want int
+	}{
+		{"bad captcha", bundleCall{Address: recipient.Hex(), Bundle: "starter", Captcha: "fail"}, http.StatusForbidden},
+		{"unknown bundle", bundleCall{Address: recipient.Hex(), Bundle: "nope", Captcha: captchaPass}, http.StatusBadRequest},
+		{"bundle and tokens", bundleCall{Address: recipient.Hex(), Bundle: "starter", TokenIDs: []string{"eth"}, Captcha: captchaPass}, http.StatusBadRequest},
+	}
+	for _, tt := range rejected {
+		if status, body := h.bundleDrip(tt.call); status != tt.want {
+			t.Errorf("%s: status %d (%s), want %d", tt.name, status, body.Error, tt.want)
+		}
+	}
+
+	// One CAPTCHA gets both tokens, sent under one batch
+	status, body := h.bundleDrip(bundleCall{Address: recipient.Hex(), Bundle: "starter", Captcha: captchaPass, Fingerprint: "fp-1"})
+	if status != http.StatusOK || !body.Success || body.BatchID == 0 || len(body.Results) != 2 {
+		t.Fatalf("starter bundle: status %d: %+v", status, body)
+	}
+	for _, result := range body.Results {
+		if !result.Success {
+			t.Fatalf("%s rejected: %s", result.TokenID, result.Error)
+		}
+		drip := h.waitForDrip(result.DripID)
+		if drip.Status != "completed" || drip.BatchID == nil || *drip.BatchID != body.BatchID {
+			t.Errorf("%s drip is %s in batch %v, want completed in %d", result.TokenID, drip.Status, drip.BatchID, body.BatchID)
+		}
+	}
+	if got := h.balanceOf("eth", recipient); got.Cmp(new(big.Int).Div(ether, big.NewInt(2))) != 0 {
+		t.Errorf("eth balance = %s, want 0.5 ETH", got)
+	}
+	if got := h.balanceOf("tst", recipient); got.Cmp(new(big.Int).Mul(big.NewInt(100), ether)) != 0 {
+		t.Errorf("tst balance = %s, want 100 TST", got)
+	}
+
+	var batch models.DripBatch
+	if err := h.db.First(&batch, body.BatchID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if batch.Bundle != "starter" || batch.TokenIDs != "eth,tst" {
+		t.Errorf("batch recorded %q with tokens %q, want starter with eth,tst", batch.Bundle, batch.TokenIDs)
+	}
+
+	// Limits apply per token: a second recipient who already had ETH only
+	// gets TST, and unknown tokens are rejected alone
+	other := testAddress(2)
+	if status, body := h.drip(dripCall{Address: other.Hex(), TokenID: "eth", Captcha: captchaPass, Fingerprint: "fp-2"}); status != http.StatusOK {
+		t.Fatalf("eth drip: status %d: %s", status, body.Error)
+	}
+	status, body = h.bundleDrip(bundleCall{Address: other.Hex(), TokenIDs: []string{"ETH", "tst", "nope"}, Captcha: captchaPass, Fingerprint: "fp-2"})
+	if status != http.StatusOK {
+		t.Fatalf("token list: status %d: %+v", status, body)
+	}
+	outcomes := make([]string, len(body.Results))
+	for i, result := range body.Results {
+		outcomes[i] = fmt.Sprintf("%s:%t", result.TokenID, result.Success)
+		if result.TokenID == "eth" && result.RetryAfter == 0 {
+			t.Errorf("eth rejection has no retryAfter: %+v", result)
+		}
+	}
+	if got := strings.Join(outcomes, ","); got != "eth:false,tst:true,nope:false" {
+		t.Errorf("token list outcomes = %s, want eth:false,tst:true,nope:false", got)
+	}
+
+	// Nothing left to give: the first rejection's status
+	status, body = h.bundleDrip(bundleCall{Address: other.Hex(), Bundle: "starter", Captcha: captchaPass, Fingerprint: "fp-2"})
+	if status != http.StatusTooManyRequests || body.Success {
+		t.Errorf("exhausted bundle: status %d: %+v", status, body)
+	}
+
+	// A send that fails stops the batch, and the next drip goes out on a
+	// nonce re-read from the node
+	third := testAddress(3)
+	h.chain.down.Store(true)
+	status, body = h.bundleDrip(bundleCall{Address: third.Hex(), Bundle: "starter", Captcha: captchaPass, Fingerprint: "fp-3"})
+	if status != http.StatusOK || len(body.Results) != 2 {
+		t.Fatalf("bundle with the node down: status %d: %+v", status, body)
+	}
+	errs := make([]string, len(body.Results))
+	for i, result := range body.Results {
+		drip := h.waitForDrip(result.DripID)
+		if drip.Status != "failed" {
+			t.Errorf("%s drip is %s with the node down, want failed", result.TokenID, drip.Status)
+		}
+		errs[i] = drip.Error
+	}
+	h.chain.down.Store(false)
+	if !strings.Contains(errs[1], "earlier drip in the batch failed") {
+		t.Errorf("second drip failed with %q, want it left unsent", errs[1])
+	}
+
+	status, dripped := h.drip(dripCall{Address: testAddress(4).Hex(), TokenID: "tst", Captcha: captchaPass, Fingerprint: "fp-4"})
+	if status != http.StatusOK {
+		t.Fatalf("drip after the node recovered: status %d: %s", status, dripped.Error)
+	}
+	if drip := h.waitForDrip(dripped.DripID); drip.Status != "completed" {
+		t.Errorf("drip after the node recovered is %s (%s), want completed", drip.Status, drip.Error)
+	}
+}
+
+type bundleCall struct {
+	Address     string   `json:"address"`
+	TokenIDs    []string `json:"tokenIds,omitempty"`
+	Bundle      string   `json:"bundle,omitempty"`
+	Captcha     string   `json:"captchaToken"`
+	Fingerprint string   `json:"fingerprint"`
+}
+
+type bundleResponse struct {
+	Success bool   `json:"success"`
+	BatchID uint   `json:"batchId"`
+	Error   string `json:"error"`
+	Results []struct {
+		TokenID    string `json:"tokenId"`
+		Success    bool   `json:"success"`
+		DripID     uint   `json:"dripId"`
+		Error      string `json:"error"`
+		RetryAfter int64  `json:"retryAfter"`
+	} `json:"results"`
+}
+
+// bundleDrip posts a multi-token drip request and returns the status code
+// and decoded body
+func (h *harness) bundleDrip(call bundleCall) (int, bundleResponse) {
+	h.t.Helper()
+
+	payload, err := json.Marshal(call)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/faucet/drip", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.app.HTTP.Test(req, -1)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var body bundleResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		h.t.Fatal(err)
+	}
+	return resp.StatusCode, body
+}
@@ -0,0 +1,88 @@
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"faucet-backend/models"
+)
+
+func TestReloadConfig(t *testing.T) {
+	h := newHarness(t)
+
+	// Drop eth and whale, change tst, add a new token and tighten the IP limit
+	path := filepath.Join(t.TempDir(), "faucet.yaml")
+	file := fmt.Sprintf(`
+limits:
+  ipDaily: 1
+tokens:
+  - id: tst
+    name: Test Token
+    symbol: TST
+    address: %q
+    dripAmount: "250"
+    cooldownHours: 12
+  - id: tst2
+    name: Second Test Token
+    symbol: TST2
+    address: %q
+    dripAmount: "1.5"
+    cooldownHours: 24
+`, h.token.Hex(), h.token.Hex())
+	if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h.app.Config.ConfigFile = path
+
+	if err := h.app.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var tokens []models.Token
+	if err := h.db.Order("id").Find(&tokens).Error; err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]models.Token)
+	for _, token := range tokens {
+		got[token.ID] = token
+	}
+
+	if got["eth"].IsActive {
+		t.Error("eth is still active after being removed from the config file")
+	}
+	if tst := got["tst"]; tst.DripAmount != "250" || tst.CooldownHours != 12 {
+		t.Errorf("tst = %s every %dh, want 250 every 12h", tst.DripAmount, tst.CooldownHours)
+	}
+	if tst2, ok := got["tst2"]; !ok || !tst2.IsActive {
+		t.Error("tst2 was not created")
+	}
+
+	// The new IP limit applies to the next request
+	status, resp := h.drip(dripCall{Address: testAddress(1).Hex(), TokenID: "tst", Captcha: captchaPass, Fingerprint: "fp-1"})
+	if status != http.StatusOK {
+		t.Fatalf("first drip: status %d: %s", status, resp.Error)
+	}
+	h.waitForDrip(resp.DripID)
+
+	status, resp = h.drip(dripCall{Address: testAddress(2).Hex(), TokenID: "tst2", Captcha: captchaPass, Fingerprint: "fp-2"})
+	if status != http.StatusTooManyRequests {
+		t.Fatalf("second drip: status %d, want %d", status, http.StatusTooManyRequests)
+	}
+
+	// An invalid file is rejected without changing anything
+	if err := os.WriteFile(path, []byte("tokens: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.app.Reload(context.Background()); err == nil {
+		t.Fatal("reload accepted a config file without tokens")
+	}
+	var active int64
+	h.db.Model(&models.Token{}).Where("is_active = true").Count(&active)
+	if active != 2 {
+		t.Errorf("%d active tokens after a rejected reload, want 2", active)
+	}
+}
@@ -0,0 +1,123 @@
+package e2e
+
+import (
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDrip(t *testing.T) {
+	tests := []struct {
+		name string
+		// before are requests that must be accepted and finish first
+		before     []dripCall
+		call       dripCall
+		wantStatus int
+		wantError  string
+		// wantDrip is the drip's final status for accepted requests
+		wantDrip string
+		// wantBalance is the recipient's balance afterwards, in base units
+		wantBalance *big.Int
+	}{
+		{
+			name:        "native drip succeeds",
+			call:        dripCall{Address: testAddress(1).Hex(), TokenID: "eth", Captcha: captchaPass, Fingerprint: "fp-1"},
+			wantStatus:  http.StatusOK,
+			wantDrip:    "completed",
+			wantBalance: new(big.Int).Div(ether, big.NewInt(2)),
+		},
+		{
+			name:        "erc20 drip succeeds",
+			call:        dripCall{Address: testAddress(1).Hex(), TokenID: "tst", Captcha: captchaPass, Fingerprint: "fp-1"},
+			wantStatus:  http.StatusOK,
+			wantDrip:    "completed",
+			wantBalance: new(big.Int).Mul(big.NewInt(100), ether),
+		},
+		{
+			name:        "erc20 transfer reverts",
+			call:        dripCall{Address: testAddress(1).Hex(), TokenID: "whale", Captcha: captchaPass, Fingerprint: "fp-1"},
+			wantStatus:  http.StatusOK,
+			wantDrip:    "failed",
+			wantBalance: new(big.Int),
+		},
+		{
+			name: "wallet cooldown",
+			before: []dripCall{
+				{Address: testAddress(1).Hex(), TokenID: "tst", Captcha: captchaPass, Fingerprint: "fp-1"},
+			},
+			call:       dripCall{Address: testAddress(1).Hex(), TokenID: "tst", Captcha: captchaPass, Fingerprint: "fp-2"},
+			wantStatus: http.StatusTooManyRequests,
+			wantError:  "Wallet cooldown",
+		},
+		{
+			name: "ip daily limit",
+			before: []dripCall{
+				{Address: testAddress(1).Hex(), TokenID: "tst", Captcha: captchaPass, Fingerprint: "fp-1"},
+				{Address: testAddress(2).Hex(), TokenID: "tst", Captcha: captchaPass, Fingerprint: "fp-2"},
+				{Address: testAddress(3).Hex(), TokenID: "tst", Captcha: captchaPass, Fingerprint: "fp-3"},
+			},
+			call:       dripCall{Address: testAddress(4).Hex(), TokenID: "tst", Captcha: captchaPass, Fingerprint: "fp-4"},
+			wantStatus: http.StatusTooManyRequests,
+			wantError:  "IP daily limit",
+		},
+		{
+			name: "fingerprint limit",
+			before: []dripCall{
+				{Address: testAddress(1).Hex(), TokenID: "tst", Captcha: captchaPass, Fingerprint: "fp-1"},
+				{Address: testAddress(2).Hex(), TokenID: "eth", Captcha: captchaPass, Fingerprint: "fp-1"},
+			},
+			call:       dripCall{Address: testAddress(3).Hex(), TokenID: "tst", Captcha: captchaPass, Fingerprint: "fp-1"},
+			wantStatus: http.StatusTooManyRequests,
+			wantError:  "Device limit",
+		},
+		{
+			name:        "captcha failure",
+			call:        dripCall{Address: testAddress(1).Hex(), TokenID: "tst", Captcha: "wrong", Fingerprint: "fp-1"},
+			wantStatus:  http.StatusForbidden,
+			wantError:   "CAPTCHA verification failed",
+			wantBalance: new(big.Int),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			h := newHarness(t)
+
+			for _, call := range tt.before {
+				status, resp := h.drip(call)
+				if status != http.StatusOK {
+					t.Fatalf("setup drip for %s: status %d: %s", call.TokenID, status, resp.Error)
+				}
+				h.waitForDrip(resp.DripID)
+			}
+
+			status, resp := h.drip(tt.call)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (error %q)", status, tt.wantStatus, resp.Error)
+			}
+			if !strings.Contains(resp.Error, tt.wantError) {
+				t.Errorf("error = %q, want it to contain %q", resp.Error, tt.wantError)
+			}
+
+			if tt.wantDrip != "" {
+				drip := h.waitForDrip(resp.DripID)
+				if drip.Status != tt.wantDrip {
+					t.Errorf("drip status = %s, want %s (error %q)", drip.Status, tt.wantDrip, drip.Error)
+				}
+				if drip.TxHash == "" {
+					t.Error("drip has no tx hash")
+				}
+			}
+
+			if tt.wantBalance != nil {
+				recipient := testAddress(1)
+				if got := h.balanceOf(tt.call.TokenID, recipient); got.Cmp(tt.wantBalance) != 0 {
+					t.Errorf("recipient balance = %s, want %s", got, tt.wantBalance)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package e2e
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/asm"
+)
+
+// erc20Runtime is a minimal ERC20 with balanceOf, transfer and an
+// unrestricted mint. Balances are stored at the holder's address as the slot
+// key; transfer reverts when the sender's balance is short. Both emit the
+// standard Transfer event. Mint and treasury tests deploy it because the
+// compiled token in tokenCode can't mint.
+const erc20Runtime = `
+	PUSH 0
+	CALLDATALOAD
+	PUSH 0xe0
+	SHR
+	DUP1
+	PUSH 0x70a08231 ;; balanceOf(address)
+	EQ
+	JUMPI @balanceOf
+	DUP1
+	PUSH 0xa9059cbb ;; transfer(address,uint256)
+	EQ
+	JUMPI @transfer
+	DUP1
+	PUSH 0x40c10f19 ;; mint(address,uint256)
+	EQ
+	JUMPI @mint
+	PUSH 0
+	DUP1
+	REVERT
+
+balanceOf:
+	PUSH 4
+	CALLDATALOAD
+	SLOAD
+	PUSH 0
+	MSTORE
+	PUSH 32
+	PUSH 0
+	RETURN
+
+transfer:
+	CALLER
+	SLOAD           ;; [balance]
+	PUSH 36
+	CALLDATALOAD    ;; [balance, amount]
+	DUP1
+	DUP3
+	LT              ;; balance < amount
+	JUMPI @insufficient
+	DUP1
+	DUP3
+	SUB
+	CALLER
+	SSTORE          ;; balances[caller] = balance - amount
+	PUSH 4
+	CALLDATALOAD
+	DUP1
+	SLOAD
+	DUP3
+	ADD
+	SWAP1
+	SSTORE          ;; balances[to] += amount
+	PUSH 0
+	MSTORE
+	PUSH 4
+	CALLDATALOAD
+	CALLER
+	PUSH 0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef ;; Transfer(address,address,uint256)
+	PUSH 32
+	PUSH 0
+	LOG3
+	PUSH 1
+	PUSH 0
+	MSTORE
+	PUSH 32
+	PUSH 0
+	RETURN
+
+insufficient:
+	PUSH 0
+	DUP1
+	REVERT
+
+mint:
+	PUSH 4
+	CALLDATALOAD
+	DUP1
+	SLOAD
+	PUSH 36
+	CALLDATALOAD
+	ADD
+	SWAP1
+	SSTORE          ;; balances[to] += amount
+	PUSH 36
+	CALLDATALOAD
+	PUSH 0
+	MSTORE
+	PUSH 4
+	CALLDATALOAD
+	PUSH 0
+	PUSH 0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef ;; Transfer(address,address,uint256)
+	PUSH 32
+	PUSH 0
+	LOG3
+	STOP
+`
+
+// erc20InitCode returns creation code that credits supply to the deployer
+// and installs erc20Runtime
+func erc20InitCode(supply *big.Int) ([]byte, error) {
+	runtime, err := compileAsm(erc20Runtime)
+	if err != nil {
+		return nil, err
+	}
+
+	// PUSH32 supply, CALLER, SSTORE, then copy the runtime that follows
+	// this 48-byte prefix into memory and return it
+	const prefixLen = 48
+	init := append([]byte{0x7f}, common.LeftPadBytes(supply.Bytes(), 32)...)
+	init = append(init, 0x33, 0x55)
+	init = append(init, 0x61, byte(len(runtime)>>8), byte(len(runtime)))
+	init = append(init, 0x80)
+	init = append(init, 0x61, 0, prefixLen)
+	init = append(init, 0x60, 0, 0x39)
+	init = append(init, 0x60, 0, 0xf3)
+	if len(init) != prefixLen {
+		return nil, fmt.Errorf("init prefix is %d bytes, expected %d", len(init), prefixLen)
+	}
+
+	return append(init, runtime...), nil
+}
+
+func compileAsm(source string) ([]byte, error) {
+	compiler := asm.NewCompiler(false)
+	compiler.Feed(asm.Lex([]byte(source), false))
+	out, errs := compiler.Compile()
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to compile contract: %v", errs)
+	}
+	return hex.DecodeString(out)
+}
@@ -0,0 +1,114 @@
+package e2e
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"faucet-backend/middleware"
+	"faucet-backend/services"
+)
+
+func TestExportDrips(t *testing.T) {
+	h := newHarness(t)
+
+	calls := []dripCall{
+		{Address: testAddress(1).Hex(), TokenID: "eth", Captcha: captchaPass, Fingerprint: "fp-1"},
+		{Address: testAddress(1).Hex(), TokenID: "whale", Captcha: captchaPass, Fingerprint: "fp-2"},
+	}
+	for _, call := range calls {
+		status, resp := h.drip(call)
+		if status != http.StatusOK {
+			t.Fatalf("drip %s: status %d: %s", call.TokenID, status, resp.Error)
+		}
+		h.waitForDrip(resp.DripID)
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		status, body := h.export("")
+		if status != http.StatusOK {
+			t.Fatalf("status %d, want 200", status)
+		}
+		records, err := csv.NewReader(body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 3 {
+			t.Fatalf("got %d records, want a header and 2 drips", len(records))
+		}
+
+		header := make(map[string]int)
+		for i, column := range records[0] {
+			header[column] = i
+		}
+		eth := records[1]
+		if eth[header["token_symbol"]] != "ETH" || eth[header["amount_base_units"]] != "500000000000000000" {
+			t.Errorf("eth record = %v", eth)
+		}
+		if eth[header["block_number"]] == "" || eth[header["gas_used"]] != "21000" || eth[header["gas_cost_wei"]] == "" {
+			t.Errorf("eth record has no receipt data: %v", eth)
+		}
+		if whale := records[2]; whale[header["status"]] != "failed" || whale[header["token_symbol"]] != "WHALE" {
+			t.Errorf("whale record = %v", whale)
+		}
+	})
+
+	t.Run("ndjson filtered", func(t *testing.T) {
+		status, body := h.export("?format=ndjson&tokenId=eth&status=completed")
+		if status != http.StatusOK {
+			t.Fatalf("status %d, want 200", status)
+		}
+
+		var rows []services.DripExportRow
+		scanner := bufio.NewScanner(body)
+		for scanner.Scan() {
+			var row services.DripExportRow
+			if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+				t.Fatal(err)
+			}
+			rows = append(rows, row)
+		}
+		if len(rows) != 1 || rows[0].TokenID != "eth" || rows[0].BlockNumber == nil {
+			t.Errorf("rows = %+v, want the completed eth drip", rows)
+		}
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		if status, _ := h.export("?format=xlsx"); status != http.StatusBadRequest {
+			t.Errorf("status %d, want 400", status)
+		}
+	})
+}
+
+// adminGet requests an admin route and returns the status code and body
+func (h *harness) adminGet(path string) (int, []byte) {
+	h.t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set(middleware.HeaderAdminKey, adminKey)
+
+	resp, err := h.app.HTTP.Test(req, -1)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return resp.StatusCode, body
+}
+
+// export requests a drip export with the given query string
+func (h *harness) export(query string) (int, io.Reader) {
+	h.t.Helper()
+
+	status, body := h.adminGet("/api/admin/drips/export" + query)
+	return status, bytes.NewReader(body)
+}
@@ -0,0 +1,261 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"faucet-backend/app"
+	"faucet-backend/config"
+	"faucet-backend/models"
+	"faucet-backend/services"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	// captchaPass is the only CAPTCHA response the fake provider accepts
+	captchaPass = "pass"
+
+	// adminKey is the X-Admin-Key admin routes accept
+	adminKey = "test-admin-key"
+
+	// Test token supply and drip amounts, in whole tokens with 18 decimals
+	tokenSupply = 1_000_000
+)
+
+var ether = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+
+// harness is the full Fiber app wired to a simulated chain with a deployed ERC20,
+// miniredis, SQLite and a fake CAPTCHA provider
+type harness struct {
+	t     *testing.T
+	app   *app.App
+	chain *simChain
+	db    *gorm.DB
+	// token is the deployed ERC20
+	token common.Address
+}
+
+// newHarness builds the harness; configure, if given, adjusts the
+// configuration before the app is wired
+func newHarness(t *testing.T, configure ...func(cfg *config.Config)) *harness {
+	t.Helper()
+	ctx := context.Background()
+
+	chain := newSimChain(t)
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.Fund(crypto.PubkeyToAddress(key.PublicKey), new(big.Int).Mul(big.NewInt(100), ether))
+
+	initCode, err := tokenInitCode(new(big.Int).Mul(big.NewInt(tokenSupply), ether), "Test Token", 18, "TST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := chain.Deploy(key, initCode)
+
+	db := newTestDB(t)
+	seedTestTokens(t, db, token)
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	wallet, err := services.NewWalletWithClient(ctx, chain, hex.EncodeToString(crypto.FromECDSA(key)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	captcha := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]bool{
+			"success": r.FormValue("response") == captchaPass,
+		})
+	}))
+	t.Cleanup(captcha.Close)
+
+	cfg := &config.Config{
+		ChainID:              big.NewInt(simChainID).String(),
+		MinFaucetBalance:     "0.1",
+		CaptchaSecretKey:     "test-secret",
+		CaptchaVerifyURL:     captcha.URL,
+		AdminAPIKey:          adminKey,
+		BalanceCheckInterval: time.Minute,
+		BalanceMinDrips:      10,
+	}
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	a := app.Build(cfg, &app.Clients{DB: db, Redis: rdb, Wallet: wallet})
+	a.Sender.PollInterval = 10 * time.Millisecond
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := a.Shutdown(ctx); err != nil {
+			t.Error(err)
+		}
+		a.Close()
+	})
+
+	return &harness{t: t, app: a, chain: chain, db: db, token: token}
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "faucet.db")), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Drips are sent from background goroutines; a single connection keeps
+	// SQLite from reporting the database as locked
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	// The SQL migrations are Postgres-only, so SQLite gets its schema from
+	// the models
+	err = db.AutoMigrate(
+		&models.Token{},
+		&models.Drip{},
+		&models.DripBatch{},
+		&models.Identity{},
+		&models.WebhookEndpoint{},
+		&models.WebhookDelivery{},
+		&models.WebhookAttempt{},
+		&models.DripRollup{},
+		&models.TreasuryTransfer{},
+		&models.InventoryItem{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// seedTestTokens adds native ETH, the deployed ERC20 and "whale", a token on
+// the same contract whose drip exceeds the faucet's balance so the transfer
+// reverts on-chain
+func seedTestTokens(t *testing.T, db *gorm.DB, token common.Address) {
+	t.Helper()
+
+	tokens := []models.Token{
+		{ID: "eth", Name: "Ether", Symbol: "ETH", DripAmount: "0.5", CooldownHours: 24, Decimals: 18, IsActive: true},
+		{ID: "tst", Name: "Test Token", Symbol: "TST", Address: token.Hex(), DripAmount: "100", CooldownHours: 24, Decimals: 18, IsActive: true},
+		{ID: "whale", Name: "Whale Token", Symbol: "WHALE", Address: token.Hex(), DripAmount: "2000000", CooldownHours: 24, Decimals: 18, IsActive: true},
+	}
+	if err := db.Create(&tokens).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+type dripCall struct {
+	Address     string `json:"address"`
+	TokenID     string `json:"tokenId"`
+	Captcha     string `json:"captchaToken"`
+	Fingerprint string `json:"fingerprint"`
+	// Session is sent as the bearer token of a logged-in identity
+	Session string `json:"-"`
+}
+
+type dripResponse struct {
+	Success bool   `json:"success"`
+	DripID  uint   `json:"dripId"`
+	Error   string `json:"error"`
+}
+
+// drip posts a drip request and returns the status code and decoded body
+func (h *harness) drip(call dripCall) (int, dripResponse) {
+	h.t.Helper()
+
+	payload, err := json.Marshal(call)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/faucet/drip", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if call.Session != "" {
+		req.Header.Set("Authorization", "Bearer "+call.Session)
+	}
+
+	resp, err := h.app.HTTP.Test(req, -1)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var body dripResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		h.t.Fatal(err)
+	}
+	return resp.StatusCode, body
+}
+
+// waitForDrip waits for the drip to be confirmed or fail
+func (h *harness) waitForDrip(id uint) models.Drip {
+	h.t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		var drip models.Drip
+		if err := h.db.First(&drip, id).Error; err != nil {
+			h.t.Fatal(err)
+		}
+		if drip.CompletedAt != nil || drip.Status == "failed" {
+			return drip
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	h.t.Fatalf("drip %d did not finish", id)
+	return models.Drip{}
+}
+
+// balanceOf returns addr's on-chain balance of tokenID in base units
+func (h *harness) balanceOf(tokenID string, addr common.Address) *big.Int {
+	h.t.Helper()
+
+	if tokenID == "eth" {
+		return h.chain.Balance(addr)
+	}
+
+	return h.erc20Balance(h.token, addr)
+}
+
+// erc20Balance returns addr's balance of the ERC20 at token in base units
+func (h *harness) erc20Balance(token, addr common.Address) *big.Int {
+	h.t.Helper()
+
+	data := append(crypto.Keccak256([]byte("balanceOf(address)"))[:4], common.LeftPadBytes(addr.Bytes(), 32)...)
+	ret, err := h.chain.Call(token, data)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return new(big.Int).SetBytes(ret)
+}
+
+// testAddress returns a deterministic, distinct recipient address
+func testAddress(n int) common.Address {
+	return common.BigToAddress(big.NewInt(int64(0x1000 + n)))
+}
@@ -0,0 +1,146 @@
+package e2e
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"faucet-backend/config"
+	"faucet-backend/models"
+	"faucet-backend/services"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestMintDistribution(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.chain.Fund(crypto.PubkeyToAddress(key.PublicKey), ether)
+
+	// A token with no supply, so every drip has to be minted
+	initCode, err := erc20InitCode(new(big.Int))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mintable := h.chain.Deploy(key, initCode)
+
+	path := filepath.Join(t.TempDir(), "faucet.yaml")
+	write := func(extra string) {
+		t.Helper()
+		file := fmt.Sprintf(`
+tokens:
+  - id: eth
+    name: Ether
+    symbol: ETH
+    dripAmount: "0.5"
+    cooldownHours: 24
+  - id: dev
+    name: Devnet Token
+    symbol: DEV
+    address: %q
+    distribution: mint
+    dripAmount: "25"
+    cooldownHours: 24
+%s`, mintable.Hex(), extra)
+		if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Mint tokens have no balance to refill
+	write(`    refill:
+      lowWater: "10"
+      highWater: "100"
+`)
+	if _, err := config.LoadFile(path); err == nil || !strings.Contains(err.Error(), "mint tokens hold no balance") {
+		t.Fatalf("refill on a mint token: err = %v", err)
+	}
+
+	write("")
+	h.app.Config.ConfigFile = path
+	if err := h.app.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var token models.Token
+	if err := h.db.First(&token, "id = ?", "dev").Error; err != nil {
+		t.Fatal(err)
+	}
+	if token.DistributionMode() != models.DistributeMint || token.MintSelector != "" {
+		t.Fatalf("dev distribution = %q, selector = %q", token.Distribution, token.MintSelector)
+	}
+	if err := h.app.TokenMetadata.VerifyMinter(ctx, &token); err != nil {
+		t.Fatalf("verify minter: %v", err)
+	}
+
+	status, body := h.drip(dripCall{Address: testAddress(1).Hex(), TokenID: "dev", Captcha: captchaPass, Fingerprint: "fp-1"})
+	if status != http.StatusOK {
+		t.Fatalf("status %d: %s", status, body.Error)
+	}
+	if drip := h.waitForDrip(body.DripID); drip.Status != "completed" {
+		t.Fatalf("drip is %s (%s), want completed", drip.Status, drip.Error)
+	}
+	want := new(big.Int).Mul(big.NewInt(25), ether)
+	if got := h.erc20Balance(mintable, testAddress(1)); got.Cmp(want) != 0 {
+		t.Errorf("recipient balance = %s, want %s", got, want)
+	}
+	if got := h.erc20Balance(mintable, common.HexToAddress(h.app.Clients.Wallet.Address())); got.Sign() != 0 {
+		t.Errorf("faucet balance = %s, want 0", got)
+	}
+
+	// A selector the contract doesn't implement fails verification
+	write(`    mintSelector: "mintTo(address,uint256)"
+`)
+	if err := h.app.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.db.First(&token, "id = ?", "dev").Error; err != nil {
+		t.Fatal(err)
+	}
+	if want := "0x" + fmt.Sprintf("%x", crypto.Keccak256([]byte("mintTo(address,uint256)"))[:4]); token.MintSelector != want {
+		t.Fatalf("selector = %q, want %q", token.MintSelector, want)
+	}
+	if err := h.app.TokenMetadata.VerifyMinter(ctx, &token); !errors.Is(err, services.ErrCannotMint) {
+		t.Fatalf("verify minter with a wrong selector: err = %v", err)
+	}
+
+	// Validation pauses the token until the wallet can mint again
+	paused := func() *models.Token {
+		t.Helper()
+		if _, err := h.app.TokenMetadata.ValidateTokens(ctx); err != nil {
+			t.Fatal(err)
+		}
+		var token models.Token
+		if err := h.db.First(&token, "id = ?", "dev").Error; err != nil {
+			t.Fatal(err)
+		}
+		return &token
+	}
+	if token := paused(); token.PausedAt == nil || token.PausedReason != services.MintPauseReason {
+		t.Fatalf("unmintable token paused at %v (%q), want paused", token.PausedAt, token.PausedReason)
+	}
+	h.app.Monitor.CheckAll(ctx)
+	if status, body := h.drip(dripCall{Address: testAddress(2).Hex(), TokenID: "dev", Captcha: captchaPass, Fingerprint: "fp-2"}); status != http.StatusServiceUnavailable {
+		t.Errorf("drip of an unmintable token: status %d (%s), want 503", status, body.Error)
+	}
+
+	write("")
+	if err := h.app.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if token := paused(); token.PausedAt != nil {
+		t.Errorf("mintable token still paused: %q", token.PausedReason)
+	}
+}
@@ -0,0 +1,322 @@
+package e2e
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// erc721Runtime is a minimal ERC-721 with balanceOf, ownerOf, the 3-argument
+// safeTransferFrom (without the receiver check) and an unrestricted
+// safeMint(to) that numbers tokens from 1. Owners are stored at the token ID
+// as the slot key, balances at the holder's address and the last minted ID
+// at slot 2^256-1. Transfers and mints emit Transfer with the ID indexed.
+const erc721Runtime = `
+	PUSH 0
+	CALLDATALOAD
+	PUSH 0xe0
+	SHR
+	DUP1
+	PUSH 0x70a08231 ;; balanceOf(address)
+	EQ
+	JUMPI @balanceOf
+	DUP1
+	PUSH 0x6352211e ;; ownerOf(uint256)
+	EQ
+	JUMPI @ownerOf
+	DUP1
+	PUSH 0x42842e0e ;; safeTransferFrom(address,address,uint256)
+	EQ
+	JUMPI @transfer
+	DUP1
+	PUSH 0x40d097c3 ;; safeMint(address)
+	EQ
+	JUMPI @mint
+	PUSH 0
+	DUP1
+	REVERT
+
+balanceOf:
+	PUSH 4
+	CALLDATALOAD
+	SLOAD
+	PUSH 0
+	MSTORE
+	PUSH 32
+	PUSH 0
+	RETURN
+
+ownerOf:
+	PUSH 4
+	CALLDATALOAD
+	SLOAD           ;; [owner]
+	DUP1
+	ISZERO
+	JUMPI @fail
+	PUSH 0
+	MSTORE
+	PUSH 32
+	PUSH 0
+	RETURN
+
+transfer:
+	PUSH 68
+	CALLDATALOAD
+	SLOAD
+	CALLER
+	EQ
+	ISZERO
+	JUMPI @fail     ;; owners[id] != caller
+	PUSH 4
+	CALLDATALOAD
+	CALLER
+	EQ
+	ISZERO
+	JUMPI @fail     ;; from != caller
+	PUSH 36
+	CALLDATALOAD
+	PUSH 68
+	CALLDATALOAD
+	SSTORE          ;; owners[id] = to
+	CALLER
+	SLOAD
+	PUSH 1
+	SWAP1
+	SUB
+	CALLER
+	SSTORE          ;; balances[caller]--
+	PUSH 36
+	CALLDATALOAD
+	DUP1
+	SLOAD
+	PUSH 1
+	ADD
+	SWAP1
+	SSTORE          ;; balances[to]++
+	PUSH 68
+	CALLDATALOAD
+	PUSH 36
+	CALLDATALOAD
+	CALLER
+	PUSH 0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef ;; Transfer(address,address,uint256)
+	PUSH 0
+	PUSH 0
+	LOG4
+	STOP
+
+mint:
+	PUSH 0
+	NOT
+	DUP1
+	SLOAD
+	PUSH 1
+	ADD             ;; [counterSlot, id]
+	DUP1
+	SWAP2
+	SSTORE          ;; counter = id
+	PUSH 4
+	CALLDATALOAD
+	DUP2
+	SSTORE          ;; owners[id] = to
+	PUSH 4
+	CALLDATALOAD
+	DUP1
+	SLOAD
+	PUSH 1
+	ADD
+	SWAP1
+	SSTORE          ;; balances[to]++
+	PUSH 4
+	CALLDATALOAD
+	PUSH 0
+	PUSH 0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef ;; Transfer(address,address,uint256)
+	PUSH 0
+	PUSH 0
+	LOG4
+	STOP
+
+fail:
+	PUSH 0
+	DUP1
+	REVERT
+`
+
+// erc1155Runtime is a minimal ERC-1155 with balanceOf, safeTransferFrom
+// (without the receiver check) and an unrestricted
+// mint(to, id, amount, data). Balances are stored at keccak256(holder, id).
+// Both emit TransferSingle.
+const erc1155Runtime = `
+	PUSH 0
+	CALLDATALOAD
+	PUSH 0xe0
+	SHR
+	DUP1
+	PUSH 0x00fdd58e ;; balanceOf(address,uint256)
+	EQ
+	JUMPI @balanceOf
+	DUP1
+	PUSH 0xf242432a ;; safeTransferFrom(address,address,uint256,uint256,bytes)
+	EQ
+	JUMPI @transfer
+	DUP1
+	PUSH 0x731133e9 ;; mint(address,uint256,uint256,bytes)
+	EQ
+	JUMPI @mint
+	PUSH 0
+	DUP1
+	REVERT
+
+balanceOf:
+	PUSH 4
+	CALLDATALOAD
+	PUSH 0
+	MSTORE
+	PUSH 36
+	CALLDATALOAD
+	PUSH 32
+	MSTORE
+	PUSH 64
+	PUSH 0
+	KECCAK256
+	SLOAD
+	PUSH 0
+	MSTORE
+	PUSH 32
+	PUSH 0
+	RETURN
+
+transfer:
+	PUSH 4
+	CALLDATALOAD
+	CALLER
+	EQ
+	ISZERO
+	JUMPI @fail     ;; from != caller
+	CALLER
+	PUSH 0
+	MSTORE
+	PUSH 68
+	CALLDATALOAD
+	PUSH 32
+	MSTORE
+	PUSH 64
+	PUSH 0
+	KECCAK256       ;; [fromSlot]
+	DUP1
+	SLOAD
+	PUSH 100
+	CALLDATALOAD    ;; [fromSlot, balance, amount]
+	DUP1
+	DUP3
+	LT
+	JUMPI @fail     ;; balance < amount
+	SWAP1
+	SUB
+	SWAP1
+	SSTORE          ;; balances[from][id] -= amount
+	PUSH 36
+	CALLDATALOAD
+	PUSH 0
+	MSTORE
+	PUSH 64
+	PUSH 0
+	KECCAK256
+	DUP1
+	SLOAD
+	PUSH 100
+	CALLDATALOAD
+	ADD
+	SWAP1
+	SSTORE          ;; balances[to][id] += amount
+	PUSH 68
+	CALLDATALOAD
+	PUSH 0
+	MSTORE
+	PUSH 100
+	CALLDATALOAD
+	PUSH 32
+	MSTORE
+	PUSH 36
+	CALLDATALOAD
+	CALLER
+	CALLER
+	PUSH 0xc3d58168c5ae7397731d063d5bbf3d657854427343f4c083240f7aacaa2d0f62 ;; TransferSingle(address,address,address,uint256,uint256)
+	PUSH 64
+	PUSH 0
+	LOG4
+	STOP
+
+mint:
+	PUSH 4
+	CALLDATALOAD
+	PUSH 0
+	MSTORE
+	PUSH 36
+	CALLDATALOAD
+	PUSH 32
+	MSTORE
+	PUSH 64
+	PUSH 0
+	KECCAK256
+	DUP1
+	SLOAD
+	PUSH 68
+	CALLDATALOAD
+	ADD
+	SWAP1
+	SSTORE          ;; balances[to][id] += amount
+	PUSH 36
+	CALLDATALOAD
+	PUSH 0
+	MSTORE
+	PUSH 68
+	CALLDATALOAD
+	PUSH 32
+	MSTORE
+	PUSH 4
+	CALLDATALOAD
+	PUSH 0
+	CALLER
+	PUSH 0xc3d58168c5ae7397731d063d5bbf3d657854427343f4c083240f7aacaa2d0f62 ;; TransferSingle(address,address,address,uint256,uint256)
+	PUSH 64
+	PUSH 0
+	LOG4
+	STOP
+
+fail:
+	PUSH 0
+	DUP1
+	REVERT
+`
+
+// runtimeInitCode returns creation code that installs the compiled runtime
+func runtimeInitCode(source string) ([]byte, error) {
+	runtime, err := compileAsm(source)
+	if err != nil {
+		return nil, err
+	}
+
+	// PUSH2 len, DUP1, PUSH1 prefixLen, PUSH1 0, CODECOPY, PUSH1 0, RETURN
+	const prefixLen = 12
+	init := []byte{0x61, byte(len(runtime) >> 8), byte(len(runtime)), 0x80, 0x60, prefixLen, 0x60, 0, 0x39, 0x60, 0, 0xf3}
+	if len(init) != prefixLen {
+		return nil, fmt.Errorf("init prefix is %d bytes, expected %d", len(init), prefixLen)
+	}
+	return append(init, runtime...), nil
+}
+
+// call encodes a call to signature with 32-byte arguments
+func call(signature string, args ...[]byte) []byte {
+	data := crypto.Keccak256([]byte(signature))[:4]
+	for _, arg := range args {
+		data = append(data, common.LeftPadBytes(arg, 32)...)
+	}
+	return data
+}
+
+// word encodes n as a 32-byte argument
+func word(n int64) []byte {
+	return big.NewInt(n).Bytes()
+}
@@ -0,0 +1,188 @@
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"faucet-backend/config"
+	"faucet-backend/models"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestNFTDrips(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.chain.Fund(crypto.PubkeyToAddress(key.PublicKey), ether)
+	faucet := common.HexToAddress(h.app.Clients.Wallet.Address())
+
+	erc721Code, err := runtimeInitCode(erc721Runtime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	erc1155Code, err := runtimeInitCode(erc1155Runtime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apes := h.chain.Deploy(key, erc721Code)
+	badges := h.chain.Deploy(key, erc721Code)
+	items := h.chain.Deploy(key, erc1155Code)
+
+	// The faucet holds apes 2 and 3 but not 1, and 10 of item 7
+	h.chain.Transact(key, apes, call("safeMint(address)", testAddress(99).Bytes()))
+	h.chain.Transact(key, apes, call("safeMint(address)", faucet.Bytes()))
+	h.chain.Transact(key, apes, call("safeMint(address)", faucet.Bytes()))
+	h.chain.Transact(key, items, call("mint(address,uint256,uint256,bytes)", faucet.Bytes(), word(7), word(10), word(128), nil))
+
+	path := filepath.Join(t.TempDir(), "faucet.yaml")
+	write := func(apesAmount string) {
+		t.Helper()
+		file := fmt.Sprintf(`
+limits:
+  ipDaily: 100
+  fingerprintDaily: 100
+tokens:
+  - id: eth
+    name: Ether
+    symbol: ETH
+    dripAmount: "0.5"
+    cooldownHours: 24
+  - id: apes
+    name: Test Apes
+    symbol: APE
+    address: %q
+    standard: erc721
+    inventory: ["1-3"]
+    dripAmount: %q
+    cooldownHours: 24
+  - id: badges
+    name: Test Badges
+    symbol: BDG
+    address: %q
+    standard: erc721
+    distribution: mint
+    dripAmount: "1"
+    cooldownHours: 24
+  - id: items
+    name: Test Items
+    symbol: ITM
+    address: %q
+    standard: erc1155
+    nftId: "7"
+    dripAmount: "4"
+    cooldownHours: 24
+`, apes.Hex(), apesAmount, badges.Hex(), items.Hex())
+		if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("2")
+	if _, err := config.LoadFile(path); err == nil || !strings.Contains(err.Error(), "must be 1 for ERC-721") {
+		t.Fatalf("two ERC-721 tokens per drip: err = %v", err)
+	}
+
+	write("1")
+	h.app.Config.ConfigFile = path
+	if err := h.app.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var stocked int64
+	h.db.Model(&models.InventoryItem{}).Where("token_id = ? AND status = ?", "apes", models.InventoryAvailable).Count(&stocked)
+	if stocked != 3 {
+		t.Fatalf("apes inventory = %d, want 3", stocked)
+	}
+
+	tests := []struct {
+		token     string
+		recipient int
+		wantDrip  string
+		wantIDs   string
+	}{
+		// Ape 1 isn't the faucet's, so it's skipped
+		{"apes", 1, "completed", "2"},
+		{"apes", 2, "completed", "3"},
+		{"apes", 3, "failed", ""},
+		{"badges", 1, "completed", "1"},
+		{"items", 1, "completed", "7"},
+	}
+	for _, tt := range tests {
+		status, body := h.drip(dripCall{Address: testAddress(tt.recipient).Hex(), TokenID: tt.token, Captcha: captchaPass, Fingerprint: "fp-1"})
+		if status != http.StatusOK {
+			t.Fatalf("%s drip: status %d: %s", tt.token, status, body.Error)
+		}
+		drip := h.waitForDrip(body.DripID)
+		if drip.Status != tt.wantDrip || drip.NFTIDs != tt.wantIDs {
+			t.Errorf("%s drip to %d is %s with IDs %q (%s), want %s with %q",
+				tt.token, tt.recipient, drip.Status, drip.NFTIDs, drip.Error, tt.wantDrip, tt.wantIDs)
+		}
+	}
+
+	owners := map[int64]common.Address{1: testAddress(99), 2: testAddress(1), 3: testAddress(2)}
+	for id, want := range owners {
+		ret, err := h.chain.Call(apes, call("ownerOf(uint256)", word(id)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := common.BytesToAddress(ret); got != want {
+			t.Errorf("owner of ape %d = %s, want %s", id, got.Hex(), want.Hex())
+		}
+	}
+	if owner, err := h.chain.Call(badges, call("ownerOf(uint256)", word(1))); err != nil || common.BytesToAddress(owner) != testAddress(1) {
+		t.Errorf("owner of badge 1 = %x (%v), want %s", owner, err, testAddress(1).Hex())
+	}
+	ret, err := h.chain.Call(items, call("balanceOf(address,uint256)", testAddress(1).Bytes(), word(7)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := new(big.Int).SetBytes(ret); got.Int64() != 4 {
+		t.Errorf("item 7 balance = %s, want 4", got)
+	}
+
+	var inventory []models.InventoryItem
+	h.db.Where("token_id = ?", "apes").Order("nft_id").Find(&inventory)
+	statuses := make([]string, len(inventory))
+	for i, item := range inventory {
+		statuses[i] = item.Status
+	}
+	if got := strings.Join(statuses, ","); got != "missing,sent,sent" {
+		t.Errorf("apes inventory statuses = %s, want missing,sent,sent", got)
+	}
+
+	// The listing exposes each collection
+	if err := h.app.TokenListing.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	_, listing := h.tokens("")
+	collections := make(map[string]string)
+	for _, entry := range listing.Tokens {
+		stock := "-"
+		if entry.Inventory != nil {
+			stock = fmt.Sprint(*entry.Inventory)
+		}
+		collections[entry.ID] = fmt.Sprintf("%s/%s/%s/%s", entry.Standard, entry.NFTID, entry.Balance, stock)
+	}
+	want := map[string]string{
+		"apes":   "erc721//0/0",
+		"badges": "erc721//0/-",
+		"items":  "erc1155/7/6/-",
+	}
+	for id, w := range want {
+		if collections[id] != w {
+			t.Errorf("%s listed as %s, want %s", id, collections[id], w)
+		}
+	}
+}
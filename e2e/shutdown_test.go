@@ -0,0 +1,116 @@
+package e2e
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"faucet-backend/models"
+	"faucet-backend/services"
+)
+
+func TestShutdownCheckpointsReceipts(t *testing.T) {
+	h := newHarness(t)
+
+	// Receipts won't be polled before shutdown
+	h.app.Sender.PollInterval = time.Hour
+
+	call := dripCall{Address: testAddress(1).Hex(), TokenID: "tst", Captcha: captchaPass, Fingerprint: "fp-1"}
+	status, resp := h.drip(call)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d: %s", status, resp.Error)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.app.Sender.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The send finished and was recorded, but tracking stopped short
+	var drip models.Drip
+	if err := h.db.First(&drip, resp.DripID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if drip.Status != "pending" || drip.TxHash == "" {
+		t.Fatalf("drip after drain = %s with tx hash %q, want pending with a tx hash", drip.Status, drip.TxHash)
+	}
+
+	// A restarted sender picks the receipt back up
+	sender := &services.ChainSender{DB: h.db, Wallet: h.app.Clients.Wallet, Events: h.app.Events, PollInterval: 10 * time.Millisecond}
+	if err := sender.Resume(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if drip := h.waitForDrip(resp.DripID); drip.Status != "completed" {
+		t.Fatalf("resumed drip status = %s, want completed", drip.Status)
+	}
+	if err := sender.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := new(big.Int).Mul(big.NewInt(100), ether)
+	if got := h.balanceOf("tst", testAddress(1)); got.Cmp(want) != 0 {
+		t.Errorf("recipient balance = %s, want %s", got, want)
+	}
+}
+
+func TestShutdownWaitsForWebhookDelivery(t *testing.T) {
+	h := newHarness(t)
+
+	// The partner holds the delivery open until it's released
+	received, release := make(chan struct{}), make(chan struct{})
+	var receiveOnce, releaseOnce sync.Once
+	unblock := func() { releaseOnce.Do(func() { close(release) }) }
+	partner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receiveOnce.Do(func() { close(received) })
+		<-release
+	}))
+	defer partner.Close()
+	defer unblock()
+
+	endpoint := models.WebhookEndpoint{URL: partner.URL, Secret: "secret", IsActive: true}
+	if err := h.db.Create(&endpoint).Error; err != nil {
+		t.Fatal(err)
+	}
+	delivery := models.WebhookDelivery{EndpointID: endpoint.ID, EventType: "drip.confirmed", Payload: "{}", Status: "pending", NextAttemptAt: time.Now()}
+	if err := h.db.Create(&delivery).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h.app.Webhooks.Start(ctx, h.app.Events)
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery was never sent")
+	}
+
+	// Stop the workers while the partner is still answering
+	cancel()
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelShutdown()
+	done := make(chan error, 1)
+	go func() { done <- h.app.Shutdown(shutdownCtx) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("shutdown returned mid-delivery: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	unblock()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.db.First(&delivery, delivery.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Status != "delivered" {
+		t.Errorf("delivery status = %s, want delivered", delivery.Status)
+	}
+}
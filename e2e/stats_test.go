@@ -0,0 +1,235 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"faucet-backend/models"
+	"faucet-backend/services"
+	"faucet-backend/units"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestStatsTimeseries(t *testing.T) {
+	h := newHarness(t)
+
+	calls := []dripCall{
+		{Address: testAddress(1).Hex(), TokenID: "eth", Captcha: captchaPass, Fingerprint: "fp-1"},
+		{Address: testAddress(2).Hex(), TokenID: "eth", Captcha: captchaPass, Fingerprint: "fp-2"},
+		{Address: testAddress(1).Hex(), TokenID: "whale", Captcha: captchaPass, Fingerprint: "fp-3"},
+	}
+	for _, call := range calls {
+		status, resp := h.drip(call)
+		if status != http.StatusOK {
+			t.Fatalf("drip %s: status %d: %s", call.TokenID, status, resp.Error)
+		}
+		h.waitForDrip(resp.DripID)
+	}
+
+	if err := h.app.Stats.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	status, body := h.timeseries("?granularity=hour&tokenId=eth,whale")
+	if status != http.StatusOK {
+		t.Fatalf("status %d, want 200", status)
+	}
+	if len(body.Series) != 2 {
+		t.Fatalf("got series for %d tokens, want 2", len(body.Series))
+	}
+
+	eth := body.Series["eth"]
+	if len(eth) != 24 && len(eth) != 25 {
+		t.Fatalf("got %d hourly buckets, want a day's worth", len(eth))
+	}
+	last := eth[len(eth)-1]
+	if last.Drips != 2 || last.Completed != 2 || last.UniqueUsers != 2 || last.FailureRate != 0 {
+		t.Errorf("eth bucket = %+v, want 2 completed drips to 2 users", last)
+	}
+	if last.AmountBaseUnits != "1000000000000000000" || last.Amount != "1" {
+		t.Errorf("eth amount = %s (%s base units), want 1", last.Amount, last.AmountBaseUnits)
+	}
+	if last.MedianConfirmationMs == nil {
+		t.Error("eth bucket has no median confirmation time")
+	}
+	if eth[0].Drips != 0 || eth[0].AmountBaseUnits != "0" {
+		t.Errorf("empty bucket = %+v, want zeros", eth[0])
+	}
+
+	whale := body.Series["whale"]
+	if got := whale[len(whale)-1]; got.Drips != 1 || got.Failed != 1 || got.FailureRate != 1 {
+		t.Errorf("whale bucket = %+v, want 1 failed drip", got)
+	}
+
+	// A later run picks up only the new drip's day and rebuilds the week
+	// from the day rollups
+	confirmed := time.Now()
+	if err := h.db.Create(&models.Drip{
+		Recipient:       testAddress(3).Hex(),
+		TokenID:         "eth",
+		Amount:          "0.5",
+		AmountBaseUnits: "500000000000000000",
+		Status:          "completed",
+		CreatedAt:       confirmed.Add(-time.Second),
+		CompletedAt:     &confirmed,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := h.app.Stats.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, granularity := range []string{"day", "week"} {
+		_, body := h.timeseries("?granularity=" + granularity + "&tokenId=eth")
+		eth := body.Series["eth"]
+		if got := eth[len(eth)-1]; got.Drips != 3 || got.Completed != 3 || got.UniqueUsers != 3 || got.Amount != "1.5" || got.MedianConfirmationMs == nil {
+			t.Errorf("eth %s bucket = %+v, want 3 completed drips of 1.5 to 3 users", granularity, got)
+		}
+	}
+
+	t.Run("invalid granularity", func(t *testing.T) {
+		if status, _ := h.timeseries("?granularity=month"); status != http.StatusBadRequest {
+			t.Errorf("status %d, want 400", status)
+		}
+	})
+}
+
+func TestStatsWeeklyRollup(t *testing.T) {
+	h := newHarness(t)
+
+	// Two days of a past week. Day one's median is 2s over three drips and
+	// day two's 55s over two; the week's is the middle of all five, 50s.
+	week := services.TruncateBucket(time.Now(), "week").AddDate(0, 0, -14)
+	confirmations := []struct {
+		day     int
+		seconds int
+	}{{0, 1}, {0, 2}, {0, 100}, {1, 50}, {1, 60}}
+	for i, c := range confirmations {
+		created := week.AddDate(0, 0, c.day).Add(time.Duration(i) * time.Hour)
+		completed := created.Add(time.Duration(c.seconds) * time.Second)
+		if err := h.db.Create(&models.Drip{
+			Recipient:       testAddress(i + 1).Hex(),
+			TokenID:         "eth",
+			Amount:          "123456.789012345678901234",
+			AmountBaseUnits: "123456789012345678901234",
+			Status:          "completed",
+			CreatedAt:       created,
+			CompletedAt:     &completed,
+		}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := h.app.Stats.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	_, body := h.timeseries("?granularity=week&tokenId=eth")
+	var got *services.TimeseriesPoint
+	for i, point := range body.Series["eth"] {
+		if point.BucketStart.Equal(week) {
+			got = &body.Series["eth"][i]
+		}
+	}
+	if got == nil {
+		t.Fatalf("no bucket for the week of %s", week)
+	}
+	if got.Completed != 5 || got.UniqueUsers != 5 {
+		t.Errorf("week = %+v, want 5 completed drips to 5 users", *got)
+	}
+	if got.MedianConfirmationMs == nil || *got.MedianConfirmationMs != 50_000 {
+		t.Errorf("median confirmation = %v, want 50000ms", got.MedianConfirmationMs)
+	}
+	// Totals this large can come back from SQLite in exponent form; they
+	// must still be read, not dropped to zero
+	if got.AmountBaseUnits == "0" {
+		t.Error("weekly amount read as 0")
+	}
+}
+
+func TestGasSpend(t *testing.T) {
+	h := newHarness(t)
+
+	calls := []dripCall{
+		{Address: testAddress(1).Hex(), TokenID: "eth", Captcha: captchaPass, Fingerprint: "fp-1"},
+		{Address: testAddress(1).Hex(), TokenID: "tst", Captcha: captchaPass, Fingerprint: "fp-2"},
+		{Address: testAddress(1).Hex(), TokenID: "whale", Captcha: captchaPass, Fingerprint: "fp-3"},
+	}
+	var ethTx string
+	for _, call := range calls {
+		status, resp := h.drip(call)
+		if status != http.StatusOK {
+			t.Fatalf("drip %s: status %d: %s", call.TokenID, status, resp.Error)
+		}
+		if drip := h.waitForDrip(resp.DripID); call.TokenID == "eth" {
+			ethTx = drip.TxHash
+		}
+	}
+
+	receipt, err := h.chain.TransactionReceipt(context.Background(), common.HexToHash(ethTx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ethCost := new(big.Int).Mul(big.NewInt(21000), receipt.EffectiveGasPrice)
+
+	if err := h.app.Stats.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	status, raw := h.adminGet("/api/admin/gas")
+	if status != http.StatusOK {
+		t.Fatalf("status %d, want 200: %s", status, raw)
+	}
+	var body struct {
+		Tokens []services.GasSpendToken `json:"tokens"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		t.Fatal(err)
+	}
+
+	spend := make(map[string]services.GasSpendToken)
+	for _, token := range body.Tokens {
+		spend[token.TokenID] = token
+	}
+	if len(spend) != 3 {
+		t.Fatalf("got gas spend for %d tokens, want 3 (reverted drips cost gas too)", len(spend))
+	}
+
+	// 21000 gas at the price the transfer was mined at
+	eth := spend["eth"]
+	if eth.Receipts != 1 || eth.GasUsed != 21000 || eth.GasCost != ethCost.String() || eth.GasCostEth != units.Format(ethCost, 18) {
+		t.Errorf("eth spend = %+v", eth)
+	}
+	if len(eth.Daily) != 1 || eth.Daily[0].GasUsed != 21000 {
+		t.Errorf("eth daily = %+v, want one day", eth.Daily)
+	}
+	if tst := spend["tst"]; tst.AvgGasUsed <= eth.AvgGasUsed {
+		t.Errorf("erc20 drip used %d gas, want more than native's %d", tst.AvgGasUsed, eth.AvgGasUsed)
+	}
+}
+
+type timeseriesResponse struct {
+	Series map[string][]services.TimeseriesPoint `json:"series"`
+}
+
+// timeseries gets the stats timeseries with the given query string
+func (h *harness) timeseries(query string) (int, timeseriesResponse) {
+	h.t.Helper()
+
+	resp, err := h.app.HTTP.Test(httptest.NewRequest(http.MethodGet, "/api/faucet/stats/timeseries"+query, nil), -1)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var body timeseriesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		h.t.Fatal(err)
+	}
+	return resp.StatusCode, body
+}
@@ -0,0 +1,106 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"faucet-backend/services"
+)
+
+func TestTokenListing(t *testing.T) {
+	h := newHarness(t)
+
+	resp, listing := h.tokens("")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want 200", resp.StatusCode)
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+	if cc := resp.Header.Get("Cache-Control"); !strings.Contains(cc, "max-age=") || !strings.Contains(cc, "stale-while-revalidate=") {
+		t.Errorf("Cache-Control %q", cc)
+	}
+	if len(listing.Tokens) != 3 || listing.Stale {
+		t.Fatalf("got %d tokens, stale %v; want 3 fresh", len(listing.Tokens), listing.Stale)
+	}
+	balances := make(map[string]string)
+	for _, token := range listing.Tokens {
+		balances[token.ID] = token.Balance
+	}
+	if balances["tst"] != "1000000" {
+		t.Errorf("tst balance %s, want 1000000", balances["tst"])
+	}
+
+	t.Run("not modified", func(t *testing.T) {
+		resp, _ := h.tokens(etag)
+		if resp.StatusCode != http.StatusNotModified {
+			t.Errorf("status %d, want 304", resp.StatusCode)
+		}
+	})
+
+	t.Run("unchanged refresh", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if err := h.app.TokenListing.Refresh(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			resp, _ := h.tokens(etag)
+			if resp.StatusCode != http.StatusNotModified {
+				t.Fatalf("refresh %d: status %d, want 304", i+1, resp.StatusCode)
+			}
+			if got := resp.Header.Get("ETag"); got != etag {
+				t.Fatalf("refresh %d: ETag %s, want %s", i+1, got, etag)
+			}
+		}
+	})
+
+	t.Run("node down", func(t *testing.T) {
+		h.chain.down.Store(true)
+		defer h.chain.down.Store(false)
+
+		if err := h.app.TokenListing.Refresh(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		resp, listing := h.tokens(etag)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status %d, want 200 with a new listing", resp.StatusCode)
+		}
+		if !listing.Stale {
+			t.Error("listing not marked stale")
+		}
+		for _, token := range listing.Tokens {
+			if token.Balance != balances[token.ID] {
+				t.Errorf("%s balance %s, want last known %s", token.ID, token.Balance, balances[token.ID])
+			}
+		}
+	})
+}
+
+// tokens gets the token listing, sending ifNoneMatch when it's set
+func (h *harness) tokens(ifNoneMatch string) (*http.Response, services.TokenListing) {
+	h.t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/faucet/tokens", nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+
+	resp, err := h.app.HTTP.Test(req, -1)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var listing services.TokenListing
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&listing); err != nil {
+			h.t.Fatal(err)
+		}
+	}
+	return resp, listing
+}
@@ -0,0 +1,166 @@
+package e2e
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"faucet-backend/models"
+	"faucet-backend/services"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestTreasury(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	treasury := crypto.PubkeyToAddress(key.PublicKey)
+	h.chain.Fund(treasury, new(big.Int).Mul(big.NewInt(1000), ether))
+
+	// A token with no supply that the faucet mints
+	initCode, err := erc20InitCode(new(big.Int))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mintable := h.chain.Deploy(key, initCode)
+
+	// eth is refilled from the treasury, tst's excess swept back and mnt
+	// minted
+	path := filepath.Join(t.TempDir(), "faucet.yaml")
+	file := fmt.Sprintf(`
+tokens:
+  - id: eth
+    name: Ether
+    symbol: ETH
+    dripAmount: "0.5"
+    cooldownHours: 24
+    refill:
+      lowWater: "200"
+      highWater: "300"
+  - id: tst
+    name: Test Token
+    symbol: TST
+    address: %q
+    dripAmount: "100"
+    cooldownHours: 24
+    refill:
+      lowWater: "1000"
+      highWater: "400000"
+      sweepAbove: "500000"
+  - id: mnt
+    name: Mintable Token
+    symbol: MNT
+    address: %q
+    dripAmount: "1"
+    cooldownHours: 24
+    refill:
+      source: mint
+      lowWater: "10"
+      highWater: "100"
+`, h.token.Hex(), mintable.Hex())
+	if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h.app.Config.ConfigFile = path
+
+	err = h.app.Reload(ctx)
+	if err == nil || !strings.Contains(err.Error(), "TREASURY_PRIVATE_KEY") {
+		t.Fatalf("reload without a treasury signer: err = %v", err)
+	}
+
+	signer, err := services.NewWalletWithClient(ctx, h.chain, hex.EncodeToString(crypto.FromECDSA(key)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(signer.Close)
+	h.app.Clients.Treasury = signer
+	h.app.Treasury.Signer = signer
+
+	if err := h.app.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first check sends, the second settles and finds nothing to do
+	for i := 0; i < 2; i++ {
+		if err := h.app.Treasury.Check(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	status, raw := h.adminGet("/api/admin/treasury/transfers")
+	if status != http.StatusOK {
+		t.Fatalf("status %d: %s", status, raw)
+	}
+	var body struct {
+		Transfers []models.TreasuryTransfer `json:"transfers"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		t.Fatal(err)
+	}
+	kinds := make(map[string]models.TreasuryTransfer)
+	for _, transfer := range body.Transfers {
+		kinds[transfer.TokenID] = transfer
+		if transfer.Status != "completed" || transfer.BlockNumber == nil {
+			t.Errorf("%s %s is %s (%s), want completed", transfer.TokenID, transfer.Kind, transfer.Status, transfer.Error)
+		}
+	}
+	if len(body.Transfers) != 3 || kinds["eth"].Kind != "refill" || kinds["tst"].Kind != "sweep" || kinds["mnt"].Kind != "mint" {
+		t.Fatalf("transfers = %+v, want an eth refill, a tst sweep and an mnt mint", body.Transfers)
+	}
+
+	faucet := common.HexToAddress(h.app.Clients.Wallet.Address())
+	whole := func(n int64) *big.Int { return new(big.Int).Mul(big.NewInt(n), ether) }
+
+	// The sweep and mint were paid for after the refill
+	if got := h.chain.Balance(faucet); got.Cmp(whole(300)) >= 0 || got.Cmp(whole(299)) < 0 {
+		t.Errorf("faucet eth = %s, want just under 300 ETH", got)
+	}
+	if got := h.erc20Balance(h.token, faucet); got.Cmp(whole(400000)) != 0 {
+		t.Errorf("faucet tst = %s, want 400000", got)
+	}
+	if got := h.erc20Balance(h.token, treasury); got.Cmp(whole(600000)) != 0 {
+		t.Errorf("treasury tst = %s, want the 600000 swept", got)
+	}
+	if got := h.erc20Balance(mintable, faucet); got.Cmp(whole(100)) != 0 {
+		t.Errorf("faucet mnt = %s, want 100", got)
+	}
+
+	// An unmined transfer stays pending, and blocks its token's refills,
+	// until it's past the receipt timeout with nothing queued from its sender
+	stale := models.TreasuryTransfer{TokenID: "eth", Kind: "refill", FromAddress: treasury.Hex(), ToAddress: faucet.Hex(),
+		AmountBaseUnits: "1", TxHash: common.HexToHash("0xdead").Hex(), CreatedAt: time.Now().Add(-time.Hour)}
+	recent := stale
+	recent.TokenID, recent.TxHash, recent.CreatedAt = "mnt", common.HexToHash("0xbeef").Hex(), time.Now()
+	if err := h.db.Create(&[]models.TreasuryTransfer{stale, recent}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := h.app.Treasury.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var after []models.TreasuryTransfer
+	h.db.Where("tx_hash IN ?", []string{stale.TxHash, recent.TxHash}).Order("token_id").Find(&after)
+	if len(after) != 2 || after[0].Status != "failed" || !strings.Contains(after[0].Error, "dropped") || after[1].Status != "pending" {
+		t.Errorf("unmined transfers = %+v, want the stale one dropped and the recent one pending", after)
+	}
+
+	// Internal transfers aren't drips
+	var drips int64
+	h.db.Model(&models.Drip{}).Count(&drips)
+	if drips != 0 {
+		t.Errorf("%d drips recorded, want none", drips)
+	}
+}
@@ -0,0 +1,103 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"faucet-backend/models"
+)
+
+// Drip lifecycle and faucet events
+const (
+	DripCreated   = "drip.created"
+	DripBroadcast = "drip.broadcast"
+	DripConfirmed = "drip.confirmed"
+	DripFailed    = "drip.failed"
+	BalanceLow    = "balance.low"
+)
+
+// All lists every event type, for validating subscriptions
+var All = []string{DripCreated, DripBroadcast, DripConfirmed, DripFailed, BalanceLow}
+
+// Event is published in-process; Data is the JSON-serialisable payload
+// (DripData for drip events)
+type Event struct {
+	Type    string    `json:"type"`
+	TokenID string    `json:"tokenId,omitempty"`
+	Time    time.Time `json:"time"`
+	Data    any       `json:"data"`
+}
+
+// DripData is the public view of a drip carried by drip events; it leaves
+// out the requester's IP and fingerprint
+type DripData struct {
+	ID        uint   `json:"id"`
+	Recipient string `json:"recipient"`
+	TokenID   string `json:"tokenId"`
+	Amount    string `json:"amount"`
+	// AmountBaseUnits is Amount in the token's smallest unit
+	AmountBaseUnits string     `json:"amountBaseUnits"`
+	TxHash          string     `json:"txHash,omitempty"`
+	NFTIDs          string     `json:"nftIds,omitempty"`
+	Status          string     `json:"status"`
+	Error           string     `json:"error,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	CompletedAt     *time.Time `json:"completedAt,omitempty"`
+}
+
+// NewDripEvent builds a drip lifecycle event from the drip's current state
+func NewDripEvent(eventType string, drip *models.Drip) Event {
+	return Event{
+		Type:    eventType,
+		TokenID: drip.TokenID,
+		Data: DripData{
+			ID:              drip.ID,
+			Recipient:       drip.Recipient,
+			TokenID:         drip.TokenID,
+			Amount:          drip.Amount,
+			AmountBaseUnits: drip.AmountBaseUnits,
+			TxHash:          drip.TxHash,
+			NFTIDs:          drip.NFTIDs,
+			Status:          drip.Status,
+			Error:           drip.Error,
+			CreatedAt:       drip.CreatedAt,
+			CompletedAt:     drip.CompletedAt,
+		},
+	}
+}
+
+type Handler func(ctx context.Context, event Event)
+
+// Bus fans published events out to in-process subscribers
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers a handler for every published event. Handlers run
+// synchronously on the publisher's goroutine and must not block.
+func (b *Bus) Subscribe(h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, h)
+}
+
+// Publish delivers event to every subscriber
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	subscribers := b.handlers
+	b.mu.RUnlock()
+
+	for _, h := range subscribers {
+		h(ctx, event)
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/url"
+
+	"faucet-backend/auth"
+	"faucet-backend/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AuthHandler serves the OAuth login flow
+type AuthHandler struct {
+	Providers auth.Providers
+	Policy    auth.Policy
+	Sessions  *auth.Sessions
+	// SuccessRedirectURL receives the session token in its fragment after a
+	// browser login; when empty the callback responds with JSON
+	SuccessRedirectURL string
+}
+
+func (h *AuthHandler) OAuthLogin(c *fiber.Ctx) error {
+	provider, err := h.Providers.Get(c.Params("provider"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Unknown login provider",
+		})
+	}
+
+	state, err := h.Sessions.NewState(c.UserContext(), provider.Name())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to start login",
+		})
+	}
+
+	return c.Redirect(provider.AuthCodeURL(state), fiber.StatusFound)
+}
+
+func (h *AuthHandler) OAuthCallback(c *fiber.Ctx) error {
+	provider, err := h.Providers.Get(c.Params("provider"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Unknown login provider",
+		})
+	}
+
+	if err := h.Sessions.ConsumeState(c.UserContext(), provider.Name(), c.Query("state")); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid or expired login state",
+		})
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Missing authorization code",
+		})
+	}
+
+	identity, err := provider.Exchange(c.UserContext(), code)
+	if err != nil {
+		return c.Status(502).JSON(fiber.Map{
+			"error": "Login with provider failed",
+		})
+	}
+
+	if err := h.Policy.CheckAccountAge(identity); err != nil {
+		return c.Status(403).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	token, err := h.Sessions.Create(c.UserContext(), identity)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to create session",
+		})
+	}
+
+	// Browser flow: hand the session back to the frontend in the URL fragment
+	if h.SuccessRedirectURL != "" {
+		fragment := url.Values{"token": {token}}
+		return c.Redirect(h.SuccessRedirectURL+"#"+fragment.Encode(), fiber.StatusFound)
+	}
+
+	return c.JSON(fiber.Map{
+		"token":    token,
+		"identity": identity,
+	})
+}
+
+func (h *AuthHandler) GetIdentity(c *fiber.Ctx) error {
+	identity := middleware.CurrentIdentity(c)
+	if identity == nil {
+		return c.Status(401).JSON(fiber.Map{
+			"error": "Not logged in",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"identity": identity,
+	})
+}
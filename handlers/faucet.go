@@ -1,16 +1,20 @@
 package handlers
 
 import (
-	"faucet-backend/database"
+	"errors"
+	"faucet-backend/auth"
+	"faucet-backend/middleware"
 	"faucet-backend/models"
 	"faucet-backend/services"
 	"fmt"
-	"math/big"
+	"log/slog"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 type DripRequest struct {
@@ -18,93 +22,185 @@ type DripRequest struct {
 	TokenID      string `json:"tokenId"`
 	CaptchaToken string `json:"captchaToken"`
 	Fingerprint  string `json:"fingerprint"`
+	// TokenIDs or Bundle request several tokens at once instead of TokenID
+	TokenIDs []string `json:"tokenIds"`
+	Bundle   string   `json:"bundle"`
 }
 
-func RequestDrip(c *fiber.Ctx) error {
+// FaucetHandler serves the public faucet API
+type FaucetHandler struct {
+	DB      *gorm.DB
+	Wallet  *services.Wallet
+	Drips   *services.DripService
+	Limiter *services.RedisLimiter
+	Listing *services.TokenListingCache
+	Stats   *services.StatsRollup
+}
+
+func (h *FaucetHandler) RequestDrip(c *fiber.Ctx) error {
 	var req DripRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{
 			"error": "Invalid request body",
 		})
 	}
+	if len(req.TokenIDs) > 0 || req.Bundle != "" {
+		return h.requestBundle(c, req)
+	}
 
-	// Validate address
-	if !common.IsHexAddress(req.Address) {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid Ethereum address",
-		})
+	result, err := h.Drips.Request(c.UserContext(), services.DripRequest{
+		Address:      req.Address,
+		TokenID:      req.TokenID,
+		CaptchaToken: req.CaptchaToken,
+		Fingerprint:  req.Fingerprint,
+		IP:           c.IP(),
+		Identity:     middleware.CurrentIdentity(c),
+		RequestID:    middleware.GetRequestID(c),
+	})
+	if err != nil {
+		return dripError(c, err)
 	}
 
-	address := common.HexToAddress(req.Address).Hex()
-	ip := c.IP()
+	token := result.Token
+	return c.JSON(fiber.Map{
+		"success":         true,
+		"txHash":          "",
+		"amount":          result.Drip.Amount,
+		"amountBaseUnits": result.Drip.AmountBaseUnits,
+		"token":           token.Symbol,
+		"message":         fmt.Sprintf("Sending %s %s to your address", result.Drip.Amount, token.Symbol),
+		"dripId":          result.Drip.ID,
+	})
+}
 
-	// Verify token exists and is active
-	var token models.Token
-	if err := database.DB.Where("id = ? AND is_active = true", req.TokenID).First(&token).Error; err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid or inactive token",
-		})
-	}
+// dripError maps DripService errors to HTTP responses
+func dripError(c *fiber.Ctx, err error) error {
+	status, body := dripErrorBody(err)
+	return c.Status(status).JSON(body)
+}
+
+// dripErrorBody returns the status and body a DripService error is answered
+// with
+func dripErrorBody(err error) (int, fiber.Map) {
+	var paused *services.TokenPausedError
+	var limited *services.RateLimitError
 
-	// Verify CAPTCHA
-	if err := services.VerifyCaptcha(req.CaptchaToken, ip); err != nil {
-		return c.Status(403).JSON(fiber.Map{
+	switch {
+	case errors.Is(err, services.ErrInvalidAddress):
+		return 400, fiber.Map{
+			"error": "Invalid Ethereum address",
+		}
+	case errors.Is(err, services.ErrTokenUnavailable):
+		return 400, fiber.Map{
+			"error": "Invalid or inactive token",
+		}
+	case errors.Is(err, services.ErrInvalidBundle):
+		return 400, fiber.Map{
+			"error": err.Error(),
+		}
+	case errors.Is(err, services.ErrUnknownBundle):
+		return 400, fiber.Map{
+			"error": "Unknown token bundle",
+		}
+	case errors.As(err, &paused):
+		return 503, fiber.Map{
+			"error": paused.Error(),
+		}
+	case errors.Is(err, services.ErrLoginRequired):
+		return 401, fiber.Map{
+			"error": "Login required to request tokens",
+		}
+	case errors.Is(err, auth.ErrAccountTooYoung), errors.Is(err, auth.ErrAccountAgeUnknown):
+		return 403, fiber.Map{
+			"error": err.Error(),
+		}
+	case errors.Is(err, services.ErrCaptchaFailed):
+		return 403, fiber.Map{
 			"error": "CAPTCHA verification failed",
-		})
+		}
+	case errors.As(err, &limited):
+		return 429, fiber.Map{
+			"error":      limited.Reason,
+			"retryAfter": limited.RetryAfter,
+		}
+	default:
+		return 500, fiber.Map{
+			"error": "Failed to process drip request",
+		}
 	}
+}
 
-	// Check rate limits
-	rateLimitCheck, err := services.CheckRateLimit(address, req.TokenID, ip, req.Fingerprint)
+// requestBundle serves drip requests for several tokens. Each token gets a
+// result; the response is 200 when any was accepted and otherwise carries
+// the first rejection's status.
+func (h *FaucetHandler) requestBundle(c *fiber.Ctx, req DripRequest) error {
+	result, err := h.Drips.RequestBundle(c.UserContext(), services.BundleRequest{
+		DripRequest: services.DripRequest{
+			Address:      req.Address,
+			CaptchaToken: req.CaptchaToken,
+			Fingerprint:  req.Fingerprint,
+			IP:           c.IP(),
+			Identity:     middleware.CurrentIdentity(c),
+			RequestID:    middleware.GetRequestID(c),
+		},
+		TokenIDs: req.TokenIDs,
+		Bundle:   req.Bundle,
+	})
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Rate limit check failed",
-		})
+		return dripError(c, err)
 	}
 
-	if !rateLimitCheck.Allowed {
-		return c.Status(429).JSON(fiber.Map{
-			"error":      rateLimitCheck.Reason,
-			"retryAfter": rateLimitCheck.RetryAfter,
-		})
-	}
+	status := 0
+	results := make([]fiber.Map, len(result.Items))
+	for i, item := range result.Items {
+		if item.Err != nil {
+			code, body := dripErrorBody(item.Err)
+			if status == 0 {
+				status = code
+			}
+			body["tokenId"] = item.TokenID
+			body["success"] = false
+			results[i] = body
+			continue
+		}
 
-	// Create drip record
-	drip := models.Drip{
-		Recipient:   address,
-		TokenID:     req.TokenID,
-		Amount:      token.DripAmount,
-		IPAddress:   ip,
-		Fingerprint: req.Fingerprint,
-		Status:      "pending",
+		status = 200
+		results[i] = fiber.Map{
+			"tokenId":         item.TokenID,
+			"success":         true,
+			"token":           item.Token.Symbol,
+			"amount":          item.Drip.Amount,
+			"amountBaseUnits": item.Drip.AmountBaseUnits,
+			"dripId":          item.Drip.ID,
+		}
 	}
 
-	if err := database.DB.Create(&drip).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to create drip record",
-		})
+	body := fiber.Map{
+		"success": status == 200,
+		"results": results,
 	}
+	if result.Batch != nil {
+		body["batchId"] = result.Batch.ID
+	}
+	return c.Status(status).JSON(body)
+}
 
-	// Execute transaction (async)
-	go func() {
-		services.ExecuteDrip(address, req.TokenID, drip.ID)
-	}()
-
-	// Record in rate limiter
-	services.RecordDrip(address, req.TokenID, ip, req.Fingerprint)
-
+// GetBundles lists the named token bundles
+func (h *FaucetHandler) GetBundles(c *fiber.Ctx) error {
+	bundles := h.Drips.Bundles()
+	if bundles == nil {
+		bundles = map[string][]string{}
+	}
 	return c.JSON(fiber.Map{
-		"success": true,
-		"txHash":  "",
-		"amount":  token.DripAmount,
-		"token":   token.Symbol,
-		"message": fmt.Sprintf("Sending %s %s to your address", token.DripAmount, token.Symbol),
-		"dripId":  drip.ID,
+		"bundles": bundles,
 	})
 }
 
-func GetStatus(c *fiber.Ctx) error {
+func (h *FaucetHandler) GetStatus(c *fiber.Ctx) error {
 	address := c.Params("address")
 	ip := c.IP()
+	ctx := c.UserContext()
+	db := h.DB.WithContext(ctx)
 
 	if !common.IsHexAddress(address) {
 		return c.Status(400).JSON(fiber.Map{
@@ -116,7 +212,7 @@ func GetStatus(c *fiber.Ctx) error {
 
 	// Get all tokens
 	var tokens []models.Token
-	database.DB.Where("is_active = true").Find(&tokens)
+	db.Where("is_active = true").Find(&tokens)
 
 	// Get drip status for each token
 	type TokenStatus struct {
@@ -131,7 +227,7 @@ func GetStatus(c *fiber.Ctx) error {
 
 	for _, token := range tokens {
 		var drip models.Drip
-		result := database.DB.Where("recipient = ? AND token_id = ?", address, token.ID).
+		result := db.Where("recipient = ? AND token_id = ?", address, token.ID).
 			Order("created_at DESC").
 			First(&drip)
 
@@ -141,10 +237,11 @@ func GetStatus(c *fiber.Ctx) error {
 
 		if result.Error == nil {
 			lastDrip = fiber.Map{
-				"txHash":    drip.TxHash,
-				"amount":    drip.Amount,
-				"timestamp": drip.CreatedAt,
-				"status":    drip.Status,
+				"txHash":          drip.TxHash,
+				"amount":          drip.Amount,
+				"amountBaseUnits": drip.AmountBaseUnits,
+				"timestamp":       drip.CreatedAt,
+				"status":          drip.Status,
 			}
 
 			// Calculate cooldown
@@ -167,7 +264,7 @@ func GetStatus(c *fiber.Ctx) error {
 	}
 
 	// Get IP rate limit
-	ipRateLimit, _ := services.GetIPRateLimit(ip)
+	ipRateLimit, _ := h.Limiter.IPRateLimit(ctx, ip)
 
 	return c.JSON(fiber.Map{
 		"drips": drips,
@@ -179,64 +276,51 @@ func GetStatus(c *fiber.Ctx) error {
 	})
 }
 
-func GetTokens(c *fiber.Ctx) error {
-	var tokens []models.Token
-	database.DB.Where("is_active = true").Find(&tokens)
+// GetTokens serves the cached token listing. Clients may reuse it for the
+// refresh interval and revalidate with If-None-Match.
+func (h *FaucetHandler) GetTokens(c *fiber.Ctx) error {
+	listing, err := h.Listing.Get(c.UserContext())
+	if err != nil {
+		slog.Error("failed to load token listing", "error", err)
+		return c.Status(503).JSON(fiber.Map{
+			"error": "Token listing unavailable",
+		})
+	}
+
+	maxAge := int(h.Listing.MaxAge().Seconds())
+	c.Set(fiber.HeaderCacheControl, fmt.Sprintf("public, max-age=%d, stale-while-revalidate=%d", maxAge, 4*maxAge))
+	c.Set(fiber.HeaderETag, listing.ETag)
+	c.Set(fiber.HeaderLastModified, listing.UpdatedAt.UTC().Format(http.TimeFormat))
 
-	// Get stats for each token
-	type TokenResponse struct {
-		models.Token
-		TotalDrips int64  `json:"totalDrips"`
-		Balance    string `json:"balance"`
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), listing.ETag) {
+		return c.SendStatus(fiber.StatusNotModified)
 	}
 
-	var response []TokenResponse
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(listing.Body)
+}
 
-	for _, token := range tokens {
-		var count int64
-		database.DB.Model(&models.Drip{}).
-			Where("token_id = ? AND status = ?", token.ID, "completed").
-			Count(&count)
-
-		// Get balance
-		var balance string
-		if token.Address == "" {
-			// Native ETH
-			bal, _ := services.GetFaucetBalance()
-			balance = bal
-		} else {
-			// ERC20
-			tokenAddr := common.HexToAddress(token.Address)
-			bal, err := services.GetERC20Balance(tokenAddr)
-			if err == nil {
-				decimals := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(token.Decimals)), nil)
-				balFloat := new(big.Float).Quo(new(big.Float).SetInt(bal), new(big.Float).SetInt(decimals))
-				balance = balFloat.Text('f', 2)
-			} else {
-				balance = "0"
-			}
+// etagMatches reports whether an If-None-Match header lists etag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
 		}
-
-		response = append(response, TokenResponse{
-			Token:      token,
-			TotalDrips: count,
-			Balance:    balance,
-		})
 	}
-
-	return c.JSON(fiber.Map{
-		"tokens": response,
-	})
+	return false
 }
 
-func GetStats(c *fiber.Ctx) error {
+func (h *FaucetHandler) GetStats(c *fiber.Ctx) error {
+	db := h.DB.WithContext(c.UserContext())
+
 	// Total drips across all tokens
 	var totalDrips int64
-	database.DB.Model(&models.Drip{}).Where("status = ?", "completed").Count(&totalDrips)
+	db.Model(&models.Drip{}).Where("status = ?", "completed").Count(&totalDrips)
 
 	// Unique users (distinct recipients)
 	var totalUsers int64
-	database.DB.Model(&models.Drip{}).
+	db.Model(&models.Drip{}).
 		Where("status = ?", "completed").
 		Distinct("recipient").
 		Count(&totalUsers)
@@ -249,7 +333,7 @@ func GetStats(c *fiber.Ctx) error {
 	}
 
 	var distributions []TokenDistribution
-	database.DB.Table("drips").
+	db.Table("drips").
 		Select("drips.token_id, tokens.symbol, COUNT(*) as count").
 		Joins("JOIN tokens ON tokens.id = drips.token_id").
 		Where("drips.status = ?", "completed").
@@ -268,6 +352,66 @@ func GetStats(c *fiber.Ctx) error {
 	})
 }
 
-func GetFaucetAddress() string {
-	return services.GetWalletAddress()
+// GetStatsTimeseries returns per-token drip stats in hour, day or week
+// buckets, read from the rollup table. Query parameters: granularity
+// (default day), from and to (RFC 3339 or YYYY-MM-DD, default the last 24
+// hours, 30 days or 12 weeks) and tokenId (comma-separated, default every
+// active token).
+func (h *FaucetHandler) GetStatsTimeseries(c *fiber.Ctx) error {
+	granularity := c.Query("granularity", "day")
+	defaultRange := map[string]time.Duration{
+		"hour": 24 * time.Hour,
+		"day":  30 * 24 * time.Hour,
+		"week": 12 * 7 * 24 * time.Hour,
+	}[granularity]
+
+	to := time.Now().UTC()
+	if value := c.Query("to"); value != "" {
+		t, err := parseStatsTime(value)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid to: use RFC 3339 or YYYY-MM-DD"})
+		}
+		to = t
+	}
+	from := to.Add(-defaultRange)
+	if value := c.Query("from"); value != "" {
+		t, err := parseStatsTime(value)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid from: use RFC 3339 or YYYY-MM-DD"})
+		}
+		from = t
+	}
+
+	var tokenIDs []string
+	if value := c.Query("tokenId"); value != "" {
+		tokenIDs = strings.Split(value, ",")
+	}
+
+	series, err := h.Stats.Timeseries(c.UserContext(), services.TimeseriesQuery{
+		Granularity: granularity,
+		From:        from,
+		To:          to,
+		TokenIDs:    tokenIDs,
+	})
+	if errors.Is(err, services.ErrInvalidStatsQuery) {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		slog.Error("failed to load stats timeseries", "error", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to load stats"})
+	}
+
+	return c.JSON(fiber.Map{
+		"granularity": granularity,
+		"from":        services.TruncateBucket(from, granularity),
+		"to":          to,
+		"series":      series,
+	})
+}
+
+func parseStatsTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse(time.DateOnly, value)
 }
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"faucet-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HealthHandler serves liveness and readiness probes
+type HealthHandler struct {
+	Checker *services.HealthChecker
+	Wallet  *services.Wallet
+}
+
+func (h *HealthHandler) Health(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"status": "ok",
+		"wallet": h.Wallet.Address(),
+	})
+}
+
+// Livez reports that the process is up; it never checks dependencies so a
+// dependency outage doesn't get the container restarted
+func (h *HealthHandler) Livez(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"status": "ok",
+	})
+}
+
+// Readyz reports whether the faucet can serve drips, with a per-component
+// breakdown. Degraded (e.g. low balance) is still ready.
+func (h *HealthHandler) Readyz(c *fiber.Ctx) error {
+	readiness := h.Checker.CheckReadiness(c.UserContext())
+
+	status := fiber.StatusOK
+	if readiness.Status == services.HealthUnavailable {
+		status = fiber.StatusServiceUnavailable
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"status":     readiness.Status,
+		"wallet":     h.Wallet.Address(),
+		"components": readiness.Components,
+	})
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"faucet-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ReportHandler serves admin cost reports
+type ReportHandler struct {
+	Stats *services.StatsRollup
+}
+
+// GetGasSpend reports gas used and fees paid per token and per UTC day.
+// Query parameters: from and to (RFC 3339 or YYYY-MM-DD, default the last
+// 30 days) and tokenId (comma-separated, default every token).
+func (h *ReportHandler) GetGasSpend(c *fiber.Ctx) error {
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -30)
+	for _, bound := range []struct {
+		name string
+		dest *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		value := c.Query(bound.name)
+		if value == "" {
+			continue
+		}
+		t, err := parseStatsTime(value)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": fmt.Sprintf("Invalid %s: use RFC 3339 or YYYY-MM-DD", bound.name),
+			})
+		}
+		*bound.dest = t
+	}
+
+	var tokenIDs []string
+	if value := c.Query("tokenId"); value != "" {
+		tokenIDs = strings.Split(value, ",")
+	}
+
+	tokens, err := h.Stats.GasSpend(c.UserContext(), services.GasSpendQuery{
+		From:     from,
+		To:       to,
+		TokenIDs: tokenIDs,
+	})
+	if errors.Is(err, services.ErrInvalidStatsQuery) {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		slog.Error("failed to load gas spend", "error", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to load gas spend",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"from":   services.TruncateBucket(from, "day"),
+		"to":     to,
+		"tokens": tokens,
+	})
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"log/slog"
+
+	"faucet-backend/models"
+	"faucet-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// TreasuryHandler serves the admin treasury API
+type TreasuryHandler struct {
+	DB       *gorm.DB
+	Treasury *services.Treasury
+}
+
+// ListTransfers returns the latest refills, mints and sweeps, optionally
+// filtered by tokenId, kind and status
+func (h *TreasuryHandler) ListTransfers(c *fiber.Ctx) error {
+	query := h.DB.WithContext(c.UserContext()).Order("id DESC").Limit(100)
+	if tokenID := c.Query("tokenId"); tokenID != "" {
+		query = query.Where("token_id = ?", tokenID)
+	}
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var transfers []models.TreasuryTransfer
+	if err := query.Find(&transfers).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to load transfers",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"transfers": transfers,
+	})
+}
+
+// Check runs a treasury check now rather than at the next interval
+func (h *TreasuryHandler) Check(c *fiber.Ctx) error {
+	if err := h.Treasury.Check(c.UserContext()); err != nil {
+		slog.Error("treasury check failed", "error", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Treasury check failed",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"slices"
+	"strings"
+
+	"faucet-backend/events"
+	"faucet-backend/models"
+	"faucet-backend/webhooks"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type WebhookRequest struct {
+	URL         string   `json:"url"`
+	Secret      string   `json:"secret"`
+	Events      []string `json:"events"`
+	TokenIDs    []string `json:"tokenIds"`
+	Description string   `json:"description"`
+}
+
+// WebhookHandler serves the admin webhook API
+type WebhookHandler struct {
+	DB         *gorm.DB
+	Dispatcher *webhooks.Dispatcher
+}
+
+func (h *WebhookHandler) CreateWebhook(c *fiber.Ctx) error {
+	var req WebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	parsed, err := url.Parse(req.URL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid webhook URL",
+		})
+	}
+
+	for _, event := range req.Events {
+		if !slices.Contains(events.All, event) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Unknown event type: " + event,
+			})
+		}
+	}
+
+	// Generate a signing secret unless the partner supplied one
+	secret := req.Secret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to generate secret",
+			})
+		}
+		secret = "whsec_" + hex.EncodeToString(buf)
+	}
+
+	endpoint := models.WebhookEndpoint{
+		URL:         req.URL,
+		Secret:      secret,
+		Events:      strings.Join(req.Events, ","),
+		TokenIDs:    strings.Join(req.TokenIDs, ","),
+		Description: req.Description,
+		IsActive:    true,
+	}
+
+	if err := h.DB.WithContext(c.UserContext()).Create(&endpoint).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to create webhook",
+		})
+	}
+
+	// The secret is only ever returned on creation
+	return c.Status(201).JSON(fiber.Map{
+		"webhook": endpoint,
+		"secret":  secret,
+	})
+}
+
+func (h *WebhookHandler) ListWebhooks(c *fiber.Ctx) error {
+	var endpoints []models.WebhookEndpoint
+	h.DB.WithContext(c.UserContext()).Order("id").Find(&endpoints)
+
+	return c.JSON(fiber.Map{
+		"webhooks": endpoints,
+	})
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid webhook ID",
+		})
+	}
+
+	result := h.DB.WithContext(c.UserContext()).Delete(&models.WebhookEndpoint{}, id)
+	if result.Error != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to delete webhook",
+		})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Webhook not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+func (h *WebhookHandler) ListWebhookDeliveries(c *fiber.Ctx) error {
+	query := h.DB.WithContext(c.UserContext()).Order("id DESC").Limit(c.QueryInt("limit", 100))
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if endpointID := c.QueryInt("endpointId"); endpointID != 0 {
+		query = query.Where("endpoint_id = ?", endpointID)
+	}
+
+	var deliveries []models.WebhookDelivery
+	query.Find(&deliveries)
+
+	return c.JSON(fiber.Map{
+		"deliveries": deliveries,
+	})
+}
+
+func (h *WebhookHandler) ReplayWebhookDelivery(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid delivery ID",
+		})
+	}
+
+	if err := h.Dispatcher.Replay(c.UserContext(), uint(id)); err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+func (h *WebhookHandler) ReplayFailedWebhookDeliveries(c *fiber.Ctx) error {
+	count, err := h.Dispatcher.ReplayFailed(c.UserContext(), uint(c.QueryInt("endpointId")))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to replay deliveries",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"replayed": count,
+	})
+}
@@ -1,97 +1,102 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"faucet-backend/app"
 	"faucet-backend/config"
-	"faucet-backend/database"
-	"faucet-backend/handlers"
-	"faucet-backend/middleware"
-	"faucet-backend/services"
-
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/logger"
-	"github.com/gofiber/fiber/v2/middleware/recover"
+	"faucet-backend/logging"
+	"faucet-backend/telemetry"
+
 	"github.com/joho/godotenv"
 )
 
 func main() {
-	// Force unbuffered output for Railway logs
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	log.SetOutput(os.Stdout)
-
-	log.Println("🚀 Starting faucet-backend...")
-
 	// Load .env in development
 	if os.Getenv("RAILWAY_ENVIRONMENT") == "" {
 		godotenv.Load()
 	}
 
-	// Check required env vars upfront
-	required := []string{"RPC_URL", "FAUCET_PRIVATE_KEY", "DATABASE_URL", "REDIS_URL"}
-	for _, env := range required {
-		if os.Getenv(env) == "" {
-			log.Printf("❌ FATAL: Required environment variable %s is not set", env)
-			os.Exit(1)
+	// Structured JSON logs on stdout for Railway
+	logging.Init()
+	slog.Info("starting faucet-backend")
+
+	// Background workers stop on SIGINT/SIGTERM; run then drains the server
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(ctx, os.Args[2:]); err != nil {
+			logging.Fatal("migration failed", "error", err)
 		}
+		return
 	}
-	log.Println("✅ All required environment variables present")
-
-	log.Println("📡 Initializing Ethereum wallet...")
-	services.InitWallet()
 
-	log.Println("🗄️ Connecting to PostgreSQL...")
-	database.Connect()
-	database.Migrate()
+	if err := run(ctx); err != nil {
+		logging.Fatal("faucet-backend stopped", "error", err)
+	}
+}
 
-	log.Println("📦 Connecting to Redis...")
-	database.ConnectRedis()
+func run(ctx context.Context) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
 
-	log.Println("🌱 Seeding tokens...")
-	config.SeedTokens()
+	shutdownTracing, err := telemetry.Init(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+	defer shutdownTracing(context.Background())
 
-	// Create Fiber app
-	app := fiber.New(fiber.Config{
-		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			code := fiber.StatusInternalServerError
-			if e, ok := err.(*fiber.Error); ok {
-				code = e.Code
+	a, err := app.New(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	a.Start(ctx)
+
+	// SIGHUP reloads the config file
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				if err := a.Reload(ctx); err != nil {
+					slog.Error("failed to reload config", "error", err)
+				} else {
+					slog.Info("config reloaded", "file", cfg.ConfigFile)
+				}
 			}
-			return c.Status(code).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		},
-	})
-
-	// Middleware
-	app.Use(recover.New())
-	app.Use(logger.New())
-	app.Use(middleware.CORS())
-
-	// Health check
-	app.Get("/health", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
-			"status": "ok",
-			"wallet": handlers.GetFaucetAddress(),
-		})
-	})
-
-	// API routes
-	api := app.Group("/api")
-
-	faucet := api.Group("/faucet")
-	faucet.Post("/drip", handlers.RequestDrip)
-	faucet.Get("/status/:address", handlers.GetStatus)
-	faucet.Get("/tokens", handlers.GetTokens)
-	faucet.Get("/stats", handlers.GetStats)
-
-	// Start server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "3000"
+		}
+	}()
+
+	served := make(chan error, 1)
+	go func() { served <- a.Listen() }()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
 	}
 
-	log.Printf("🚀 Multi-token faucet backend starting on port %s", port)
-	log.Fatal(app.Listen(":" + port))
+	slog.Info("shutting down", "timeout", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := a.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	slog.Info("faucet-backend stopped cleanly")
+	return nil
 }
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "faucet"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	Drips = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "drips_total",
+		Help:      "Drip outcomes by token, outcome and reason.",
+	}, []string{"token", "outcome", "reason"})
+
+	RateLimitRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Drip requests rejected by the rate limiter, by limit dimension.",
+	}, []string{"dimension"})
+
+	CaptchaFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "captcha_failures_total",
+		Help:      "CAPTCHA verifications that did not pass, by reason.",
+	}, []string{"reason"})
+
+	RPCDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_request_duration_seconds",
+		Help:      "Ethereum RPC call latency by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	RPCErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_errors_total",
+		Help:      "Ethereum RPC call errors by method.",
+	}, []string{"method"})
+
+	PendingTransactions = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_transactions",
+		Help:      "Drips broadcast or queued but not yet confirmed.",
+	})
+
+	WalletNonce = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "wallet_nonce",
+		Help:      "Next nonce the faucet wallet will use.",
+	})
+
+	WalletBalance = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "wallet_balance",
+		Help:      "Faucet wallet balance per token, in whole token units.",
+	}, []string{"token"})
+
+	TreasuryTransfers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "treasury_transfers_total",
+		Help:      "Refills, mints and sweeps of the faucet wallet by token, kind and outcome.",
+	}, []string{"token", "kind", "outcome"})
+
+	GasSpent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gas_spent_eth_total",
+		Help:      "Transaction fees paid for drips per token, in ETH.",
+	}, []string{"token"})
+)
+
+// ObserveRPC records the latency and outcome of an RPC call started at start
+func ObserveRPC(method string, start time.Time, err error) {
+	RPCDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		RPCErrors.WithLabelValues(method).Inc()
+	}
+}
@@ -0,0 +1,20 @@
+package metrics
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Serve exposes /metrics on its own port so it isn't reachable through the
+// public API
+func Serve(port string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	slog.Info("metrics server listening", "port", port)
+	if err := http.ListenAndServe(":"+port, mux); err != nil {
+		slog.Error("metrics server stopped", "error", err)
+	}
+}
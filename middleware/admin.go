@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const HeaderAdminKey = "X-Admin-Key"
+
+// AdminAuth requires the X-Admin-Key header to match adminKey. Admin routes
+// are disabled entirely when no key is configured.
+func AdminAuth(adminKey string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if adminKey == "" {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Not found",
+			})
+		}
+
+		provided := c.Get(HeaderAdminKey)
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(adminKey)) != 1 {
+			return c.Status(401).JSON(fiber.Map{
+				"error": "Invalid admin key",
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"strings"
+
+	"faucet-backend/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Identity resolves an optional "Authorization: Bearer <session>" header into
+// the logged-in OAuth identity, available via CurrentIdentity.
+func Identity(sessions *auth.Sessions) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		header := c.Get(fiber.HeaderAuthorization)
+		if header == "" {
+			return c.Next()
+		}
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || token == "" {
+			return c.Status(401).JSON(fiber.Map{
+				"error": "Invalid authorization header",
+			})
+		}
+
+		identity, err := sessions.Get(c.UserContext(), token)
+		if err != nil {
+			return c.Status(401).JSON(fiber.Map{
+				"error": "Session expired, please log in again",
+			})
+		}
+
+		c.Locals("identity", identity)
+		return c.Next()
+	}
+}
+
+// CurrentIdentity returns the identity set by the Identity middleware, or nil
+func CurrentIdentity(c *fiber.Ctx) *auth.Identity {
+	identity, _ := c.Locals("identity").(*auth.Identity)
+	return identity
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"faucet-backend/logging"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const HeaderRequestID = "X-Request-ID"
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID assigns every request a correlation ID (reusing a well-formed
+// incoming X-Request-ID), echoes it in the response and attaches a logger
+// carrying it to the request's user context.
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(HeaderRequestID)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+
+		c.Locals("requestId", id)
+		c.Set(HeaderRequestID, id)
+
+		logger := slog.Default().With("request_id", id)
+		c.SetUserContext(logging.WithLogger(c.UserContext(), logger))
+
+		return c.Next()
+	}
+}
+
+// GetRequestID returns the ID assigned by the RequestID middleware
+func GetRequestID(c *fiber.Ctx) string {
+	id, _ := c.Locals("requestId").(string)
+	return id
+}
+
+// AccessLog writes one structured line per request
+func AccessLog() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if e, ok := err.(*fiber.Error); ok {
+			status = e.Code
+		} else if err != nil {
+			status = fiber.StatusInternalServerError
+		}
+
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+
+		logging.FromContext(c.UserContext()).Log(c.UserContext(), level, "http request",
+			"method", c.Method(),
+			"path", c.Path(),
+			"route", c.Route().Path,
+			"status", status,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"ip", c.IP(),
+		)
+
+		return err
+	}
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
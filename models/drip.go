@@ -7,16 +7,31 @@ import (
 )
 
 type Drip struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	Recipient   string         `gorm:"size:42;not null;index:idx_recipient_token" json:"recipient"`
-	TokenID     string         `gorm:"size:20;not null;index:idx_recipient_token" json:"tokenId"`
-	Amount      string         `gorm:"size:30;not null" json:"amount"`
-	TxHash      string         `gorm:"size:66;index" json:"txHash"`
-	IPAddress   string         `gorm:"type:inet;index" json:"ipAddress"`
-	Fingerprint string         `gorm:"size:64;index" json:"fingerprint"`
-	Status      string         `gorm:"size:20;default:pending;index" json:"status"`
-	Error       string         `gorm:"type:text" json:"error,omitempty"`
-	CreatedAt   time.Time      `json:"createdAt"`
-	CompletedAt *time.Time     `json:"completedAt,omitempty"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	Recipient string `gorm:"size:42;not null;index:idx_recipient_token" json:"recipient"`
+	TokenID   string `gorm:"size:20;not null;index:idx_recipient_token" json:"tokenId"`
+	Amount    string `gorm:"size:80;not null" json:"amount"`
+	// AmountBaseUnits is Amount in the token's smallest unit
+	AmountBaseUnits string `gorm:"type:numeric(78,0)" json:"amountBaseUnits"`
+	TxHash          string `gorm:"size:66;index" json:"txHash"`
+	// NFTIDs are the comma-separated token IDs an NFT drip sent
+	NFTIDs string `gorm:"type:text" json:"nftIds,omitempty"`
+	// BatchID is the multi-token request the drip is part of
+	BatchID *uint `gorm:"index" json:"batchId,omitempty"`
+	// BlockNumber, GasUsed, EffectiveGasPrice and GasCost, the fee paid, come
+	// from the receipt. Prices and fees are in wei.
+	BlockNumber       *uint64        `json:"blockNumber,omitempty"`
+	GasUsed           *uint64        `json:"gasUsed,omitempty"`
+	EffectiveGasPrice string         `gorm:"type:numeric(78,0);default:null" json:"effectiveGasPrice,omitempty"`
+	GasCost           string         `gorm:"type:numeric(78,0);default:null" json:"gasCost,omitempty"`
+	IPAddress         string         `gorm:"type:inet;index;default:null" json:"ipAddress"`
+	Fingerprint       string         `gorm:"size:64;index" json:"fingerprint"`
+	IdentityID        string         `gorm:"size:64;index" json:"identityId,omitempty"`
+	RequestID         string         `gorm:"size:64;index" json:"requestId,omitempty"`
+	Status            string         `gorm:"size:20;default:pending;index" json:"status"`
+	Error             string         `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt         time.Time      `gorm:"index" json:"createdAt"`
+	UpdatedAt         time.Time      `gorm:"index" json:"updatedAt"`
+	CompletedAt       *time.Time     `json:"completedAt,omitempty"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
 }
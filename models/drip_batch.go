@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// DripBatch is one request for several tokens at once. Each token accepted
+// is a drip that references the batch.
+type DripBatch struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	Recipient string `gorm:"size:42;not null;index" json:"recipient"`
+	// Bundle is the named bundle requested, empty for a list of token IDs
+	Bundle string `gorm:"size:20" json:"bundle,omitempty"`
+	// TokenIDs are the comma-separated tokens requested
+	TokenIDs  string    `gorm:"type:text;not null" json:"tokenIds"`
+	RequestID string    `gorm:"size:64;index" json:"requestId,omitempty"`
+	CreatedAt time.Time `gorm:"index" json:"createdAt"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Identity is an external account (e.g. GitHub) a user logged in with.
+// ID is "<provider>:<provider user id>" and is what drips and quotas key on.
+type Identity struct {
+	ID               string         `gorm:"primaryKey;size:64" json:"id"`
+	Provider         string         `gorm:"size:20;not null;index" json:"provider"`
+	ProviderUserID   string         `gorm:"size:40;not null" json:"providerUserId"`
+	Login            string         `gorm:"size:100" json:"login"`
+	AccountCreatedAt time.Time      `json:"accountCreatedAt"`
+	LastLoginAt      time.Time      `json:"lastLoginAt"`
+	CreatedAt        time.Time      `json:"createdAt"`
+	UpdatedAt        time.Time      `json:"updatedAt"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Inventory item statuses
+const (
+	InventoryAvailable = "available"
+	InventoryReserved  = "reserved"
+	InventorySent      = "sent"
+	// InventoryMissing marks an ID the faucet wallet no longer owns
+	InventoryMissing = "missing"
+)
+
+// InventoryItem is one ERC-721 token ID the faucet wallet holds for drips of
+// a transfer-distributed collection
+type InventoryItem struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	TokenID string `gorm:"size:20;not null;uniqueIndex:idx_inventory_token_nft" json:"tokenId"`
+	NFTID   string `gorm:"type:numeric(78,0);not null;uniqueIndex:idx_inventory_token_nft" json:"nftId"`
+	Status  string `gorm:"size:20;not null;default:available;index" json:"status"`
+	// DripID is the drip the ID is reserved for or was sent by
+	DripID    *uint     `gorm:"index" json:"dripId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
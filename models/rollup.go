@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// DripRollup aggregates one token's drips requested in one hour, day or
+// week (UTC). Rows are rebuilt by the stats rollup job.
+type DripRollup struct {
+	Granularity string    `gorm:"primaryKey;size:10" json:"granularity"` // hour, day or week
+	BucketStart time.Time `gorm:"primaryKey" json:"bucketStart"`
+	TokenID     string    `gorm:"primaryKey;size:20" json:"tokenId"`
+	Drips       int64     `gorm:"not null;default:0" json:"drips"`
+	Completed   int64     `gorm:"not null;default:0" json:"completed"`
+	Failed      int64     `gorm:"not null;default:0" json:"failed"`
+	// UniqueUsers counts distinct recipients of completed drips
+	UniqueUsers int64 `gorm:"not null;default:0" json:"uniqueUsers"`
+	// AmountBaseUnits is the total of completed drips in the token's
+	// smallest unit
+	AmountBaseUnits string `gorm:"type:numeric(78,0);not null;default:0" json:"amountBaseUnits"`
+	// MedianConfirmationMs is the median time from request to confirmation
+	// of completed drips, nil when there are none
+	MedianConfirmationMs *int64 `json:"medianConfirmationMs"`
+	// Receipts counts drips mined, reverted or not; GasUsed and GasCost, in
+	// wei, are their totals
+	Receipts    int64     `gorm:"not null;default:0" json:"receipts"`
+	GasUsed     int64     `gorm:"not null;default:0" json:"gasUsed"`
+	GasCost     string    `gorm:"type:numeric(78,0);not null;default:0" json:"gasCost"`
+	RefreshedAt time.Time `gorm:"not null" json:"refreshedAt"`
+}
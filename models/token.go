@@ -10,17 +10,60 @@ type Token struct {
 	ID            string         `gorm:"primaryKey;size:20" json:"id"`
 	Name          string         `gorm:"size:50;not null" json:"name"`
 	Symbol        string         `gorm:"size:10;not null" json:"symbol"`
-	Address       string         `gorm:"size:42" json:"address"` // Empty for native ETH
+	Address       string         `gorm:"size:42" json:"address"`                // Empty for native ETH
+	Distribution  string         `gorm:"size:10" json:"distribution"`           // native, transfer or mint; see DistributionMode
+	MintSelector  string         `gorm:"size:10" json:"mintSelector,omitempty"` // 4-byte selector of the mint function; empty for the standard's default
+	Standard      string         `gorm:"size:10" json:"standard,omitempty"`     // erc721 or erc1155; empty for fungible tokens
+	NFTID         string         `gorm:"type:numeric(78,0);default:null" json:"nftId,omitempty"`
 	DripAmount    string         `gorm:"size:30;not null" json:"dripAmount"`
 	CooldownHours int            `gorm:"not null;default:24" json:"cooldownHours"`
 	Decimals      int            `gorm:"not null;default:18" json:"decimals"`
 	LogoURL       string         `gorm:"size:200" json:"logoUrl"`
 	IsActive      bool           `gorm:"default:true" json:"isActive"`
+	PausedAt      *time.Time     `json:"pausedAt,omitempty"` // Set while the balance can't cover drips
+	PausedReason  string         `gorm:"size:200" json:"pausedReason,omitempty"`
 	CreatedAt     time.Time      `json:"createdAt"`
 	UpdatedAt     time.Time      `json:"updatedAt"`
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// Distribution modes: how a token's drips are paid
+const (
+	// DistributeNative sends the chain's native token
+	DistributeNative = "native"
+	// DistributeTransfer transfers ERC20 tokens from the faucet's balance
+	DistributeTransfer = "transfer"
+	// DistributeMint mints ERC20 tokens to the recipient; the faucet wallet
+	// must be allowed to mint
+	DistributeMint = "mint"
+)
+
+// DistributionMode returns how the token's drips are paid. Tokens stored
+// before modes existed are native when they have no address and transfers
+// otherwise.
+func (t *Token) DistributionMode() string {
+	switch {
+	case t.Distribution != "":
+		return t.Distribution
+	case t.Address == "":
+		return DistributeNative
+	default:
+		return DistributeTransfer
+	}
+}
+
+// NFT standards. An ERC-721 drip sends one token; an ERC-1155 drip sends
+// DripAmount of the collection's NFTID.
+const (
+	StandardERC721  = "erc721"
+	StandardERC1155 = "erc1155"
+)
+
+// IsNFT reports whether the token is an ERC-721 or ERC-1155 collection
+func (t *Token) IsNFT() bool {
+	return t.Standard == StandardERC721 || t.Standard == StandardERC1155
+}
+
 type TokenStats struct {
 	TokenID    string `json:"tokenId"`
 	TotalDrips int64  `json:"totalDrips"`
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// TreasuryTransfer is an internal movement of faucet funds: a refill from
+// the treasury, a mint to the faucet wallet or a sweep back to the
+// treasury. They're kept apart from drips so drip stats only count users.
+type TreasuryTransfer struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	TokenID string `gorm:"size:20;not null;index" json:"tokenId"`
+	Kind    string `gorm:"size:10;not null" json:"kind"` // refill, mint or sweep
+	// FromAddress is the account that signed the transaction
+	FromAddress     string `gorm:"size:42;not null" json:"fromAddress"`
+	ToAddress       string `gorm:"size:42;not null" json:"toAddress"`
+	AmountBaseUnits string `gorm:"type:numeric(78,0);not null" json:"amountBaseUnits"`
+	TxHash          string `gorm:"size:66;index" json:"txHash"`
+	Status          string `gorm:"size:20;default:pending;index" json:"status"`
+	Error           string `gorm:"type:text" json:"error,omitempty"`
+	// BlockNumber and GasCost, the fee paid in wei, come from the receipt
+	BlockNumber *uint64    `json:"blockNumber,omitempty"`
+	GasCost     string     `gorm:"type:numeric(78,0);default:null" json:"gasCost,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
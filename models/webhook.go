@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookEndpoint is a partner URL subscribed to faucet events
+type WebhookEndpoint struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	URL         string         `gorm:"size:500;not null" json:"url"`
+	Secret      string         `gorm:"size:100;not null" json:"-"`
+	Events      string         `gorm:"size:200" json:"events"`   // Comma-separated event types, empty for all
+	TokenIDs    string         `gorm:"size:200" json:"tokenIds"` // Comma-separated token IDs, empty for all
+	Description string         `gorm:"size:200" json:"description"`
+	IsActive    bool           `gorm:"default:true" json:"isActive"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// WebhookDelivery is one event queued for one endpoint
+type WebhookDelivery struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	EndpointID    uint       `gorm:"not null;index" json:"endpointId"`
+	EventType     string     `gorm:"size:40;not null" json:"eventType"`
+	Payload       string     `gorm:"type:text;not null" json:"payload"`
+	Status        string     `gorm:"size:20;default:pending;index:idx_delivery_due" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"index:idx_delivery_due" json:"nextAttemptAt"`
+	LastError     string     `gorm:"type:text" json:"lastError,omitempty"`
+	DeliveredAt   *time.Time `json:"deliveredAt,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+// WebhookAttempt records the outcome of a single POST for a delivery
+type WebhookAttempt struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	DeliveryID   uint      `gorm:"not null;index" json:"deliveryId"`
+	ResponseCode int       `json:"responseCode"`
+	Error        string    `gorm:"type:text" json:"error,omitempty"`
+	DurationMs   int64     `json:"durationMs"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
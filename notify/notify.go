@@ -0,0 +1,127 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+const (
+	LevelInfo     = "info"
+	LevelWarning  = "warning"
+	LevelCritical = "critical"
+)
+
+// Alert is an operator-facing notification
+type Alert struct {
+	Level   string    `json:"level"`
+	Title   string    `json:"title"`
+	Message string    `json:"message"`
+	TokenID string    `json:"tokenId,omitempty"`
+	Balance string    `json:"balance,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Notifier delivers alerts to operators
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// LogNotifier writes alerts to the structured log
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, alert Alert) error {
+	level := slog.LevelInfo
+	switch alert.Level {
+	case LevelWarning:
+		level = slog.LevelWarn
+	case LevelCritical:
+		level = slog.LevelError
+	}
+
+	slog.Log(ctx, level, alert.Title,
+		"alert", true,
+		"message", alert.Message,
+		"token", alert.TokenID,
+		"balance", alert.Balance,
+	)
+	return nil
+}
+
+// WebhookNotifier posts alerts as JSON. The payload includes a "text" field
+// so it can point straight at a Slack or Discord-compatible incoming webhook.
+type WebhookNotifier struct {
+	URL    string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		URL: url,
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+	}
+}
+
+func (w *WebhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	payload, err := json.Marshal(struct {
+		Alert
+		Text    string `json:"text"`
+		Content string `json:"content"`
+	}{
+		Alert:   alert,
+		Text:    fmt.Sprintf("[%s] %s: %s", alert.Level, alert.Title, alert.Message),
+		Content: fmt.Sprintf("[%s] %s: %s", alert.Level, alert.Title, alert.Message),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("alert webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Multi fans an alert out to several notifiers
+type Multi []Notifier
+
+func (m Multi) Notify(ctx context.Context, alert Alert) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, alert); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// New always logs alerts, and also posts them to webhookURL if set
+func New(webhookURL string) Notifier {
+	notifiers := Multi{LogNotifier{}}
+	if webhookURL != "" {
+		notifiers = append(notifiers, NewWebhookNotifier(webhookURL))
+	}
+	return notifiers
+}
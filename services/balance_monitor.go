@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"time"
+
+	"faucet-backend/models"
+	"faucet-backend/notify"
+	"faucet-backend/units"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+)
+
+// BalanceMonitor pauses tokens whose faucet balance can't cover MinDrips more
+// drips, resumes them once refilled, and alerts on both transitions
+type BalanceMonitor struct {
+	DB     *gorm.DB
+	Wallet *Wallet
+	// Amounts sizes the drips the balance must cover; nil uses every
+	// token's fixed amount
+	Amounts  *DripAmounts
+	Interval time.Duration
+	MinDrips int64
+	Notifier notify.Notifier
+}
+
+// Start runs the monitor in the background until ctx is cancelled
+func (m *BalanceMonitor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(m.Interval)
+		defer ticker.Stop()
+
+		for {
+			m.CheckAll(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// CheckAll checks every active token once
+func (m *BalanceMonitor) CheckAll(ctx context.Context) {
+	var tokens []models.Token
+	if err := m.DB.WithContext(ctx).Where("is_active = true").Find(&tokens).Error; err != nil {
+		slog.Error("balance monitor failed to load tokens", "error", err)
+		return
+	}
+
+	for _, token := range tokens {
+		if err := m.check(ctx, &token); err != nil {
+			slog.Warn("balance check failed", "token", token.ID, "error", err)
+		}
+	}
+}
+
+func (m *BalanceMonitor) check(ctx context.Context, token *models.Token) error {
+	// Mint tokens don't drip from the wallet's balance. One paused before it
+	// was switched to minting is resumed, unless it's paused because the
+	// wallet can't mint.
+	if token.DistributionMode() == models.DistributeMint {
+		if token.PausedAt == nil || token.PausedReason == MintPauseReason {
+			return nil
+		}
+		return m.DB.WithContext(ctx).Model(token).Updates(map[string]interface{}{
+			"paused_at":     nil,
+			"paused_reason": "",
+		}).Error
+	}
+
+	balance, err := m.Wallet.TokenBalance(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	perDrip, err := m.Amounts.MinimumAmount(ctx, token, balance)
+	if err != nil {
+		return err
+	}
+	required := new(big.Int).Mul(perDrip, big.NewInt(m.MinDrips))
+	formatted := units.Format(balance, token.Decimals)
+
+	switch {
+	case balance.Cmp(required) < 0 && token.PausedAt == nil:
+		reason := fmt.Sprintf("balance %s %s can't cover %d drips", formatted, token.Symbol, m.MinDrips)
+		now := time.Now()
+		if err := m.DB.WithContext(ctx).Model(token).Updates(map[string]interface{}{
+			"paused_at":     now,
+			"paused_reason": reason,
+		}).Error; err != nil {
+			return err
+		}
+
+		m.notify(ctx, notify.Alert{
+			Level:   notify.LevelCritical,
+			Title:   fmt.Sprintf("%s drips paused", token.Symbol),
+			Message: reason + "; refill " + m.Wallet.Address() + " to resume",
+			TokenID: token.ID,
+			Balance: formatted,
+		})
+
+	case balance.Cmp(required) >= 0 && token.PausedAt != nil:
+		if err := m.DB.WithContext(ctx).Model(token).Updates(map[string]interface{}{
+			"paused_at":     nil,
+			"paused_reason": "",
+		}).Error; err != nil {
+			return err
+		}
+
+		m.notify(ctx, notify.Alert{
+			Level:   notify.LevelInfo,
+			Title:   fmt.Sprintf("%s drips resumed", token.Symbol),
+			Message: fmt.Sprintf("balance refilled to %s %s", formatted, token.Symbol),
+			TokenID: token.ID,
+			Balance: formatted,
+		})
+	}
+
+	return nil
+}
+
+func (m *BalanceMonitor) notify(ctx context.Context, alert notify.Alert) {
+	alert.Time = time.Now()
+	if err := m.Notifier.Notify(ctx, alert); err != nil {
+		slog.Error("failed to send alert", "title", alert.Title, "error", err)
+	}
+}
+
+// TokenBalance returns the faucet's balance of token in base units, or the
+// number of NFTs held
+func (w *Wallet) TokenBalance(ctx context.Context, token *models.Token) (*big.Int, error) {
+	switch {
+	case token.Address == "":
+		return w.NativeBalance(ctx)
+	case token.Standard == models.StandardERC1155:
+		return w.ERC1155Balance(ctx, common.HexToAddress(token.Address), nftID(token))
+	}
+	// ERC-721 balanceOf counts the IDs held
+	return w.ERC20Balance(ctx, common.HexToAddress(token.Address))
+}
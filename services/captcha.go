@@ -1,47 +1,87 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
-	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"faucet-backend/metrics"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
+var captchaClient = &http.Client{
+	Timeout:   10 * time.Second,
+	Transport: otelhttp.NewTransport(http.DefaultTransport),
+}
+
 type CaptchaResponse struct {
 	Success bool `json:"success"`
 }
 
-func VerifyCaptcha(token, remoteIP string) error {
-	secretKey := os.Getenv("GOTCHA_SECRET_KEY")
-	if secretKey == "" {
-		return errors.New("GOTCHA_SECRET_KEY not configured")
-	}
+// GotchaCaptcha verifies responses with the configured CAPTCHA provider
+type GotchaCaptcha struct {
+	SecretKey string
+	VerifyURL string
+	// Disabled skips verification entirely
+	Disabled bool
+
+	mu sync.RWMutex
+}
 
-	// Use your custom CAPTCHA URL
-	verifyURL := os.Getenv("GOTCHA_VERIFY_URL")
-	if verifyURL == "" {
-		verifyURL = "http://api.gotcha.land/api/siteverify"
+// Configure changes the verify URL and whether CAPTCHAs are checked. It is
+// safe to call while requests are being verified.
+func (g *GotchaCaptcha) Configure(verifyURL string, disabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.VerifyURL = verifyURL
+	g.Disabled = disabled
+}
+
+func (g *GotchaCaptcha) Verify(ctx context.Context, token, remoteIP string) error {
+	g.mu.RLock()
+	verifyURL, disabled := g.VerifyURL, g.Disabled
+	g.mu.RUnlock()
+
+	if disabled {
+		return nil
+	}
+	if g.SecretKey == "" {
+		return errors.New("GOTCHA_SECRET_KEY not configured")
 	}
 
-	resp, err := http.PostForm(verifyURL, url.Values{
-		"secret":   {secretKey},
+	form := url.Values{
+		"secret":   {g.SecretKey},
 		"response": {token},
 		"remoteip": {remoteIP},
-	})
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
+	resp, err := captchaClient.Do(req)
 	if err != nil {
+		metrics.CaptchaFailures.WithLabelValues("request_error").Inc()
 		return fmt.Errorf("captcha verification request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	var captchaResp CaptchaResponse
 	if err := json.NewDecoder(resp.Body).Decode(&captchaResp); err != nil {
+		metrics.CaptchaFailures.WithLabelValues("invalid_response").Inc()
 		return fmt.Errorf("failed to parse captcha response: %w", err)
 	}
 
 	if !captchaResp.Success {
+		metrics.CaptchaFailures.WithLabelValues("rejected").Inc()
 		return errors.New("captcha verification failed")
 	}
 
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"faucet-backend/metrics"
+	"faucet-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// GormDripStore stores tokens and drips in Postgres
+type GormDripStore struct {
+	DB *gorm.DB
+}
+
+// FindActiveToken looks the token up by ID. Token IDs are lowercase.
+func (s *GormDripStore) FindActiveToken(ctx context.Context, id string) (*models.Token, error) {
+	var token models.Token
+	err := s.DB.WithContext(ctx).
+		Where("id = ? AND is_active = true", strings.ToLower(id)).
+		First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTokenUnavailable
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (s *GormDripStore) FindActiveTokenBySymbol(ctx context.Context, symbol string) (*models.Token, error) {
+	var tokens []models.Token
+	err := s.DB.WithContext(ctx).
+		Where("LOWER(symbol) = ? AND is_active = true", strings.ToLower(symbol)).
+		Order("id").
+		Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(tokens) {
+	case 0:
+		return nil, ErrTokenUnavailable
+	case 1:
+		return &tokens[0], nil
+	}
+	ambiguous := &AmbiguousTokenError{Symbol: tokens[0].Symbol}
+	for _, token := range tokens {
+		ambiguous.TokenIDs = append(ambiguous.TokenIDs, token.ID)
+	}
+	return nil, ambiguous
+}
+
+func (s *GormDripStore) CreateDrip(ctx context.Context, drip *models.Drip) error {
+	return s.DB.WithContext(ctx).Create(drip).Error
+}
+
+func (s *GormDripStore) CreateBatch(ctx context.Context, batch *models.DripBatch) error {
+	return s.DB.WithContext(ctx).Create(batch).Error
+}
+
+// PrometheusDripMetrics counts drip outcomes in the faucet's Prometheus
+// metrics
+type PrometheusDripMetrics struct{}
+
+func (PrometheusDripMetrics) Drip(tokenID, outcome, reason string) {
+	metrics.Drips.WithLabelValues(tokenID, outcome, reason).Inc()
+}
+
+func (PrometheusDripMetrics) RateLimited(dimension string) {
+	metrics.RateLimitRejections.WithLabelValues(dimension).Inc()
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"faucet-backend/models"
+	"faucet-backend/units"
+
+	"gorm.io/gorm"
+)
+
+// Amount policy kinds
+const (
+	AmountFixed   = "fixed"
+	AmountBalance = "balance"
+	AmountDemand  = "demand"
+)
+
+// demandWindow is how far back a demand policy counts requests
+const demandWindow = time.Hour
+
+// AmountPolicy sizes one token's drips. Amounts are in base units; Min and
+// Max are nil when unbounded.
+type AmountPolicy struct {
+	TokenID string
+	Kind    string
+	// PartsPerMillion is the share of the faucet balance a balance drip
+	// sends
+	PartsPerMillion int64
+	// TargetPerHour is the hourly request rate at which a demand drip is
+	// the token's drip amount
+	TargetPerHour int
+	Min           *big.Int
+	Max           *big.Int
+}
+
+// DripAmounts computes what a drip sends when it's requested. Tokens without
+// a policy drip their fixed DripAmount.
+type DripAmounts struct {
+	DB     *gorm.DB
+	Wallet *Wallet
+
+	policies atomic.Pointer[map[string]AmountPolicy]
+}
+
+// Configure replaces the amount policies. It is safe to call while drips
+// are being requested.
+func (a *DripAmounts) Configure(policies []AmountPolicy) {
+	byToken := make(map[string]AmountPolicy, len(policies))
+	for _, policy := range policies {
+		byToken[policy.TokenID] = policy
+	}
+	a.policies.Store(&byToken)
+}
+
+// Policy returns the token's amount policy kind
+func (a *DripAmounts) Policy(tokenID string) string {
+	if policy, ok := a.policy(tokenID); ok {
+		return policy.Kind
+	}
+	return AmountFixed
+}
+
+func (a *DripAmounts) policy(tokenID string) (AmountPolicy, bool) {
+	if a == nil {
+		return AmountPolicy{}, false
+	}
+	policies := a.policies.Load()
+	if policies == nil {
+		return AmountPolicy{}, false
+	}
+	policy, ok := (*policies)[tokenID]
+	return policy, ok && policy.Kind != AmountFixed
+}
+
+// Amount returns the base units a drip of token sends now
+func (a *DripAmounts) Amount(ctx context.Context, token *models.Token) (*big.Int, error) {
+	return a.amount(ctx, token, nil)
+}
+
+// MinimumAmount returns the smallest drip token's policy sends at balance,
+// in base units: the current amount, or for a balance policy, which shrinks
+// drips with the balance, its Min. It's zero when drips can always scale
+// down to what the faucet holds.
+func (a *DripAmounts) MinimumAmount(ctx context.Context, token *models.Token, balance *big.Int) (*big.Int, error) {
+	if policy, ok := a.policy(token.ID); ok && policy.Kind == AmountBalance {
+		if policy.Min == nil {
+			return new(big.Int), nil
+		}
+		return policy.Min, nil
+	}
+	return a.amount(ctx, token, balance)
+}
+
+// amount is Amount with the faucet's balance of token when the caller has
+// already read it. A zero amount means the token can't drip now, which
+// callers report as paused.
+func (a *DripAmounts) amount(ctx context.Context, token *models.Token, balance *big.Int) (*big.Int, error) {
+	fixed, err := units.Parse(token.DripAmount, token.Decimals)
+	if err != nil {
+		return nil, fmt.Errorf("token %s drip amount: %w", token.ID, err)
+	}
+
+	policy, ok := a.policy(token.ID)
+	if !ok {
+		return fixed, nil
+	}
+
+	amount, ceiling := new(big.Int), policy.Max
+	switch policy.Kind {
+	case AmountBalance:
+		if balance == nil {
+			if balance, err = a.Wallet.TokenBalance(ctx, token); err != nil {
+				return nil, fmt.Errorf("failed to read %s balance: %w", token.ID, err)
+			}
+		}
+		// The drip never exceeds what the faucet holds, so a balance under
+		// Min pauses the token
+		if policy.Min != nil && balance.Cmp(policy.Min) < 0 {
+			return new(big.Int), nil
+		}
+		if ceiling == nil || ceiling.Cmp(balance) > 0 {
+			ceiling = balance
+		}
+		amount.Mul(balance, big.NewInt(policy.PartsPerMillion))
+		amount.Div(amount, big.NewInt(1_000_000))
+	case AmountDemand:
+		// Failed drips sent nothing, so they don't count towards demand
+		var recent int64
+		if err := a.DB.WithContext(ctx).Model(&models.Drip{}).
+			Where("token_id = ? AND status IN ? AND created_at > ?", token.ID, []string{"pending", "completed"}, time.Now().Add(-demandWindow)).
+			Count(&recent).Error; err != nil {
+			return nil, fmt.Errorf("failed to count recent %s drips: %w", token.ID, err)
+		}
+		// At the target rate the drip is the fixed amount and each request
+		// over it shrinks the drip. Under the target it grows up to Max, or
+		// stays at the fixed amount when there's no Max.
+		amount.Mul(fixed, big.NewInt(int64(policy.TargetPerHour)))
+		amount.Div(amount, big.NewInt(max(recent, 1)))
+		if policy.Max == nil && amount.Cmp(fixed) > 0 {
+			amount = fixed
+		}
+	default:
+		return fixed, nil
+	}
+
+	if policy.Min != nil && amount.Cmp(policy.Min) < 0 {
+		amount = policy.Min
+	}
+	if ceiling != nil && amount.Cmp(ceiling) > 0 {
+		amount = ceiling
+	}
+	return amount, nil
+}
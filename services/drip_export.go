@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"faucet-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// exportBatchSize is how many drips an export reads per query
+const exportBatchSize = 1000
+
+// DripExportFilter selects the drips to export. Empty fields match
+// everything.
+type DripExportFilter struct {
+	TokenID   string
+	Status    string
+	Recipient string
+	// From and To bound the request time, inclusive and exclusive
+	From, To *time.Time
+}
+
+// DripExportRow is one exported drip
+type DripExportRow struct {
+	ID              uint       `json:"id"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	CompletedAt     *time.Time `json:"completedAt"`
+	Recipient       string     `json:"recipient"`
+	TokenID         string     `json:"tokenId"`
+	TokenSymbol     string     `json:"tokenSymbol"`
+	Amount          string     `json:"amount"`
+	AmountBaseUnits string     `json:"amountBaseUnits"`
+	Status          string     `json:"status"`
+	TxHash          string     `json:"txHash"`
+	NFTIDs          string     `json:"nftIds"`
+	BlockNumber     *uint64    `json:"blockNumber"`
+	GasUsed         *uint64    `json:"gasUsed"`
+	// EffectiveGasPrice and GasCost, the transaction fee, are in wei
+	EffectiveGasPrice string `json:"effectiveGasPrice"`
+	GasCost           string `json:"gasCost"`
+	Error             string `json:"error"`
+}
+
+// ExportDrips calls fn with every drip matching filter, oldest first. Rows
+// are read in batches with a keyset cursor on the drip ID, so an export of
+// any size holds one batch in memory and no long-running transaction.
+func ExportDrips(ctx context.Context, db *gorm.DB, filter DripExportFilter, fn func(*DripExportRow) error) error {
+	var after uint
+	for {
+		query := db.WithContext(ctx).Model(&models.Drip{}).
+			Select("drips.id, drips.created_at, drips.completed_at, drips.recipient, drips.token_id, tokens.symbol AS token_symbol, "+
+				"drips.amount, drips.amount_base_units, drips.status, drips.tx_hash, drips.nft_ids, drips.block_number, drips.gas_used, drips.effective_gas_price, drips.gas_cost, drips.error").
+			Joins("LEFT JOIN tokens ON tokens.id = drips.token_id").
+			Where("drips.id > ?", after).
+			Order("drips.id").
+			Limit(exportBatchSize)
+
+		if filter.TokenID != "" {
+			query = query.Where("drips.token_id = ?", filter.TokenID)
+		}
+		if filter.Status != "" {
+			query = query.Where("drips.status = ?", filter.Status)
+		}
+		if filter.Recipient != "" {
+			query = query.Where("drips.recipient = ?", filter.Recipient)
+		}
+		if filter.From != nil {
+			query = query.Where("drips.created_at >= ?", *filter.From)
+		}
+		if filter.To != nil {
+			query = query.Where("drips.created_at < ?", *filter.To)
+		}
+
+		var rows []DripExportRow
+		if err := query.Scan(&rows).Error; err != nil {
+			return fmt.Errorf("failed to read drips after %d: %w", after, err)
+		}
+
+		for i := range rows {
+			if err := fn(&rows[i]); err != nil {
+				return err
+			}
+		}
+		if len(rows) < exportBatchSize {
+			return nil
+		}
+		after = rows[len(rows)-1].ID
+	}
+}
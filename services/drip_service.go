@@ -0,0 +1,459 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync/atomic"
+
+	"faucet-backend/auth"
+	"faucet-backend/events"
+	"faucet-backend/logging"
+	"faucet-backend/models"
+	"faucet-backend/units"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DripStore persists tokens and drips
+type DripStore interface {
+	// FindActiveToken looks a token up by ID, returning ErrTokenUnavailable
+	// if it doesn't exist or is inactive
+	FindActiveToken(ctx context.Context, id string) (*models.Token, error)
+	// FindActiveTokenBySymbol looks an active token up by symbol, ignoring
+	// case. It returns ErrTokenUnavailable when none matches and an
+	// *AmbiguousTokenError when several do.
+	FindActiveTokenBySymbol(ctx context.Context, symbol string) (*models.Token, error)
+	CreateDrip(ctx context.Context, drip *models.Drip) error
+	CreateBatch(ctx context.Context, batch *models.DripBatch) error
+}
+
+// LimitKey identifies a requester along every rate-limit dimension. Empty
+// fields are not limited.
+type LimitKey struct {
+	Wallet      string
+	TokenID     string
+	IP          string
+	Fingerprint string
+	Identity    string
+}
+
+// Limiter enforces per-requester quotas
+type Limiter interface {
+	Check(ctx context.Context, key LimitKey) (*RateLimitCheck, error)
+	Record(ctx context.Context, key LimitKey) error
+}
+
+// CaptchaVerifier checks a CAPTCHA response token
+type CaptchaVerifier interface {
+	Verify(ctx context.Context, token, remoteIP string) error
+}
+
+// DripSender sends created drips on-chain and tracks them to completion.
+// Send and SendBatch return immediately; the work runs on the sender's own
+// goroutines.
+type DripSender interface {
+	Send(ctx context.Context, drip *models.Drip)
+	// SendBatch sends the drips of one batch back to back, in order
+	SendBatch(ctx context.Context, drips []*models.Drip)
+}
+
+// AmountCalculator sizes drips. A zero amount means the token can't drip
+// now.
+type AmountCalculator interface {
+	Amount(ctx context.Context, token *models.Token) (*big.Int, error)
+}
+
+// EventPublisher publishes drip lifecycle events
+type EventPublisher interface {
+	Publish(ctx context.Context, event events.Event)
+}
+
+// DripMetrics counts the requests the service accepts and rejects
+type DripMetrics interface {
+	// Drip counts one drip outcome, with the reason for rejections
+	Drip(tokenID, outcome, reason string)
+	// RateLimited counts a rejection by the named limit dimension
+	RateLimited(dimension string)
+}
+
+// DripRequest is a request for tokens from any front-end
+type DripRequest struct {
+	Address      string
+	TokenID      string
+	CaptchaToken string
+	Fingerprint  string
+	IP           string
+	Identity     *auth.Identity
+	RequestID    string
+	// SkipCaptcha is set by front-ends whose users are already
+	// authenticated by their platform (chat bots)
+	SkipCaptcha bool
+	// AllowSymbol lets TokenID name a token by symbol when no token has
+	// that ID, for front-ends where users type the token (chat bots)
+	AllowSymbol bool
+}
+
+// DripResult is an accepted drip; the transaction is sent asynchronously
+type DripResult struct {
+	Drip  *models.Drip
+	Token *models.Token
+}
+
+// MaxBundleTokens caps how many tokens one request lists
+const MaxBundleTokens = 10
+
+// BundleRequest is a request for several tokens at once, either listed by
+// ID in TokenIDs or named by a configured Bundle. The embedded
+// request's TokenID is ignored.
+type BundleRequest struct {
+	DripRequest
+	TokenIDs []string
+	Bundle   string
+}
+
+// BundleItem is the outcome for one requested token: Drip when it was
+// accepted, Err when it was rejected. Token is nil for unknown tokens.
+type BundleItem struct {
+	TokenID string
+	Token   *models.Token
+	Drip    *models.Drip
+	Err     error
+}
+
+// BundleResult is the outcome of a bundle request. Batch is nil when no
+// token could be requested.
+type BundleResult struct {
+	Batch *models.DripBatch
+	Items []BundleItem
+}
+
+var (
+	ErrInvalidAddress   = errors.New("invalid Ethereum address")
+	ErrTokenUnavailable = errors.New("invalid or inactive token")
+	ErrLoginRequired    = errors.New("login required to request tokens")
+	ErrCaptchaFailed    = errors.New("CAPTCHA verification failed")
+	ErrInvalidBundle    = errors.New("invalid token bundle request")
+	ErrUnknownBundle    = errors.New("unknown token bundle")
+)
+
+// TokenPausedError is returned while a token's faucet balance is empty
+type TokenPausedError struct {
+	Symbol string
+}
+
+func (e *TokenPausedError) Error() string {
+	return fmt.Sprintf("%s is temporarily empty, please try again later", e.Symbol)
+}
+
+// AmbiguousTokenError is returned when a symbol names several active tokens
+type AmbiguousTokenError struct {
+	Symbol   string
+	TokenIDs []string
+}
+
+func (e *AmbiguousTokenError) Error() string {
+	return fmt.Sprintf("%s matches several tokens (%s), use the token ID", e.Symbol, strings.Join(e.TokenIDs, ", "))
+}
+
+// RateLimitError is returned when a quota is exhausted
+type RateLimitError struct {
+	Reason     string
+	RetryAfter int64
+	Dimension  string
+}
+
+func (e *RateLimitError) Error() string {
+	return e.Reason
+}
+
+// DripService runs the drip pipeline shared by every front-end: token
+// lookup, identity eligibility, CAPTCHA, rate limits, persistence and the
+// asynchronous send
+type DripService struct {
+	Store   DripStore
+	Limiter Limiter
+	Captcha CaptchaVerifier
+	Sender  DripSender
+	Auth    auth.Policy
+	Events  EventPublisher
+	Metrics DripMetrics
+	// Amounts sizes each drip; nil drips every token's fixed amount
+	Amounts AmountCalculator
+
+	bundles atomic.Pointer[map[string][]string]
+}
+
+// SetBundles replaces the named token bundles. It is safe to call while
+// requests are being served.
+func (s *DripService) SetBundles(bundles map[string][]string) {
+	s.bundles.Store(&bundles)
+}
+
+// Bundles returns the named token bundles
+func (s *DripService) Bundles() map[string][]string {
+	if bundles := s.bundles.Load(); bundles != nil {
+		return *bundles
+	}
+	return nil
+}
+
+func (s *DripService) Request(ctx context.Context, req DripRequest) (DripResult, error) {
+	if !common.IsHexAddress(req.Address) {
+		return DripResult{}, ErrInvalidAddress
+	}
+	address := common.HexToAddress(req.Address).Hex()
+
+	// Verify token exists and is active
+	token, err := s.Store.FindActiveToken(ctx, req.TokenID)
+	if errors.Is(err, ErrTokenUnavailable) && req.AllowSymbol {
+		token, err = s.Store.FindActiveTokenBySymbol(ctx, req.TokenID)
+	}
+	if err != nil {
+		return DripResult{}, err
+	}
+
+	if token.PausedAt != nil {
+		s.Metrics.Drip(token.ID, "rejected", "paused")
+		return DripResult{}, &TokenPausedError{Symbol: token.Symbol}
+	}
+
+	identityID, err := s.admit(ctx, req, address, []*models.Token{token})
+	if err != nil {
+		return DripResult{}, err
+	}
+
+	drip, err := s.accept(ctx, req, address, identityID, token, nil)
+	if err != nil {
+		return DripResult{}, err
+	}
+
+	// Execute transaction (async)
+	s.Sender.Send(ctx, drip)
+
+	return DripResult{Drip: drip, Token: token}, nil
+}
+
+// RequestBundle runs the drip pipeline for several tokens at once. The
+// requester is checked and the CAPTCHA verified once, then each token is
+// limited and accepted on its own, so one exhausted quota doesn't reject
+// the others. The accepted drips are recorded under one batch and sent back
+// to back. It returns an error only when the request as a whole is
+// rejected; per-token rejections are in the result's items.
+func (s *DripService) RequestBundle(ctx context.Context, req BundleRequest) (BundleResult, error) {
+	if !common.IsHexAddress(req.Address) {
+		return BundleResult{}, ErrInvalidAddress
+	}
+	address := common.HexToAddress(req.Address).Hex()
+
+	tokenIDs, err := s.bundleTokens(req)
+	if err != nil {
+		return BundleResult{}, err
+	}
+
+	var result BundleResult
+	var live []*models.Token
+	seen := make(map[string]bool)
+	for _, id := range tokenIDs {
+		token, err := s.Store.FindActiveToken(ctx, id)
+		switch {
+		case errors.Is(err, ErrTokenUnavailable):
+			result.Items = append(result.Items, BundleItem{TokenID: id, Err: err})
+			continue
+		case err != nil:
+			return BundleResult{}, err
+		case seen[token.ID]:
+			continue
+		}
+		seen[token.ID] = true
+
+		item := BundleItem{TokenID: token.ID, Token: token}
+		if token.PausedAt != nil {
+			s.Metrics.Drip(token.ID, "rejected", "paused")
+			item.Err = &TokenPausedError{Symbol: token.Symbol}
+		} else {
+			live = append(live, token)
+		}
+		result.Items = append(result.Items, item)
+	}
+	if len(live) == 0 {
+		return result, nil
+	}
+
+	identityID, err := s.admit(ctx, req.DripRequest, address, live)
+	if err != nil {
+		return BundleResult{}, err
+	}
+
+	requested := make([]string, len(result.Items))
+	for i, item := range result.Items {
+		requested[i] = item.TokenID
+	}
+	batch := &models.DripBatch{
+		Recipient: address,
+		Bundle:    req.Bundle,
+		TokenIDs:  strings.Join(requested, ","),
+		RequestID: req.RequestID,
+	}
+	if err := s.Store.CreateBatch(ctx, batch); err != nil {
+		logging.FromContext(ctx).Error("failed to create drip batch", "recipient", address, "error", err)
+		return BundleResult{}, fmt.Errorf("failed to create drip batch: %w", err)
+	}
+	result.Batch = batch
+
+	var drips []*models.Drip
+	for i := range result.Items {
+		item := &result.Items[i]
+		if item.Token == nil || item.Err != nil {
+			continue
+		}
+		item.Drip, item.Err = s.accept(ctx, req.DripRequest, address, identityID, item.Token, &batch.ID)
+		if item.Drip != nil {
+			drips = append(drips, item.Drip)
+		}
+	}
+
+	// Execute transactions (async)
+	if len(drips) > 0 {
+		s.Sender.SendBatch(ctx, drips)
+	}
+
+	return result, nil
+}
+
+// bundleTokens returns the token IDs or symbols a bundle request names
+func (s *DripService) bundleTokens(req BundleRequest) ([]string, error) {
+	switch {
+	case req.Bundle != "" && len(req.TokenIDs) > 0:
+		return nil, fmt.Errorf("%w: request either a bundle or a list of tokens", ErrInvalidBundle)
+	case req.Bundle != "":
+		tokenIDs, ok := s.Bundles()[strings.ToLower(req.Bundle)]
+		if !ok {
+			return nil, ErrUnknownBundle
+		}
+		return tokenIDs, nil
+	case len(req.TokenIDs) == 0:
+		return nil, fmt.Errorf("%w: no tokens requested", ErrInvalidBundle)
+	case len(req.TokenIDs) > MaxBundleTokens:
+		return nil, fmt.Errorf("%w: at most %d tokens per request", ErrInvalidBundle, MaxBundleTokens)
+	}
+	return req.TokenIDs, nil
+}
+
+// admit checks the requester may request the tokens at all: identity
+// eligibility and the CAPTCHA. It returns the requester's identity key.
+func (s *DripService) admit(ctx context.Context, req DripRequest, address string, tokens []*models.Token) (string, error) {
+	reject := func(reason string) {
+		for _, token := range tokens {
+			s.Metrics.Drip(token.ID, "rejected", reason)
+		}
+	}
+
+	// Check identity eligibility
+	identityID := ""
+	if req.Identity == nil && s.Auth.Required {
+		reject("login_required")
+		return "", ErrLoginRequired
+	}
+	if req.Identity != nil {
+		if err := s.Auth.CheckAccountAge(req.Identity); err != nil {
+			reject("account_age")
+			return "", err
+		}
+		identityID = req.Identity.Key()
+	}
+
+	// Verify CAPTCHA
+	if !req.SkipCaptcha {
+		if err := s.Captcha.Verify(ctx, req.CaptchaToken, req.IP); err != nil {
+			reject("captcha")
+			ids := make([]string, len(tokens))
+			for i, token := range tokens {
+				ids[i] = token.ID
+			}
+			logging.FromContext(ctx).Info("captcha rejected", "token", strings.Join(ids, ","), "recipient", address, "error", err)
+			return "", ErrCaptchaFailed
+		}
+	}
+
+	return identityID, nil
+}
+
+// accept checks the token's rate limits and records a pending drip of it.
+// The caller sends the drip.
+func (s *DripService) accept(ctx context.Context, req DripRequest, address, identityID string, token *models.Token, batchID *uint) (*models.Drip, error) {
+	logger := logging.FromContext(ctx)
+
+	// Check rate limits
+	key := LimitKey{
+		Wallet:      address,
+		TokenID:     token.ID,
+		IP:          req.IP,
+		Fingerprint: req.Fingerprint,
+		Identity:    identityID,
+	}
+	check, err := s.Limiter.Check(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("rate limit check failed: %w", err)
+	}
+	if !check.Allowed {
+		s.Metrics.Drip(token.ID, "rejected", "rate_limit")
+		s.Metrics.RateLimited(check.Dimension)
+		return nil, &RateLimitError{
+			Reason:     check.Reason,
+			RetryAfter: check.RetryAfter,
+			Dimension:  check.Dimension,
+		}
+	}
+
+	// Size the drip by the token's amount policy
+	baseUnits, err := s.amount(ctx, token)
+	if err != nil {
+		logger.Error("failed to compute drip amount", "token", token.ID, "amount", token.DripAmount, "error", err)
+		return nil, err
+	}
+	if baseUnits.Sign() == 0 {
+		s.Metrics.Drip(token.ID, "rejected", "paused")
+		return nil, &TokenPausedError{Symbol: token.Symbol}
+	}
+
+	// Create drip record
+	drip := &models.Drip{
+		Recipient:       address,
+		TokenID:         token.ID,
+		Amount:          units.Format(baseUnits, token.Decimals),
+		AmountBaseUnits: baseUnits.String(),
+		IPAddress:       req.IP,
+		Fingerprint:     req.Fingerprint,
+		IdentityID:      identityID,
+		RequestID:       req.RequestID,
+		BatchID:         batchID,
+		Status:          "pending",
+	}
+	if err := s.Store.CreateDrip(ctx, drip); err != nil {
+		logger.Error("failed to create drip record", "token", token.ID, "recipient", address, "error", err)
+		return nil, fmt.Errorf("failed to create drip record: %w", err)
+	}
+
+	s.Metrics.Drip(token.ID, "accepted", "")
+	s.Events.Publish(ctx, events.NewDripEvent(events.DripCreated, drip))
+	logger.Info("drip accepted", "drip_id", drip.ID, "token", token.ID, "recipient", address, "tx_hash", "")
+
+	// Record in rate limiter
+	if err := s.Limiter.Record(ctx, key); err != nil {
+		logger.Error("failed to record drip in rate limiter", "drip_id", drip.ID, "error", err)
+	}
+
+	return drip, nil
+}
+
+// amount sizes a drip of token by the configured calculator, or as the
+// token's fixed amount when there is none
+func (s *DripService) amount(ctx context.Context, token *models.Token) (*big.Int, error) {
+	if s.Amounts == nil {
+		return units.Parse(token.DripAmount, token.Decimals)
+	}
+	return s.Amounts.Amount(ctx, token)
+}
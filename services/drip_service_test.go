@@ -0,0 +1,431 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"faucet-backend/auth"
+	"faucet-backend/events"
+	"faucet-backend/models"
+	"faucet-backend/units"
+)
+
+type fakeStore struct {
+	tokens  map[string]*models.Token
+	drips   []*models.Drip
+	batches []*models.DripBatch
+	err     error
+}
+
+func (s *fakeStore) FindActiveToken(ctx context.Context, id string) (*models.Token, error) {
+	token, ok := s.tokens[strings.ToLower(id)]
+	if !ok || !token.IsActive {
+		return nil, ErrTokenUnavailable
+	}
+	return token, nil
+}
+
+func (s *fakeStore) FindActiveTokenBySymbol(ctx context.Context, symbol string) (*models.Token, error) {
+	var found []*models.Token
+	for _, token := range s.tokens {
+		if token.IsActive && strings.EqualFold(token.Symbol, symbol) {
+			found = append(found, token)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, ErrTokenUnavailable
+	case 1:
+		return found[0], nil
+	}
+	ambiguous := &AmbiguousTokenError{Symbol: found[0].Symbol}
+	for _, token := range found {
+		ambiguous.TokenIDs = append(ambiguous.TokenIDs, token.ID)
+	}
+	sort.Strings(ambiguous.TokenIDs)
+	return nil, ambiguous
+}
+
+func (s *fakeStore) CreateDrip(ctx context.Context, drip *models.Drip) error {
+	if s.err != nil {
+		return s.err
+	}
+	drip.ID = uint(len(s.drips) + 1)
+	s.drips = append(s.drips, drip)
+	return nil
+}
+
+func (s *fakeStore) CreateBatch(ctx context.Context, batch *models.DripBatch) error {
+	if s.err != nil {
+		return s.err
+	}
+	batch.ID = uint(len(s.batches) + 1)
+	s.batches = append(s.batches, batch)
+	return nil
+}
+
+// fakeLimiter rejects the tokens in limited and records the rest
+type fakeLimiter struct {
+	limited  map[string]*RateLimitCheck
+	err      error
+	recorded []LimitKey
+}
+
+func (l *fakeLimiter) Check(ctx context.Context, key LimitKey) (*RateLimitCheck, error) {
+	if l.err != nil {
+		return nil, l.err
+	}
+	if check, ok := l.limited[key.TokenID]; ok {
+		return check, nil
+	}
+	return &RateLimitCheck{Allowed: true}, nil
+}
+
+func (l *fakeLimiter) Record(ctx context.Context, key LimitKey) error {
+	l.recorded = append(l.recorded, key)
+	return nil
+}
+
+// fakeCaptcha accepts only "pass"
+type fakeCaptcha struct{}
+
+func (fakeCaptcha) Verify(ctx context.Context, token, remoteIP string) error {
+	if token != "pass" {
+		return errors.New("captcha rejected")
+	}
+	return nil
+}
+
+// fakeSender records each Send or SendBatch call as one batch
+type fakeSender struct {
+	batches [][]*models.Drip
+}
+
+func (s *fakeSender) Send(ctx context.Context, drip *models.Drip) {
+	s.batches = append(s.batches, []*models.Drip{drip})
+}
+
+func (s *fakeSender) SendBatch(ctx context.Context, drips []*models.Drip) {
+	s.batches = append(s.batches, drips)
+}
+
+// fakeAmounts returns amount, or the token's fixed amount when it's nil
+type fakeAmounts struct {
+	amount *big.Int
+	err    error
+}
+
+func (a *fakeAmounts) Amount(ctx context.Context, token *models.Token) (*big.Int, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+	if a.amount != nil {
+		return a.amount, nil
+	}
+	return units.Parse(token.DripAmount, token.Decimals)
+}
+
+type fakeEvents struct {
+	published []events.Event
+}
+
+func (e *fakeEvents) Publish(ctx context.Context, event events.Event) {
+	e.published = append(e.published, event)
+}
+
+// fakeMetrics counts "token/outcome/reason" and "limit/dimension"
+type fakeMetrics map[string]int
+
+func (m fakeMetrics) Drip(tokenID, outcome, reason string) {
+	m[tokenID+"/"+outcome+"/"+reason]++
+}
+
+func (m fakeMetrics) RateLimited(dimension string) {
+	m["limit/"+dimension]++
+}
+
+// serviceFixture is a DripService on fakes, with the ETH, TST and USDC
+// tokens
+type serviceFixture struct {
+	service *DripService
+	store   *fakeStore
+	limiter *fakeLimiter
+	sender  *fakeSender
+	amounts *fakeAmounts
+	events  *fakeEvents
+	metrics fakeMetrics
+}
+
+func newServiceFixture() *serviceFixture {
+	f := &serviceFixture{
+		store: &fakeStore{tokens: map[string]*models.Token{
+			"eth":    {ID: "eth", Symbol: "ETH", DripAmount: "0.5", Decimals: 18, IsActive: true},
+			"tst":    {ID: "tst", Symbol: "TST", DripAmount: "100", Decimals: 6, IsActive: true},
+			"old":    {ID: "old", Symbol: "OLD", DripAmount: "1", Decimals: 18, IsActive: false},
+			"usdc-a": {ID: "usdc-a", Symbol: "USDC", DripAmount: "10", Decimals: 6, IsActive: true},
+		}},
+		limiter: &fakeLimiter{limited: make(map[string]*RateLimitCheck)},
+		sender:  &fakeSender{},
+		amounts: &fakeAmounts{},
+		events:  &fakeEvents{},
+		metrics: make(fakeMetrics),
+	}
+	f.service = &DripService{
+		Store:   f.store,
+		Limiter: f.limiter,
+		Captcha: fakeCaptcha{},
+		Sender:  f.sender,
+		Events:  f.events,
+		Metrics: f.metrics,
+		Amounts: f.amounts,
+	}
+	return f
+}
+
+const testRecipient = "0x00000000000000000000000000000000000000aa"
+
+func TestDripServiceRequest(t *testing.T) {
+	paused := time.Now()
+
+	tests := []struct {
+		name  string
+		setup func(f *serviceFixture)
+		req   DripRequest
+		// wantErr is part of the error text; empty for accepted requests
+		wantErr    string
+		wantMetric string
+		wantAmount string
+	}{
+		{
+			name:       "accepted",
+			req:        DripRequest{Address: testRecipient, TokenID: "tst", CaptchaToken: "pass"},
+			wantMetric: "tst/accepted/",
+			wantAmount: "100000000",
+		},
+		{
+			name:       "fixed amount without a calculator",
+			setup:      func(f *serviceFixture) { f.service.Amounts = nil },
+			req:        DripRequest{Address: testRecipient, TokenID: "tst", CaptchaToken: "pass"},
+			wantMetric: "tst/accepted/",
+			wantAmount: "100000000",
+		},
+		{
+			name:       "computed amount",
+			setup:      func(f *serviceFixture) { f.amounts.amount = big.NewInt(42) },
+			req:        DripRequest{Address: testRecipient, TokenID: "tst", CaptchaToken: "pass"},
+			wantMetric: "tst/accepted/",
+			wantAmount: "42",
+		},
+		{
+			name:       "captcha skipped for bots",
+			req:        DripRequest{Address: testRecipient, TokenID: "eth", SkipCaptcha: true},
+			wantMetric: "eth/accepted/",
+			wantAmount: "500000000000000000",
+		},
+		{
+			name:    "invalid address",
+			req:     DripRequest{Address: "0x1234", TokenID: "tst", CaptchaToken: "pass"},
+			wantErr: ErrInvalidAddress.Error(),
+		},
+		{
+			name:    "unknown token",
+			req:     DripRequest{Address: testRecipient, TokenID: "nope", CaptchaToken: "pass"},
+			wantErr: ErrTokenUnavailable.Error(),
+		},
+		{
+			name:    "symbol without AllowSymbol",
+			req:     DripRequest{Address: testRecipient, TokenID: "USDC", CaptchaToken: "pass"},
+			wantErr: ErrTokenUnavailable.Error(),
+		},
+		{
+			name:       "symbol from a bot",
+			req:        DripRequest{Address: testRecipient, TokenID: "usdc", SkipCaptcha: true, AllowSymbol: true},
+			wantMetric: "usdc-a/accepted/",
+			wantAmount: "10000000",
+		},
+		{
+			name: "ambiguous symbol",
+			setup: func(f *serviceFixture) {
+				f.store.tokens["usdc-b"] = &models.Token{ID: "usdc-b", Symbol: "USDC", DripAmount: "10", Decimals: 6, IsActive: true}
+			},
+			req:     DripRequest{Address: testRecipient, TokenID: "USDC", SkipCaptcha: true, AllowSymbol: true},
+			wantErr: "USDC matches several tokens (usdc-a, usdc-b)",
+		},
+		{
+			name:    "inactive token",
+			req:     DripRequest{Address: testRecipient, TokenID: "old", CaptchaToken: "pass"},
+			wantErr: ErrTokenUnavailable.Error(),
+		},
+		{
+			name:       "paused token",
+			setup:      func(f *serviceFixture) { f.store.tokens["tst"].PausedAt = &paused },
+			req:        DripRequest{Address: testRecipient, TokenID: "tst", CaptchaToken: "pass"},
+			wantErr:    "TST is temporarily empty",
+			wantMetric: "tst/rejected/paused",
+		},
+		{
+			name:       "zero amount",
+			setup:      func(f *serviceFixture) { f.amounts.amount = new(big.Int) },
+			req:        DripRequest{Address: testRecipient, TokenID: "tst", CaptchaToken: "pass"},
+			wantErr:    "TST is temporarily empty",
+			wantMetric: "tst/rejected/paused",
+		},
+		{
+			name:       "login required",
+			setup:      func(f *serviceFixture) { f.service.Auth.Required = true },
+			req:        DripRequest{Address: testRecipient, TokenID: "tst", CaptchaToken: "pass"},
+			wantErr:    ErrLoginRequired.Error(),
+			wantMetric: "tst/rejected/login_required",
+		},
+		{
+			name:  "account too young",
+			setup: func(f *serviceFixture) { f.service.Auth.MinAccountAge = 30 * 24 * time.Hour },
+			req: DripRequest{Address: testRecipient, TokenID: "tst", CaptchaToken: "pass",
+				Identity: &auth.Identity{Provider: "github", ProviderUserID: "1", AccountCreatedAt: time.Now()}},
+			wantErr:    "at least 30 days old",
+			wantMetric: "tst/rejected/account_age",
+		},
+		{
+			name:       "captcha failed",
+			req:        DripRequest{Address: testRecipient, TokenID: "tst", CaptchaToken: "fail"},
+			wantErr:    ErrCaptchaFailed.Error(),
+			wantMetric: "tst/rejected/captcha",
+		},
+		{
+			name: "rate limited",
+			setup: func(f *serviceFixture) {
+				f.limiter.limited["tst"] = &RateLimitCheck{Reason: "IP daily limit reached", RetryAfter: 60, Dimension: "ip"}
+			},
+			req:        DripRequest{Address: testRecipient, TokenID: "tst", CaptchaToken: "pass"},
+			wantErr:    "IP daily limit reached",
+			wantMetric: "limit/ip",
+		},
+		{
+			name:    "limiter failure",
+			setup:   func(f *serviceFixture) { f.limiter.err = errors.New("redis down") },
+			req:     DripRequest{Address: testRecipient, TokenID: "tst", CaptchaToken: "pass"},
+			wantErr: "rate limit check failed: redis down",
+		},
+		{
+			name:    "amount failure",
+			setup:   func(f *serviceFixture) { f.amounts.err = errors.New("rpc down") },
+			req:     DripRequest{Address: testRecipient, TokenID: "tst", CaptchaToken: "pass"},
+			wantErr: "rpc down",
+		},
+		{
+			name:    "store failure",
+			setup:   func(f *serviceFixture) { f.store.err = errors.New("db down") },
+			req:     DripRequest{Address: testRecipient, TokenID: "tst", CaptchaToken: "pass"},
+			wantErr: "failed to create drip record: db down",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newServiceFixture()
+			if tt.setup != nil {
+				tt.setup(f)
+			}
+
+			result, err := f.service.Request(context.Background(), tt.req)
+			if tt.wantMetric != "" && f.metrics[tt.wantMetric] != 1 {
+				t.Errorf("metrics = %v, want %s counted once", f.metrics, tt.wantMetric)
+			}
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				// A rejected request leaves nothing behind
+				if len(f.store.drips) != 0 || len(f.sender.batches) != 0 || len(f.limiter.recorded) != 0 || len(f.events.published) != 0 {
+					t.Errorf("rejected request stored %d drips, sent %d, recorded %d limits and published %d events",
+						len(f.store.drips), len(f.sender.batches), len(f.limiter.recorded), len(f.events.published))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			drip := result.Drip
+			if drip.ID == 0 || drip.Status != "pending" || drip.AmountBaseUnits != tt.wantAmount {
+				t.Errorf("drip = %+v, want a pending drip of %s", drip, tt.wantAmount)
+			}
+			if drip.Recipient != "0x00000000000000000000000000000000000000AA" {
+				t.Errorf("recipient = %s, want the checksummed address", drip.Recipient)
+			}
+			if len(f.sender.batches) != 1 || f.sender.batches[0][0] != drip {
+				t.Errorf("sent %v, want the drip sent once", f.sender.batches)
+			}
+			if len(f.limiter.recorded) != 1 || f.limiter.recorded[0].TokenID != result.Token.ID {
+				t.Errorf("limits recorded = %+v, want the drip's", f.limiter.recorded)
+			}
+			if len(f.events.published) != 1 || f.events.published[0].Type != events.DripCreated {
+				t.Errorf("events = %+v, want drip.created", f.events.published)
+			}
+		})
+	}
+}
+
+func TestDripServiceRequestBundle(t *testing.T) {
+	t.Run("partial", func(t *testing.T) {
+		f := newServiceFixture()
+		f.limiter.limited["eth"] = &RateLimitCheck{Reason: "Wallet cooldown", Dimension: "wallet"}
+
+		result, err := f.service.RequestBundle(context.Background(), BundleRequest{
+			DripRequest: DripRequest{Address: testRecipient, CaptchaToken: "pass", Identity: &auth.Identity{Provider: "github", ProviderUserID: "1"}},
+			TokenIDs:    []string{"eth", "tst", "TST", "nope"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Batch == nil || result.Batch.TokenIDs != "eth,tst,nope" {
+			t.Fatalf("batch = %+v, want eth, tst and nope once each", result.Batch)
+		}
+		var limited *RateLimitError
+		if len(result.Items) != 3 || !errors.As(result.Items[0].Err, &limited) || result.Items[1].Drip == nil ||
+			!errors.Is(result.Items[2].Err, ErrTokenUnavailable) {
+			t.Fatalf("items = %+v, want eth limited, tst accepted and nope unknown", result.Items)
+		}
+		if drip := result.Items[1].Drip; drip.BatchID == nil || *drip.BatchID != result.Batch.ID || drip.IdentityID != "github:1" {
+			t.Errorf("tst drip = %+v, want it in the batch for github:1", drip)
+		}
+		if len(f.sender.batches) != 1 || len(f.sender.batches[0]) != 1 {
+			t.Errorf("sent %v, want one batch of the tst drip", f.sender.batches)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		f := newServiceFixture()
+		f.service.SetBundles(map[string][]string{"starter": {"eth", "tst"}})
+
+		tests := []struct {
+			name    string
+			req     BundleRequest
+			wantErr error
+		}{
+			{"no tokens", BundleRequest{DripRequest: DripRequest{Address: testRecipient}}, ErrInvalidBundle},
+			{"bundle and tokens", BundleRequest{DripRequest: DripRequest{Address: testRecipient}, Bundle: "starter", TokenIDs: []string{"eth"}}, ErrInvalidBundle},
+			{"too many tokens", BundleRequest{DripRequest: DripRequest{Address: testRecipient}, TokenIDs: make([]string, MaxBundleTokens+1)}, ErrInvalidBundle},
+			{"unknown bundle", BundleRequest{DripRequest: DripRequest{Address: testRecipient}, Bundle: "deluxe"}, ErrUnknownBundle},
+			{"captcha failed", BundleRequest{DripRequest: DripRequest{Address: testRecipient, CaptchaToken: "fail"}, Bundle: "Starter"}, ErrCaptchaFailed},
+		}
+		for _, tt := range tests {
+			if _, err := f.service.RequestBundle(context.Background(), tt.req); !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+			}
+		}
+		if len(f.store.batches) != 0 || len(f.sender.batches) != 0 {
+			t.Errorf("rejected bundles stored %d batches and sent %d", len(f.store.batches), len(f.sender.batches))
+		}
+		if f.metrics["eth/rejected/captcha"] != 1 || f.metrics["tst/rejected/captcha"] != 1 {
+			t.Errorf("metrics = %v, want a captcha rejection per bundle token", f.metrics)
+		}
+	})
+}
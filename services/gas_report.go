@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"faucet-backend/models"
+	"faucet-backend/units"
+)
+
+// GasSpendQuery selects the days of a gas spend report
+type GasSpendQuery struct {
+	// From and To bound the UTC days reported, the day containing From up
+	// to, but excluding, the one starting at or after To
+	From, To time.Time
+	// TokenIDs is empty for every token with mined drips in the range
+	TokenIDs []string
+}
+
+// GasSpendDay is one token's gas spend on one UTC day. Costs are in wei,
+// with GasCostEth the same amount in ETH.
+type GasSpendDay struct {
+	Day        time.Time `json:"day"`
+	Receipts   int64     `json:"receipts"`
+	GasUsed    int64     `json:"gasUsed"`
+	GasCost    string    `json:"gasCost"`
+	GasCostEth string    `json:"gasCostEth"`
+}
+
+// GasSpendToken is one token's gas spend over a report's range. Averages
+// are per mined drip.
+type GasSpendToken struct {
+	TokenID       string        `json:"tokenId"`
+	Symbol        string        `json:"symbol"`
+	Receipts      int64         `json:"receipts"`
+	GasUsed       int64         `json:"gasUsed"`
+	GasCost       string        `json:"gasCost"`
+	GasCostEth    string        `json:"gasCostEth"`
+	AvgGasUsed    int64         `json:"avgGasUsed"`
+	AvgGasCost    string        `json:"avgGasCost"`
+	AvgGasCostEth string        `json:"avgGasCostEth"`
+	Daily         []GasSpendDay `json:"daily"`
+}
+
+// GasSpend totals the fees paid for drips per token and per day from the
+// daily rollups
+func (r *StatsRollup) GasSpend(ctx context.Context, q GasSpendQuery) ([]GasSpendToken, error) {
+	if !q.From.Before(q.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidStatsQuery)
+	}
+
+	db := r.DB.WithContext(ctx)
+
+	query := db.Where("granularity = ? AND bucket_start >= ? AND bucket_start < ? AND receipts > 0",
+		"day", TruncateBucket(q.From, "day"), q.To)
+	if len(q.TokenIDs) > 0 {
+		query = query.Where("token_id IN ?", q.TokenIDs)
+	}
+	var rollups []models.DripRollup
+	if err := query.Order("token_id, bucket_start").Find(&rollups).Error; err != nil {
+		return nil, fmt.Errorf("failed to load rollups: %w", err)
+	}
+
+	// Removed tokens keep their history
+	var tokens []models.Token
+	if err := db.Unscoped().Find(&tokens).Error; err != nil {
+		return nil, fmt.Errorf("failed to load tokens: %w", err)
+	}
+	symbols := make(map[string]string, len(tokens))
+	for _, token := range tokens {
+		symbols[token.ID] = token.Symbol
+	}
+
+	byToken := make(map[string]*GasSpendToken)
+	totals := make(map[string]*big.Int)
+	for _, rollup := range rollups {
+		report := byToken[rollup.TokenID]
+		if report == nil {
+			report = &GasSpendToken{TokenID: rollup.TokenID, Symbol: symbols[rollup.TokenID], Daily: []GasSpendDay{}}
+			byToken[rollup.TokenID] = report
+			totals[rollup.TokenID] = new(big.Int)
+		}
+
+		cost, ok := new(big.Int).SetString(rollup.GasCost, 10)
+		if !ok {
+			cost = new(big.Int)
+		}
+		report.Receipts += rollup.Receipts
+		report.GasUsed += rollup.GasUsed
+		totals[rollup.TokenID].Add(totals[rollup.TokenID], cost)
+		report.Daily = append(report.Daily, GasSpendDay{
+			Day:        rollup.BucketStart.UTC(),
+			Receipts:   rollup.Receipts,
+			GasUsed:    rollup.GasUsed,
+			GasCost:    cost.String(),
+			GasCostEth: units.Format(cost, 18),
+		})
+	}
+
+	reports := make([]GasSpendToken, 0, len(byToken))
+	for tokenID, report := range byToken {
+		total := totals[tokenID]
+		average := new(big.Int).Div(total, big.NewInt(report.Receipts))
+
+		report.GasCost = total.String()
+		report.GasCostEth = units.Format(total, 18)
+		report.AvgGasUsed = report.GasUsed / report.Receipts
+		report.AvgGasCost = average.String()
+		report.AvgGasCostEth = units.Format(average, 18)
+		reports = append(reports, *report)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].TokenID < reports[j].TokenID })
+	return reports, nil
+}
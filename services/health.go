@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"faucet-backend/units"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	HealthOK          = "ok"
+	HealthDegraded    = "degraded"
+	HealthUnavailable = "unavailable"
+
+	healthCheckTimeout = 3 * time.Second
+)
+
+type ComponentHealth struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latencyMs"`
+	Message   string `json:"message,omitempty"`
+}
+
+type Readiness struct {
+	Status     string                      `json:"status"`
+	Components map[string]*ComponentHealth `json:"components"`
+}
+
+// HealthChecker probes the faucet's dependencies
+type HealthChecker struct {
+	DB     *gorm.DB
+	Redis  *redis.Client
+	Wallet *Wallet
+	// ChainID is the expected chain; empty skips the comparison
+	ChainID string
+	// MinBalance is the native balance (ETH) below which readiness degrades
+	MinBalance string
+}
+
+// CheckReadiness probes every dependency concurrently. Postgres, Redis and
+// the RPC node (reachable, on the configured chain and synced) are required;
+// a faucet balance below MinBalance only degrades readiness.
+func (h *HealthChecker) CheckReadiness(ctx context.Context) *Readiness {
+	checks := map[string]func(context.Context) (string, string){
+		"postgres": h.checkPostgres,
+		"redis":    h.checkRedis,
+		"rpc":      h.checkRPC,
+		"chainId":  h.checkChainID,
+		"sync":     h.checkSync,
+		"balance":  h.checkBalance,
+	}
+
+	readiness := &Readiness{
+		Status:     HealthOK,
+		Components: make(map[string]*ComponentHealth, len(checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(context.Context) (string, string)) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			status, message := check(checkCtx)
+
+			mu.Lock()
+			readiness.Components[name] = &ComponentHealth{
+				Status:    status,
+				LatencyMs: time.Since(start).Milliseconds(),
+				Message:   message,
+			}
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	for _, component := range readiness.Components {
+		switch {
+		case component.Status == HealthUnavailable:
+			readiness.Status = HealthUnavailable
+		case component.Status == HealthDegraded && readiness.Status == HealthOK:
+			readiness.Status = HealthDegraded
+		}
+	}
+
+	return readiness
+}
+
+func (h *HealthChecker) checkPostgres(ctx context.Context) (string, string) {
+	sqlDB, err := h.DB.DB()
+	if err != nil {
+		return HealthUnavailable, err.Error()
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return HealthUnavailable, err.Error()
+	}
+	return HealthOK, ""
+}
+
+func (h *HealthChecker) checkRedis(ctx context.Context) (string, string) {
+	if err := h.Redis.Ping(ctx).Err(); err != nil {
+		return HealthUnavailable, err.Error()
+	}
+	return HealthOK, ""
+}
+
+func (h *HealthChecker) checkRPC(ctx context.Context) (string, string) {
+	ctx, done := traceRPC(ctx, "eth_blockNumber")
+	block, err := h.Wallet.client.BlockNumber(ctx)
+	done(err)
+	if err != nil {
+		return HealthUnavailable, err.Error()
+	}
+	return HealthOK, fmt.Sprintf("block %d", block)
+}
+
+func (h *HealthChecker) checkChainID(ctx context.Context) (string, string) {
+	ctx, done := traceRPC(ctx, "eth_chainId")
+	chainID, err := h.Wallet.client.ChainID(ctx)
+	done(err)
+	if err != nil {
+		return HealthUnavailable, err.Error()
+	}
+
+	expected := h.ChainID
+	if expected == "" {
+		return HealthOK, fmt.Sprintf("chain %s (CHAIN_ID not configured)", chainID)
+	}
+	if chainID.String() != expected {
+		return HealthUnavailable, fmt.Sprintf("node is on chain %s, expected %s", chainID, expected)
+	}
+	return HealthOK, fmt.Sprintf("chain %s", chainID)
+}
+
+func (h *HealthChecker) checkSync(ctx context.Context) (string, string) {
+	ctx, done := traceRPC(ctx, "eth_syncing")
+	progress, err := h.Wallet.client.SyncProgress(ctx)
+	done(err)
+	if err != nil {
+		return HealthUnavailable, err.Error()
+	}
+	if progress != nil {
+		return HealthUnavailable, fmt.Sprintf("node syncing: block %d of %d", progress.CurrentBlock, progress.HighestBlock)
+	}
+	return HealthOK, ""
+}
+
+func (h *HealthChecker) checkBalance(ctx context.Context) (string, string) {
+	balance, err := h.Wallet.NativeBalance(ctx)
+	if err != nil {
+		return HealthUnavailable, err.Error()
+	}
+
+	threshold := h.MinBalance
+	minWei, err := units.Parse(threshold, 18)
+	if err != nil {
+		return HealthDegraded, fmt.Sprintf("invalid MIN_FAUCET_BALANCE %q", threshold)
+	}
+
+	balanceEth := units.Format(balance, 18)
+	if balance.Cmp(minWei) < 0 {
+		return HealthDegraded, fmt.Sprintf("balance %s ETH below threshold %s ETH", balanceEth, threshold)
+	}
+	return HealthOK, fmt.Sprintf("balance %s ETH", balanceEth)
+}
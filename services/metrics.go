@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"faucet-backend/metrics"
+	"faucet-backend/models"
+	"faucet-backend/units"
+
+	"gorm.io/gorm"
+)
+
+// MetricsCollector periodically refreshes gauges that need a DB or RPC
+// round-trip, so scrapes stay cheap
+type MetricsCollector struct {
+	DB       *gorm.DB
+	Wallet   *Wallet
+	Interval time.Duration
+}
+
+// Start runs the collector in the background until ctx is cancelled
+func (m *MetricsCollector) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(m.Interval)
+		defer ticker.Stop()
+
+		for {
+			m.collect(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (m *MetricsCollector) collect(ctx context.Context) {
+	var pending int64
+	if err := m.DB.WithContext(ctx).Model(&models.Drip{}).Where("status = ?", "pending").Count(&pending).Error; err == nil {
+		metrics.PendingTransactions.Set(float64(pending))
+	}
+
+	var tokens []models.Token
+	if err := m.DB.WithContext(ctx).Where("is_active = true").Find(&tokens).Error; err != nil {
+		slog.Warn("metrics collector failed to load tokens", "error", err)
+		return
+	}
+
+	for _, token := range tokens {
+		balance, err := m.Wallet.TokenBalance(ctx, &token)
+		if err != nil {
+			continue
+		}
+
+		metrics.WalletBalance.WithLabelValues(token.ID).Set(units.Float64(balance, token.Decimals))
+	}
+}
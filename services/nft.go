@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"faucet-backend/logging"
+	"faucet-backend/models"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// NFT function selectors
+var (
+	erc721SafeMintSelector   = common.FromHex("0x40d097c3") // safeMint(address)
+	erc721TransferSelector   = common.FromHex("0x42842e0e") // safeTransferFrom(address,address,uint256)
+	erc721OwnerOfSelector    = common.FromHex("0x6352211e") // ownerOf(uint256)
+	erc1155MintSelector      = common.FromHex("0x731133e9") // mint(address,uint256,uint256,bytes)
+	erc1155TransferSelector  = common.FromHex("0xf242432a") // safeTransferFrom(address,address,uint256,uint256,bytes)
+	erc1155BalanceOfSelector = common.FromHex("0x00fdd58e") // balanceOf(address,uint256)
+)
+
+// transferTopic is Transfer(address,address,uint256). ERC-721 indexes the
+// token ID as a third topic where ERC20 logs the amount as data.
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// nftGasLimit covers NFT mints and transfers, which cost more than ERC20
+// ones
+const nftGasLimit = 250000
+
+// ErrInventoryEmpty is returned when an ERC-721 collection has no ID left to
+// send
+var ErrInventoryEmpty = errors.New("NFT inventory is empty")
+
+// calldata concatenates a selector and its 32-byte arguments
+func calldata(selector []byte, args ...[]byte) []byte {
+	data := append([]byte{}, selector...)
+	for _, arg := range args {
+		data = append(data, common.LeftPadBytes(arg, 32)...)
+	}
+	return data
+}
+
+// emptyBytesArg is a trailing empty bytes argument after head words of
+// arguments: its offset, then its zero length
+func emptyBytesArg(head int) [][]byte {
+	return [][]byte{big.NewInt(int64(32 * head)).Bytes(), nil}
+}
+
+// nftID returns the ERC-1155 token ID the token drips
+func nftID(token *models.Token) *big.Int {
+	id, ok := new(big.Int).SetString(token.NFTID, 10)
+	if !ok {
+		return new(big.Int)
+	}
+	return id
+}
+
+// TransferNFT sends amount of the NFT id from the wallet to to: the token
+// itself for ERC-721, amount copies for ERC-1155
+func (w *Wallet) TransferNFT(ctx context.Context, token *models.Token, to common.Address, id, amount *big.Int) (string, error) {
+	var data []byte
+	if token.Standard == models.StandardERC1155 {
+		args := [][]byte{w.address.Bytes(), to.Bytes(), id.Bytes(), amount.Bytes()}
+		data = calldata(erc1155TransferSelector, append(args, emptyBytesArg(5)...)...)
+	} else {
+		data = calldata(erc721TransferSelector, w.address.Bytes(), to.Bytes(), id.Bytes())
+	}
+	return w.sendCall(ctx, common.HexToAddress(token.Address), data, nftGasLimit)
+}
+
+// NFTOwner returns the owner of an ERC-721 token ID. A call the contract
+// reverts, as it does for IDs that don't exist, returns the zero address;
+// other errors are returned so the caller can retry.
+func (w *Wallet) NFTOwner(ctx context.Context, token *models.Token, id *big.Int) (common.Address, error) {
+	addr := common.HexToAddress(token.Address)
+
+	ctx, done := traceRPC(ctx, "eth_call")
+	ret, err := w.client.CallContract(ctx, ethereum.CallMsg{
+		To:   &addr,
+		Data: calldata(erc721OwnerOfSelector, id.Bytes()),
+	}, nil)
+	if isRevert(err) {
+		done(nil)
+		return common.Address{}, nil
+	}
+	done(err)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return common.BytesToAddress(ret), nil
+}
+
+// ERC1155Balance returns the wallet's balance of one ERC-1155 token ID
+func (w *Wallet) ERC1155Balance(ctx context.Context, tokenAddress common.Address, id *big.Int) (*big.Int, error) {
+	ctx, done := traceRPC(ctx, "eth_call")
+	ret, err := w.client.CallContract(ctx, ethereum.CallMsg{
+		To:   &tokenAddress,
+		Data: calldata(erc1155BalanceOfSelector, w.address.Bytes(), id.Bytes()),
+	}, nil)
+	done(err)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(ret), nil
+}
+
+// sendNFT transfers the drip's NFT from the wallet: the next ID of an
+// ERC-721 collection's inventory or the ERC-1155 collection's ID. It returns
+// the ID sent.
+func (s *ChainSender) sendNFT(ctx context.Context, token *models.Token, dripID uint, to common.Address, amount *big.Int) (string, string, error) {
+	if token.Standard == models.StandardERC1155 {
+		txHash, err := s.Wallet.TransferNFT(ctx, token, to, nftID(token), amount)
+		return txHash, token.NFTID, err
+	}
+
+	id, err := s.reserveNFT(ctx, token, dripID)
+	if err != nil {
+		return "", "", err
+	}
+	txHash, err := s.Wallet.TransferNFT(ctx, token, to, id, big.NewInt(1))
+	if err != nil {
+		s.releaseNFTs(ctx, dripID)
+		return "", "", err
+	}
+	return txHash, id.String(), nil
+}
+
+// reserveNFT reserves the lowest available ID of the token's inventory for
+// the drip. IDs the wallet no longer owns are marked missing and skipped.
+func (s *ChainSender) reserveNFT(ctx context.Context, token *models.Token, dripID uint) (*big.Int, error) {
+	db := s.DB.WithContext(ctx)
+
+	for {
+		var item models.InventoryItem
+		found := db.Where("token_id = ? AND status = ?", token.ID, models.InventoryAvailable).
+			Order("nft_id").Limit(1).Find(&item)
+		if found.Error != nil {
+			return nil, fmt.Errorf("failed to load inventory: %w", found.Error)
+		}
+		if found.RowsAffected == 0 {
+			return nil, ErrInventoryEmpty
+		}
+
+		// Another drip may claim the same ID first
+		claimed := db.Model(&models.InventoryItem{}).
+			Where("id = ? AND status = ?", item.ID, models.InventoryAvailable).
+			Updates(map[string]interface{}{"status": models.InventoryReserved, "drip_id": dripID})
+		if claimed.Error != nil {
+			return nil, fmt.Errorf("failed to reserve inventory: %w", claimed.Error)
+		}
+		if claimed.RowsAffected == 0 {
+			continue
+		}
+
+		id, _ := new(big.Int).SetString(item.NFTID, 10)
+		owner, err := s.Wallet.NFTOwner(ctx, token, id)
+		if err != nil {
+			s.releaseNFTs(ctx, dripID)
+			return nil, fmt.Errorf("failed to read owner of %s: %w", item.NFTID, err)
+		}
+		if owner == s.Wallet.address {
+			return id, nil
+		}
+
+		logging.FromContext(ctx).Warn("inventory ID no longer owned by the faucet", "nft_id", item.NFTID, "owner", owner.Hex())
+		if err := db.Model(&item).Update("status", models.InventoryMissing).Error; err != nil {
+			return nil, fmt.Errorf("failed to update inventory: %w", err)
+		}
+	}
+}
+
+// releaseNFTs returns the IDs reserved for a drip that failed to the
+// inventory
+func (s *ChainSender) releaseNFTs(ctx context.Context, dripID uint) {
+	if err := s.DB.WithContext(ctx).Model(&models.InventoryItem{}).
+		Where("drip_id = ? AND status = ?", dripID, models.InventoryReserved).
+		Updates(map[string]interface{}{"status": models.InventoryAvailable, "drip_id": nil}).Error; err != nil {
+		logging.FromContext(ctx).Error("failed to release reserved inventory", "error", err)
+	}
+}
+
+// settleNFTs records a mined NFT drip: inventory reserved for it is marked
+// sent, or released if it reverted, and the IDs an ERC-721 mint created are
+// read from its Transfer logs
+func (s *ChainSender) settleNFTs(ctx context.Context, tokenID string, dripID uint, receipt *types.Receipt) {
+	db := s.DB.WithContext(ctx)
+
+	var token models.Token
+	if err := db.Unscoped().Where("id = ?", tokenID).Limit(1).Find(&token).Error; err != nil || !token.IsNFT() {
+		return
+	}
+
+	if receipt.Status == types.ReceiptStatusFailed {
+		s.releaseNFTs(ctx, dripID)
+		return
+	}
+	db.Model(&models.InventoryItem{}).
+		Where("drip_id = ? AND status = ?", dripID, models.InventoryReserved).
+		Update("status", models.InventorySent)
+
+	if token.Standard != models.StandardERC721 || token.DistributionMode() != models.DistributeMint {
+		return
+	}
+	var minted []string
+	contract := common.HexToAddress(token.Address)
+	for _, entry := range receipt.Logs {
+		if entry.Address == contract && len(entry.Topics) == 4 && entry.Topics[0] == transferTopic && entry.Topics[1] == (common.Hash{}) {
+			minted = append(minted, entry.Topics[3].Big().String())
+		}
+	}
+	if len(minted) > 0 {
+		db.Model(&models.Drip{}).Where("id = ?", dripID).Update("nft_ids", strings.Join(minted, ","))
+	}
+}
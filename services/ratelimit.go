@@ -2,18 +2,33 @@ package services
 
 import (
 	"context"
-	"fmt"
-	"faucet-backend/database"
 	"faucet-backend/models"
+	"fmt"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
+// Limits are the daily request quotas shared across all tokens
+type Limits struct {
+	IPDaily          int
+	FingerprintDaily int
+	// IdentityDaily is the per-account limit, so one account can't cycle
+	// through wallets
+	IdentityDaily int
+}
+
+var DefaultLimits = Limits{IPDaily: 3, FingerprintDaily: 2, IdentityDaily: 5}
+
 type RateLimitCheck struct {
 	Allowed    bool
 	RetryAfter int64
 	Reason     string
+	Dimension  string // Which limit rejected the request: wallet, ip, fingerprint or identity
 }
 
 type IPRateLimit struct {
@@ -22,22 +37,55 @@ type IPRateLimit struct {
 	CanRequest bool
 }
 
-func CheckRateLimit(wallet, tokenID, ip, fingerprint string) (*RateLimitCheck, error) {
-	ctx := context.Background()
+// RedisLimiter applies per-token wallet and identity cooldowns and daily
+// IP, fingerprint and identity quotas, counted in Redis
+type RedisLimiter struct {
+	DB    *gorm.DB
+	Redis *redis.Client
+
+	limits atomic.Pointer[Limits]
+}
+
+// SetLimits replaces the daily quotas; zero fields keep their default. It is
+// safe to call while requests are being checked.
+func (l *RedisLimiter) SetLimits(limits Limits) {
+	if limits.IPDaily == 0 {
+		limits.IPDaily = DefaultLimits.IPDaily
+	}
+	if limits.FingerprintDaily == 0 {
+		limits.FingerprintDaily = DefaultLimits.FingerprintDaily
+	}
+	if limits.IdentityDaily == 0 {
+		limits.IdentityDaily = DefaultLimits.IdentityDaily
+	}
+	l.limits.Store(&limits)
+}
+
+// Limits returns the daily quotas in effect
+func (l *RedisLimiter) Limits() Limits {
+	if limits := l.limits.Load(); limits != nil {
+		return *limits
+	}
+	return DefaultLimits
+}
+
+func (l *RedisLimiter) Check(ctx context.Context, key LimitKey) (*RateLimitCheck, error) {
+	tokenID, ip, fingerprint, identity := key.TokenID, key.IP, key.Fingerprint, key.Identity
+	limits := l.Limits()
 
 	// Get token cooldown
 	var cooldownHours int
-	if err := database.DB.Model(&models.Token{}).
+	if err := l.DB.WithContext(ctx).Model(&models.Token{}).
 		Where("id = ?", tokenID).
 		Pluck("cooldown_hours", &cooldownHours).Error; err != nil {
 		cooldownHours = 24
 	}
 
-	wallet = strings.ToLower(wallet)
+	wallet := strings.ToLower(key.Wallet)
 
 	// Check wallet cooldown for specific token
 	walletKey := fmt.Sprintf("faucet:wallet:%s:%s", wallet, tokenID)
-	lastDrip, err := database.Redis.Get(ctx, walletKey).Result()
+	lastDrip, err := l.Redis.Get(ctx, walletKey).Result()
 
 	if err == nil && lastDrip != "" {
 		timestamp, _ := strconv.ParseInt(lastDrip, 10, 64)
@@ -50,39 +98,76 @@ func CheckRateLimit(wallet, tokenID, ip, fingerprint string) (*RateLimitCheck, e
 				Allowed:    false,
 				RetryAfter: retryAfter,
 				Reason:     fmt.Sprintf("Wallet cooldown for this token: %d hours remaining", retryAfter/3600),
+				Dimension:  "wallet",
 			}, nil
 		}
 	}
 
-	// Check IP rate limit (3 total requests per day across all tokens)
-	ipKey := fmt.Sprintf("faucet:ip:%s", ip)
-	ipCount, err := database.Redis.Get(ctx, ipKey).Result()
+	// Check IP rate limit (total requests per day across all tokens).
+	// Bot requests have no IP and are limited by identity instead.
+	if ip != "" {
+		ipKey := fmt.Sprintf("faucet:ip:%s", ip)
+		ipCount, err := l.Redis.Get(ctx, ipKey).Result()
 
-	if err == nil && ipCount != "" {
-		count, _ := strconv.Atoi(ipCount)
-		if count >= 3 {
-			ttl, _ := database.Redis.TTL(ctx, ipKey).Result()
-			return &RateLimitCheck{
-				Allowed:    false,
-				RetryAfter: int64(ttl.Seconds()),
-				Reason:     "IP daily limit reached (3 requests per day)",
-			}, nil
+		if err == nil && ipCount != "" {
+			count, _ := strconv.Atoi(ipCount)
+			if count >= limits.IPDaily {
+				ttl, _ := l.Redis.TTL(ctx, ipKey).Result()
+				return &RateLimitCheck{
+					Allowed:    false,
+					RetryAfter: int64(ttl.Seconds()),
+					Reason:     fmt.Sprintf("IP daily limit reached (%d requests per day)", limits.IPDaily),
+					Dimension:  "ip",
+				}, nil
+			}
 		}
 	}
 
-	// Check fingerprint (2 per day)
+	// Check fingerprint daily limit
 	if fingerprint != "" {
 		fpKey := fmt.Sprintf("faucet:fp:%s", fingerprint)
-		fpCount, err := database.Redis.Get(ctx, fpKey).Result()
+		fpCount, err := l.Redis.Get(ctx, fpKey).Result()
 
 		if err == nil && fpCount != "" {
 			count, _ := strconv.Atoi(fpCount)
-			if count >= 2 {
-				ttl, _ := database.Redis.TTL(ctx, fpKey).Result()
+			if count >= limits.FingerprintDaily {
+				ttl, _ := l.Redis.TTL(ctx, fpKey).Result()
 				return &RateLimitCheck{
 					Allowed:    false,
 					RetryAfter: int64(ttl.Seconds()),
-					Reason:     "Device limit exceeded (2 per day)",
+					Reason:     fmt.Sprintf("Device limit exceeded (%d per day)", limits.FingerprintDaily),
+					Dimension:  "fingerprint",
+				}, nil
+			}
+		}
+	}
+
+	// Check identity cooldown for this token and daily limit, so one account
+	// can't cycle through wallets
+	if identity != "" {
+		idTokenKey := fmt.Sprintf("faucet:identity:%s:%s", identity, tokenID)
+		ttl, err := l.Redis.TTL(ctx, idTokenKey).Result()
+		if err == nil && ttl > 0 {
+			return &RateLimitCheck{
+				Allowed:    false,
+				RetryAfter: int64(ttl.Seconds()),
+				Reason:     fmt.Sprintf("Account cooldown for this token: %d hours remaining", int64(ttl.Hours())),
+				Dimension:  "identity",
+			}, nil
+		}
+
+		idKey := fmt.Sprintf("faucet:identity:%s", identity)
+		idCount, err := l.Redis.Get(ctx, idKey).Result()
+
+		if err == nil && idCount != "" {
+			count, _ := strconv.Atoi(idCount)
+			if count >= limits.IdentityDaily {
+				ttl, _ := l.Redis.TTL(ctx, idKey).Result()
+				return &RateLimitCheck{
+					Allowed:    false,
+					RetryAfter: int64(ttl.Seconds()),
+					Reason:     fmt.Sprintf("Account daily limit reached (%d requests per day)", limits.IdentityDaily),
+					Dimension:  "identity",
 				}, nil
 			}
 		}
@@ -91,55 +176,69 @@ func CheckRateLimit(wallet, tokenID, ip, fingerprint string) (*RateLimitCheck, e
 	return &RateLimitCheck{Allowed: true}, nil
 }
 
-func RecordDrip(wallet, tokenID, ip, fingerprint string) error {
-	ctx := context.Background()
+func (l *RedisLimiter) Record(ctx context.Context, key LimitKey) error {
+	tokenID, ip, fingerprint, identity := key.TokenID, key.IP, key.Fingerprint, key.Identity
 
 	// Get token cooldown
 	var cooldownHours int
-	if err := database.DB.Model(&models.Token{}).
+	if err := l.DB.WithContext(ctx).Model(&models.Token{}).
 		Where("id = ?", tokenID).
 		Pluck("cooldown_hours", &cooldownHours).Error; err != nil {
 		cooldownHours = 24
 	}
 
-	wallet = strings.ToLower(wallet)
+	wallet := strings.ToLower(key.Wallet)
 
 	// Set wallet cooldown for specific token
 	walletKey := fmt.Sprintf("faucet:wallet:%s:%s", wallet, tokenID)
-	database.Redis.Set(ctx, walletKey, time.Now().Unix(), time.Duration(cooldownHours)*time.Hour)
+	l.Redis.Set(ctx, walletKey, time.Now().Unix(), time.Duration(cooldownHours)*time.Hour)
 
 	// Increment IP counter (across all tokens)
-	ipKey := fmt.Sprintf("faucet:ip:%s", ip)
-	count, _ := database.Redis.Incr(ctx, ipKey).Result()
-	if count == 1 {
-		database.Redis.Expire(ctx, ipKey, 24*time.Hour)
+	if ip != "" {
+		ipKey := fmt.Sprintf("faucet:ip:%s", ip)
+		count, _ := l.Redis.Incr(ctx, ipKey).Result()
+		if count == 1 {
+			l.Redis.Expire(ctx, ipKey, 24*time.Hour)
+		}
 	}
 
 	// Increment fingerprint counter
 	if fingerprint != "" {
 		fpKey := fmt.Sprintf("faucet:fp:%s", fingerprint)
-		count, _ := database.Redis.Incr(ctx, fpKey).Result()
+		count, _ := l.Redis.Incr(ctx, fpKey).Result()
+		if count == 1 {
+			l.Redis.Expire(ctx, fpKey, 24*time.Hour)
+		}
+	}
+
+	// Set identity cooldown and increment identity counter
+	if identity != "" {
+		idTokenKey := fmt.Sprintf("faucet:identity:%s:%s", identity, tokenID)
+		l.Redis.Set(ctx, idTokenKey, time.Now().Unix(), time.Duration(cooldownHours)*time.Hour)
+
+		idKey := fmt.Sprintf("faucet:identity:%s", identity)
+		count, _ := l.Redis.Incr(ctx, idKey).Result()
 		if count == 1 {
-			database.Redis.Expire(ctx, fpKey, 24*time.Hour)
+			l.Redis.Expire(ctx, idKey, 24*time.Hour)
 		}
 	}
 
 	return nil
 }
 
-func GetIPRateLimit(ip string) (*IPRateLimit, error) {
-	ctx := context.Background()
+func (l *RedisLimiter) IPRateLimit(ctx context.Context, ip string) (*IPRateLimit, error) {
 	ipKey := fmt.Sprintf("faucet:ip:%s", ip)
 
-	ipCount, err := database.Redis.Get(ctx, ipKey).Result()
+	ipCount, err := l.Redis.Get(ctx, ipKey).Result()
 	used := 0
 	if err == nil && ipCount != "" {
 		used, _ = strconv.Atoi(ipCount)
 	}
 
+	limit := l.Limits().IPDaily
 	return &IPRateLimit{
 		Used:       used,
-		Limit:      3,
-		CanRequest: used < 3,
+		Limit:      limit,
+		CanRequest: used < limit,
 	}, nil
 }
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"faucet-backend/metrics"
+	"faucet-backend/telemetry"
+
+	"github.com/ethereum/go-ethereum"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceRPC wraps an ethclient call in a client span and records its latency
+// and errors. Call the returned function with the call's error when it returns.
+func traceRPC(ctx context.Context, method string) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := telemetry.Tracer().Start(ctx, "rpc "+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "jsonrpc"),
+			attribute.String("rpc.method", method),
+		),
+	)
+
+	return ctx, func(err error) {
+		// A missing receipt is the normal answer while a tx is pending
+		if errors.Is(err, ethereum.NotFound) {
+			err = nil
+		}
+		metrics.ObserveRPC(method, start, err)
+		telemetry.End(span, err)
+	}
+}
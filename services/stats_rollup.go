@@ -0,0 +1,518 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"faucet-backend/models"
+	"faucet-backend/units"
+
+	"gorm.io/gorm"
+)
+
+// Rollup granularities, finest first
+var Granularities = []string{"hour", "day", "week"}
+
+// MaxTimeseriesBuckets bounds how many buckets one timeseries query returns
+const MaxTimeseriesBuckets = 1000
+
+var ErrInvalidStatsQuery = errors.New("invalid stats query")
+
+// TruncateBucket returns the start of the UTC hour, day or ISO week (from
+// Monday) containing t
+func TruncateBucket(t time.Time, granularity string) time.Time {
+	t = t.UTC()
+	switch granularity {
+	case "hour":
+		return t.Truncate(time.Hour)
+	case "day":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	default:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+}
+
+// nextBucket returns the start of the bucket after start
+func nextBucket(start time.Time, granularity string) time.Time {
+	switch granularity {
+	case "hour":
+		return start.Add(time.Hour)
+	case "day":
+		return start.AddDate(0, 0, 1)
+	default:
+		return start.AddDate(0, 0, 7)
+	}
+}
+
+// StatsRollup maintains the drip_rollups table that timeseries stats are
+// read from, so dashboards don't scan drips
+type StatsRollup struct {
+	DB *gorm.DB
+	// Interval is how often rollups are refreshed. Defaults to a minute.
+	Interval time.Duration
+	// Lookback is how far before the watermark changed drips are looked
+	// for, so writes that commit after a run has read past them are still
+	// counted. Defaults to five minutes.
+	Lookback time.Duration
+
+	mu sync.Mutex
+	// watermark is when the previous run started
+	watermark time.Time
+}
+
+// Start refreshes rollups every Interval until ctx is cancelled
+func (r *StatsRollup) Start(ctx context.Context) {
+	interval := r.Interval
+	if interval == 0 {
+		interval = time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := r.Refresh(ctx); err != nil {
+				slog.Error("failed to refresh stats rollups", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+type rollupKey struct {
+	granularity string
+	bucket      time.Time
+	tokenID     string
+}
+
+type rollupTotals struct {
+	drips, completed, failed int64
+	users                    map[string]struct{}
+	amount                   *big.Int
+	confirmations            []int64
+	receipts, gasUsed        int64
+	gasCost                  *big.Int
+}
+
+// dayRange is the UTC days from from up to, but excluding, to
+type dayRange struct {
+	from, to time.Time
+}
+
+// Refresh rebuilds the hour and day rollups of every day with drips created
+// or updated since the watermark, then rebuilds those days' weeks from the
+// day rollups. The first run after a restart continues from the newest
+// rollup; an empty table is backfilled from the first drip.
+func (r *StatsRollup) Refresh(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	db := r.DB.WithContext(ctx)
+	now := time.Now().UTC()
+
+	watermark := r.watermark
+	if watermark.IsZero() {
+		var newest models.DripRollup
+		if err := db.Order("refreshed_at DESC").Limit(1).Find(&newest).Error; err != nil {
+			return fmt.Errorf("failed to load newest rollup: %w", err)
+		}
+		watermark = newest.RefreshedAt
+	}
+
+	ranges, err := r.changedDays(ctx, watermark, now)
+	if err != nil {
+		return err
+	}
+
+	var rollups []models.DripRollup
+	weeks := make(map[time.Time]struct{})
+	for _, days := range ranges {
+		totals, err := r.aggregate(ctx, days.from, days.to)
+		if err != nil {
+			return err
+		}
+		for key, t := range totals {
+			rollups = append(rollups, t.rollup(key, now))
+		}
+		for day := days.from; day.Before(days.to); day = nextBucket(day, "day") {
+			weeks[TruncateBucket(day, "week")] = struct{}{}
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, days := range ranges {
+			if err := tx.Where("granularity IN ? AND bucket_start >= ? AND bucket_start < ?", []string{"hour", "day"}, days.from, days.to).
+				Delete(&models.DripRollup{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(rollups) > 0 {
+			if err := tx.CreateInBatches(rollups, 500).Error; err != nil {
+				return err
+			}
+		}
+
+		for week := range weeks {
+			weekly, err := r.rollupWeek(tx, week, now)
+			if err != nil {
+				return err
+			}
+			if err := tx.Where("granularity = ? AND bucket_start = ?", "week", week).
+				Delete(&models.DripRollup{}).Error; err != nil {
+				return err
+			}
+			if len(weekly) > 0 {
+				if err := tx.Create(weekly).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write rollups: %w", err)
+	}
+
+	r.watermark = now
+	slog.Debug("stats rollups refreshed", "day_ranges", len(ranges), "rollups", len(rollups), "weeks", len(weeks))
+	return nil
+}
+
+// changedDays returns the days with drips created or updated since the
+// watermark, less Lookback, merged into runs of consecutive days. With no
+// watermark it's every day from the first drip's to today.
+func (r *StatsRollup) changedDays(ctx context.Context, watermark, now time.Time) ([]dayRange, error) {
+	db := r.DB.WithContext(ctx)
+
+	if watermark.IsZero() {
+		var first models.Drip
+		found := db.Order("created_at").Limit(1).Find(&first)
+		if found.Error != nil {
+			return nil, fmt.Errorf("failed to load first drip: %w", found.Error)
+		}
+		if found.RowsAffected == 0 {
+			return nil, nil
+		}
+		return []dayRange{{TruncateBucket(first.CreatedAt, "day"), nextBucket(TruncateBucket(now, "day"), "day")}}, nil
+	}
+
+	lookback := r.Lookback
+	if lookback == 0 {
+		lookback = 5 * time.Minute
+	}
+
+	var created []time.Time
+	if err := db.Model(&models.Drip{}).Where("updated_at >= ?", watermark.Add(-lookback)).
+		Pluck("created_at", &created).Error; err != nil {
+		return nil, fmt.Errorf("failed to load changed drips: %w", err)
+	}
+
+	days := make([]time.Time, 0, len(created))
+	for _, at := range created {
+		days = append(days, TruncateBucket(at, "day"))
+	}
+	slices.SortFunc(days, func(a, b time.Time) int { return a.Compare(b) })
+	days = slices.Compact(days)
+
+	var ranges []dayRange
+	for _, day := range days {
+		if n := len(ranges); n > 0 && ranges[n-1].to.Equal(day) {
+			ranges[n-1].to = nextBucket(day, "day")
+			continue
+		}
+		ranges = append(ranges, dayRange{day, nextBucket(day, "day")})
+	}
+	return ranges, nil
+}
+
+// aggregate totals drips requested from from up to to into hour and day
+// buckets
+func (r *StatsRollup) aggregate(ctx context.Context, from, to time.Time) (map[rollupKey]*rollupTotals, error) {
+	rows, err := r.DB.WithContext(ctx).Model(&models.Drip{}).
+		Select("token_id, recipient, status, amount_base_units, gas_used, gas_cost, created_at, completed_at").
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Rows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan drips: %w", err)
+	}
+	defer rows.Close()
+
+	totals := make(map[rollupKey]*rollupTotals)
+	for rows.Next() {
+		var drip models.Drip
+		if err := r.DB.ScanRows(rows, &drip); err != nil {
+			return nil, fmt.Errorf("failed to scan drip: %w", err)
+		}
+
+		for _, granularity := range []string{"hour", "day"} {
+			key := rollupKey{granularity, TruncateBucket(drip.CreatedAt, granularity), drip.TokenID}
+			t := totals[key]
+			if t == nil {
+				t = &rollupTotals{users: make(map[string]struct{}), amount: new(big.Int), gasCost: new(big.Int)}
+				totals[key] = t
+			}
+
+			t.drips++
+			switch drip.Status {
+			case "completed":
+				t.completed++
+				t.users[drip.Recipient] = struct{}{}
+				t.amount.Add(t.amount, parseDecimal(drip.AmountBaseUnits))
+				if drip.CompletedAt != nil {
+					t.confirmations = append(t.confirmations, drip.CompletedAt.Sub(drip.CreatedAt).Milliseconds())
+				}
+			case "failed":
+				t.failed++
+			}
+
+			// Reverted drips were mined and paid for too
+			if drip.GasUsed != nil {
+				t.receipts++
+				t.gasUsed += int64(*drip.GasUsed)
+				t.gasCost.Add(t.gasCost, parseDecimal(drip.GasCost))
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan drips: %w", err)
+	}
+	return totals, nil
+}
+
+func (t *rollupTotals) rollup(key rollupKey, now time.Time) models.DripRollup {
+	rollup := models.DripRollup{
+		Granularity:     key.granularity,
+		BucketStart:     key.bucket,
+		TokenID:         key.tokenID,
+		Drips:           t.drips,
+		Completed:       t.completed,
+		Failed:          t.failed,
+		UniqueUsers:     int64(len(t.users)),
+		AmountBaseUnits: t.amount.String(),
+		Receipts:        t.receipts,
+		GasUsed:         t.gasUsed,
+		GasCost:         t.gasCost.String(),
+		RefreshedAt:     now,
+	}
+	if len(t.confirmations) > 0 {
+		median := medianInt64(t.confirmations)
+		rollup.MedianConfirmationMs = &median
+	}
+	return rollup
+}
+
+// rollupWeek sums the week's day rollups per token. Unique users and the
+// median confirmation are computed from the week's drips, since neither can
+// be derived from the daily values.
+func (r *StatsRollup) rollupWeek(tx *gorm.DB, week, now time.Time) ([]models.DripRollup, error) {
+	end := nextBucket(week, "week")
+
+	var days []models.DripRollup
+	if err := tx.Where("granularity = ? AND bucket_start >= ? AND bucket_start < ?", "day", week, end).
+		Order("bucket_start").Find(&days).Error; err != nil {
+		return nil, fmt.Errorf("failed to load day rollups: %w", err)
+	}
+
+	var users []struct {
+		TokenID string
+		Users   int64
+	}
+	if err := tx.Model(&models.Drip{}).
+		Select("token_id, COUNT(DISTINCT recipient) AS users").
+		Where("status = ? AND created_at >= ? AND created_at < ?", "completed", week, end).
+		Group("token_id").Scan(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to count weekly users: %w", err)
+	}
+	usersByToken := make(map[string]int64, len(users))
+	for _, u := range users {
+		usersByToken[u.TokenID] = u.Users
+	}
+
+	var confirmed []models.Drip
+	if err := tx.Select("token_id, created_at, completed_at").
+		Where("status = ? AND completed_at IS NOT NULL AND created_at >= ? AND created_at < ?", "completed", week, end).
+		Find(&confirmed).Error; err != nil {
+		return nil, fmt.Errorf("failed to load weekly confirmations: %w", err)
+	}
+	confirmations := make(map[string][]int64)
+	for _, drip := range confirmed {
+		confirmations[drip.TokenID] = append(confirmations[drip.TokenID], drip.CompletedAt.Sub(drip.CreatedAt).Milliseconds())
+	}
+
+	byToken := make(map[string]*models.DripRollup)
+	var tokenIDs []string
+	for _, day := range days {
+		w := byToken[day.TokenID]
+		if w == nil {
+			w = &models.DripRollup{
+				Granularity:     "week",
+				BucketStart:     week,
+				TokenID:         day.TokenID,
+				UniqueUsers:     usersByToken[day.TokenID],
+				AmountBaseUnits: "0",
+				GasCost:         "0",
+				RefreshedAt:     now,
+			}
+			byToken[day.TokenID] = w
+			tokenIDs = append(tokenIDs, day.TokenID)
+		}
+
+		w.Drips += day.Drips
+		w.Completed += day.Completed
+		w.Failed += day.Failed
+		w.Receipts += day.Receipts
+		w.GasUsed += day.GasUsed
+		w.AmountBaseUnits = addDecimal(w.AmountBaseUnits, day.AmountBaseUnits)
+		w.GasCost = addDecimal(w.GasCost, day.GasCost)
+	}
+
+	weekly := make([]models.DripRollup, 0, len(tokenIDs))
+	for _, tokenID := range tokenIDs {
+		w := byToken[tokenID]
+		if c := confirmations[tokenID]; len(c) > 0 {
+			median := medianInt64(c)
+			w.MedianConfirmationMs = &median
+		}
+		weekly = append(weekly, *w)
+	}
+	return weekly, nil
+}
+
+// addDecimal adds two base-unit totals, treating unparseable ones as zero.
+// SQLite may return numeric columns in exponent form.
+func addDecimal(a, b string) string {
+	sum := parseDecimal(a)
+	return sum.Add(sum, parseDecimal(b)).String()
+}
+
+func parseDecimal(s string) *big.Int {
+	if v, ok := new(big.Int).SetString(s, 10); ok {
+		return v
+	}
+	if f, ok := new(big.Float).SetPrec(256).SetString(s); ok {
+		v, _ := f.Int(nil)
+		return v
+	}
+	return new(big.Int)
+}
+
+func medianInt64(values []int64) int64 {
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	mid := len(values) / 2
+	if len(values)%2 == 1 {
+		return values[mid]
+	}
+	return (values[mid-1] + values[mid]) / 2
+}
+
+// TimeseriesQuery selects rollups for GET /api/faucet/stats/timeseries
+type TimeseriesQuery struct {
+	Granularity string
+	// From and To bound the buckets returned: the bucket containing From up
+	// to, but excluding, the one starting at or after To
+	From, To time.Time
+	// TokenIDs is empty for every active token
+	TokenIDs []string
+}
+
+// TimeseriesPoint is one token's stats for one bucket
+type TimeseriesPoint struct {
+	BucketStart     time.Time `json:"bucketStart"`
+	Drips           int64     `json:"drips"`
+	Completed       int64     `json:"completed"`
+	Failed          int64     `json:"failed"`
+	UniqueUsers     int64     `json:"uniqueUsers"`
+	Amount          string    `json:"amount"`
+	AmountBaseUnits string    `json:"amountBaseUnits"`
+	// FailureRate is failed over finished (completed or failed) drips
+	FailureRate          float64 `json:"failureRate"`
+	MedianConfirmationMs *int64  `json:"medianConfirmationMs"`
+}
+
+// Timeseries returns each token's points for every bucket in the query,
+// with empty buckets filled in
+func (r *StatsRollup) Timeseries(ctx context.Context, q TimeseriesQuery) (map[string][]TimeseriesPoint, error) {
+	if !slices.Contains(Granularities, q.Granularity) {
+		return nil, fmt.Errorf("%w: granularity must be hour, day or week", ErrInvalidStatsQuery)
+	}
+	if !q.From.Before(q.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidStatsQuery)
+	}
+
+	var buckets []time.Time
+	for b := TruncateBucket(q.From, q.Granularity); b.Before(q.To); b = nextBucket(b, q.Granularity) {
+		if len(buckets) == MaxTimeseriesBuckets {
+			return nil, fmt.Errorf("%w: more than %d %s buckets", ErrInvalidStatsQuery, MaxTimeseriesBuckets, q.Granularity)
+		}
+		buckets = append(buckets, b)
+	}
+
+	db := r.DB.WithContext(ctx)
+
+	var tokens []models.Token
+	tokenQuery := db.Where("is_active = true")
+	if len(q.TokenIDs) > 0 {
+		tokenQuery = db.Where("id IN ?", q.TokenIDs)
+	}
+	if err := tokenQuery.Order("created_at, id").Find(&tokens).Error; err != nil {
+		return nil, fmt.Errorf("failed to load tokens: %w", err)
+	}
+
+	tokenIDs := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		tokenIDs = append(tokenIDs, token.ID)
+	}
+
+	var rollups []models.DripRollup
+	if err := db.Where("granularity = ? AND bucket_start >= ? AND bucket_start < ? AND token_id IN ?",
+		q.Granularity, buckets[0], q.To, tokenIDs).
+		Find(&rollups).Error; err != nil {
+		return nil, fmt.Errorf("failed to load rollups: %w", err)
+	}
+
+	byKey := make(map[rollupKey]models.DripRollup, len(rollups))
+	for _, rollup := range rollups {
+		byKey[rollupKey{q.Granularity, rollup.BucketStart.UTC(), rollup.TokenID}] = rollup
+	}
+
+	series := make(map[string][]TimeseriesPoint, len(tokens))
+	for _, token := range tokens {
+		points := make([]TimeseriesPoint, 0, len(buckets))
+		for _, bucket := range buckets {
+			// Buckets without a rollup are empty; parseDecimal reads "" as zero
+			rollup := byKey[rollupKey{q.Granularity, bucket, token.ID}]
+			amount := parseDecimal(rollup.AmountBaseUnits)
+
+			point := TimeseriesPoint{
+				BucketStart:          bucket,
+				Drips:                rollup.Drips,
+				Completed:            rollup.Completed,
+				Failed:               rollup.Failed,
+				UniqueUsers:          rollup.UniqueUsers,
+				Amount:               units.Format(amount, token.Decimals),
+				AmountBaseUnits:      amount.String(),
+				MedianConfirmationMs: rollup.MedianConfirmationMs,
+			}
+			if finished := rollup.Completed + rollup.Failed; finished > 0 {
+				point.FailureRate = float64(rollup.Failed) / float64(finished)
+			}
+			points = append(points, point)
+		}
+		series[token.ID] = points
+	}
+	return series, nil
+}
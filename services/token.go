@@ -3,20 +3,25 @@ package services
 import (
 	"context"
 	"math/big"
+	"strings"
+
+	"faucet-backend/metrics"
+	"faucet-backend/models"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"strings"
 )
 
 // ERC20 Transfer ABI
 const erc20TransferABI = `[{"constant":false,"inputs":[{"name":"_to","type":"address"},{"name":"_value","type":"uint256"}],"name":"transfer","outputs":[{"name":"","type":"bool"}],"type":"function"}]`
 
-// SendERC20 sends ERC20 tokens
-func SendERC20(tokenAddress common.Address, to common.Address, amount *big.Int) (string, error) {
-	ctx := context.Background()
+// defaultMintSelector is mint(address,uint256), as on OpenZeppelin
+// ERC20PresetMinterPauser
+var defaultMintSelector = common.FromHex("0x40c10f19")
 
+// SendERC20 sends ERC20 tokens
+func (w *Wallet) SendERC20(ctx context.Context, tokenAddress common.Address, to common.Address, amount *big.Int) (string, error) {
 	// Parse ABI
 	parsedABI, err := abi.JSON(strings.NewReader(erc20TransferABI))
 	if err != nil {
@@ -29,45 +34,91 @@ func SendERC20(tokenAddress common.Address, to common.Address, amount *big.Int)
 		return "", err
 	}
 
-	nonce, err := GetNextNonce()
+	// ERC20 transfer typically needs ~65k gas
+	return w.sendCall(ctx, tokenAddress, data, 100000)
+}
+
+// Mint calls token's mint function, the standard's default unless the token
+// configures another selector. The wallet must be allowed to mint.
+func (w *Wallet) Mint(ctx context.Context, token *models.Token, to common.Address, amount *big.Int) (string, error) {
+	gasLimit := uint64(150000)
+	if token.IsNFT() {
+		gasLimit = nftGasLimit
+	}
+	return w.sendCall(ctx, common.HexToAddress(token.Address), mintCalldata(token, to, amount), gasLimit)
+}
+
+// mintCalldata encodes a call to token's mint function: mint(to, amount)
+// for ERC20, safeMint(to) for ERC-721, where the contract picks the ID, and
+// mint(to, id, amount, "") for ERC-1155
+func mintCalldata(token *models.Token, to common.Address, amount *big.Int) []byte {
+	selector := defaultMintSelector
+	switch token.Standard {
+	case models.StandardERC721:
+		selector = erc721SafeMintSelector
+	case models.StandardERC1155:
+		selector = erc1155MintSelector
+	}
+	if token.MintSelector != "" {
+		selector = common.FromHex(token.MintSelector)
+	}
+
+	switch token.Standard {
+	case models.StandardERC721:
+		return calldata(selector, to.Bytes())
+	case models.StandardERC1155:
+		args := [][]byte{to.Bytes(), nftID(token).Bytes(), amount.Bytes()}
+		return calldata(selector, append(args, emptyBytesArg(4)...)...)
+	}
+	return calldata(selector, to.Bytes(), amount.Bytes())
+}
+
+// sendCall signs and broadcasts a contract call with no ETH value
+func (w *Wallet) sendCall(ctx context.Context, contract common.Address, data []byte, gasLimit uint64) (string, error) {
+	nonce, err := w.NextNonce(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	gasLimit := uint64(100000) // ERC20 transfer typically needs ~65k gas
-
-	gasPrice, err := client.SuggestGasPrice(ctx)
+	gasCtx, done := traceRPC(ctx, "eth_gasPrice")
+	gasPrice, err := w.client.SuggestGasPrice(gasCtx)
+	done(err)
 	if err != nil {
 		return "", err
 	}
 
-	chainID, err := client.NetworkID(ctx)
+	chainCtx, done := traceRPC(ctx, "net_version")
+	chainID, err := w.client.NetworkID(chainCtx)
+	done(err)
 	if err != nil {
 		return "", err
 	}
 
 	tx := types.NewTransaction(
 		nonce,
-		tokenAddress,
-		big.NewInt(0), // No ETH value for ERC20 transfer
+		contract,
+		big.NewInt(0),
 		gasLimit,
 		gasPrice,
 		data,
 	)
 
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), privateKey)
+	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), w.privateKey)
 	if err != nil {
 		return "", err
 	}
 
-	err = client.SendTransaction(ctx, signedTx)
+	sendCtx, done := traceRPC(ctx, "eth_sendRawTransaction")
+	err = w.client.SendTransaction(sendCtx, signedTx)
+	done(err)
 	if err != nil {
 		// Rollback nonce
-		nonceMutex.Lock()
-		if currentNonce != nil && *currentNonce > 0 {
-			*currentNonce--
+		w.nonceMutex.Lock()
+		if w.currentNonce != nil && *w.currentNonce > 0 {
+			*w.currentNonce--
+			metrics.WalletNonce.Set(float64(*w.currentNonce))
 		}
-		nonceMutex.Unlock()
+		w.nonceMutex.Unlock()
 		return "", err
 	}
 
@@ -0,0 +1,352 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"faucet-backend/models"
+	"faucet-backend/units"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const tokenListingKey = "faucet:cache:tokens"
+
+// TokenListingEntry is one token in GET /api/faucet/tokens
+type TokenListingEntry struct {
+	models.Token
+	TotalDrips int64 `json:"totalDrips"`
+	// Balance is the exact balance in whole tokens, BalanceBaseUnits the
+	// raw on-chain value
+	Balance             string `json:"balance"`
+	BalanceBaseUnits    string `json:"balanceBaseUnits"`
+	DripAmountBaseUnits string `json:"dripAmountBaseUnits"`
+	Status              string `json:"status"`
+	// Inventory is how many IDs an ERC-721 collection distributed by
+	// transfer has left to send
+	Inventory *int64 `json:"inventory,omitempty"`
+	// AmountPolicy is how drips are sized, and EffectiveAmount what a drip
+	// requested as of UpdatedAt sends
+	AmountPolicy             string `json:"amountPolicy"`
+	EffectiveAmount          string `json:"effectiveAmount"`
+	EffectiveAmountBaseUnits string `json:"effectiveAmountBaseUnits"`
+	// BalanceUpdatedAt is when the balance was last read from the chain; it
+	// lags UpdatedAt while the RPC node is failing
+	BalanceUpdatedAt *time.Time `json:"balanceUpdatedAt,omitempty"`
+}
+
+// TokenListing is the body of GET /api/faucet/tokens
+type TokenListing struct {
+	Tokens []TokenListingEntry `json:"tokens"`
+	// Stale is set when some balances couldn't be refreshed and are from an
+	// earlier listing
+	Stale     bool      `json:"stale"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// CachedTokenListing is a rendered listing and its ETag
+type CachedTokenListing struct {
+	Body      []byte
+	ETag      string
+	UpdatedAt time.Time
+}
+
+// TokenListingCache builds the token listing in the background and caches it
+// in Redis, shared by every replica, with an in-memory copy for when Redis
+// is unavailable
+type TokenListingCache struct {
+	DB     *gorm.DB
+	Redis  *redis.Client
+	Wallet *Wallet
+	// Amounts computes the effective drip amounts; nil lists every token's
+	// fixed amount
+	Amounts *DripAmounts
+	// Interval is how often the listing is rebuilt. Defaults to 15s.
+	Interval time.Duration
+	// TTL is how long a listing is kept in Redis, and so how stale a listing
+	// can be served while refreshes fail. Defaults to 10 minutes.
+	TTL time.Duration
+
+	refreshing sync.Mutex
+	mu         sync.RWMutex
+	local      *CachedTokenListing
+	previous   *TokenListing
+}
+
+func (c *TokenListingCache) interval() time.Duration {
+	if c.Interval == 0 {
+		return 15 * time.Second
+	}
+	return c.Interval
+}
+
+func (c *TokenListingCache) ttl() time.Duration {
+	if c.TTL == 0 {
+		return 10 * time.Minute
+	}
+	return c.TTL
+}
+
+// MaxAge is how long clients may cache the listing
+func (c *TokenListingCache) MaxAge() time.Duration {
+	return c.interval()
+}
+
+// Start rebuilds the listing every Interval until ctx is cancelled
+func (c *TokenListingCache) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(c.interval())
+		defer ticker.Stop()
+
+		for {
+			if err := c.Refresh(ctx); err != nil {
+				slog.Warn("failed to refresh token listing", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Get returns the cached listing. A listing older than Interval is still
+// returned, and a refresh is started in the background. Only a cold cache
+// is built synchronously.
+func (c *TokenListingCache) Get(ctx context.Context) (*CachedTokenListing, error) {
+	cached := c.load(ctx)
+	if cached == nil {
+		if err := c.Refresh(ctx); err != nil {
+			return nil, err
+		}
+		if cached = c.load(ctx); cached == nil {
+			return nil, errors.New("token listing unavailable")
+		}
+		return cached, nil
+	}
+
+	if time.Since(cached.UpdatedAt) > c.interval() && c.refreshing.TryLock() {
+		c.refreshing.Unlock()
+		go func() {
+			if err := c.Refresh(context.WithoutCancel(ctx)); err != nil {
+				slog.Warn("failed to refresh token listing", "error", err)
+			}
+		}()
+	}
+	return cached, nil
+}
+
+// load reads the listing from Redis, falling back to the in-memory copy
+func (c *TokenListingCache) load(ctx context.Context) *CachedTokenListing {
+	values, err := c.Redis.HGetAll(ctx, tokenListingKey).Result()
+	if err == nil && values["body"] != "" {
+		updatedAt, _ := strconv.ParseInt(values["updated_at"], 10, 64)
+		return &CachedTokenListing{
+			Body:      []byte(values["body"]),
+			ETag:      values["etag"],
+			UpdatedAt: time.UnixMilli(updatedAt),
+		}
+	}
+	if err != nil {
+		slog.Warn("failed to read token listing from redis", "error", err)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.local
+}
+
+// Refresh rebuilds the listing from the database and the chain. A token
+// whose balance can't be read keeps its balance from the previous listing.
+func (c *TokenListingCache) Refresh(ctx context.Context) error {
+	c.refreshing.Lock()
+	defer c.refreshing.Unlock()
+
+	listing, err := c.build(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(listing)
+	if err != nil {
+		return err
+	}
+	etag, err := listingETag(listing)
+	if err != nil {
+		return err
+	}
+	cached := &CachedTokenListing{
+		Body:      body,
+		ETag:      etag,
+		UpdatedAt: listing.UpdatedAt,
+	}
+
+	c.mu.Lock()
+	c.local = cached
+	c.previous = listing
+	c.mu.Unlock()
+
+	pipe := c.Redis.TxPipeline()
+	pipe.HSet(ctx, tokenListingKey,
+		"body", body,
+		"etag", cached.ETag,
+		"updated_at", cached.UpdatedAt.UnixMilli(),
+	)
+	pipe.Expire(ctx, tokenListingKey, c.ttl())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to cache token listing: %w", err)
+	}
+	return nil
+}
+
+// listingETag hashes the listing without its refresh timestamps, so
+// rebuilding an unchanged listing keeps its ETag
+func listingETag(listing *TokenListing) (string, error) {
+	content := *listing
+	content.UpdatedAt = time.Time{}
+	content.Tokens = make([]TokenListingEntry, len(listing.Tokens))
+	for i, entry := range listing.Tokens {
+		entry.BalanceUpdatedAt = nil
+		content.Tokens[i] = entry
+	}
+
+	body, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`, nil
+}
+
+func (c *TokenListingCache) build(ctx context.Context) (*TokenListing, error) {
+	db := c.DB.WithContext(ctx)
+
+	var tokens []models.Token
+	if err := db.Where("is_active = true").Order("created_at, id").Find(&tokens).Error; err != nil {
+		return nil, fmt.Errorf("failed to load tokens: %w", err)
+	}
+
+	var counts []struct {
+		TokenID string
+		Count   int64
+	}
+	if err := db.Model(&models.Drip{}).
+		Select("token_id, COUNT(*) AS count").
+		Where("status = ?", "completed").
+		Group("token_id").
+		Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count drips: %w", err)
+	}
+	totals := make(map[string]int64, len(counts))
+	for _, count := range counts {
+		totals[count.TokenID] = count.Count
+	}
+
+	var stock []struct {
+		TokenID string
+		Count   int64
+	}
+	if err := db.Model(&models.InventoryItem{}).
+		Select("token_id, COUNT(*) AS count").
+		Where("status = ?", models.InventoryAvailable).
+		Group("token_id").
+		Scan(&stock).Error; err != nil {
+		return nil, fmt.Errorf("failed to count inventory: %w", err)
+	}
+	inventory := make(map[string]int64, len(stock))
+	for _, count := range stock {
+		inventory[count.TokenID] = count.Count
+	}
+
+	c.mu.RLock()
+	last := c.previous
+	c.mu.RUnlock()
+	if last == nil {
+		last = c.decodeCached(ctx)
+	}
+	previous := make(map[string]TokenListingEntry)
+	if last != nil {
+		for _, entry := range last.Tokens {
+			previous[entry.ID] = entry
+		}
+	}
+
+	now := time.Now()
+	listing := &TokenListing{Tokens: []TokenListingEntry{}, UpdatedAt: now}
+	for _, token := range tokens {
+		entry := TokenListingEntry{
+			Token:      token,
+			TotalDrips: totals[token.ID],
+			Status:     "available",
+		}
+		if token.PausedAt != nil {
+			entry.Status = "temporarily empty"
+		}
+		if token.Standard == models.StandardERC721 && token.DistributionMode() == models.DistributeTransfer {
+			available := inventory[token.ID]
+			entry.Inventory = &available
+		}
+		if perDrip, err := units.Parse(token.DripAmount, token.Decimals); err == nil {
+			entry.DripAmountBaseUnits = perDrip.String()
+		}
+
+		balance, err := c.Wallet.TokenBalance(ctx, &token)
+		switch {
+		case err == nil:
+			entry.Balance = units.Format(balance, token.Decimals)
+			entry.BalanceBaseUnits = balance.String()
+			entry.BalanceUpdatedAt = &now
+		case previous[token.ID].BalanceUpdatedAt != nil:
+			// Serve the last known balance while the RPC node is failing
+			last := previous[token.ID]
+			entry.Balance, entry.BalanceBaseUnits, entry.BalanceUpdatedAt = last.Balance, last.BalanceBaseUnits, last.BalanceUpdatedAt
+			listing.Stale = true
+		default:
+			entry.Balance, entry.BalanceBaseUnits = "0", "0"
+			listing.Stale = true
+		}
+		if err != nil {
+			slog.Warn("failed to refresh token balance", "token", token.ID, "error", err)
+		}
+
+		entry.AmountPolicy = c.Amounts.Policy(token.ID)
+		if err != nil && entry.AmountPolicy == AmountBalance {
+			// A share of a balance that couldn't be read
+			last := previous[token.ID]
+			entry.EffectiveAmount, entry.EffectiveAmountBaseUnits = last.EffectiveAmount, last.EffectiveAmountBaseUnits
+		} else if amount, err := c.Amounts.amount(ctx, &token, balance); err == nil {
+			entry.EffectiveAmount = units.Format(amount, token.Decimals)
+			entry.EffectiveAmountBaseUnits = amount.String()
+		} else {
+			slog.Warn("failed to compute effective drip amount", "token", token.ID, "error", err)
+		}
+
+		listing.Tokens = append(listing.Tokens, entry)
+	}
+	return listing, nil
+}
+
+// decodeCached returns the listing cached in Redis by any replica, so a
+// fresh process can fall back to its balances
+func (c *TokenListingCache) decodeCached(ctx context.Context) *TokenListing {
+	body, err := c.Redis.HGet(ctx, tokenListingKey, "body").Bytes()
+	if err != nil {
+		return nil
+	}
+	var listing TokenListing
+	if err := json.Unmarshal(body, &listing); err != nil {
+		return nil
+	}
+	return &listing
+}
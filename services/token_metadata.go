@@ -0,0 +1,307 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"faucet-backend/models"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"gorm.io/gorm"
+)
+
+// ERC20 metadata selectors
+var (
+	nameSelector     = common.FromHex("0x06fdde03")
+	symbolSelector   = common.FromHex("0x95d89b41")
+	decimalsSelector = common.FromHex("0x313ce567")
+)
+
+// TokenMetadata is what a token contract reports about itself. Name, Symbol
+// and Decimals are empty or nil when the contract doesn't implement them.
+type TokenMetadata struct {
+	Address  string `json:"address"`
+	HasCode  bool   `json:"hasCode"`
+	Name     string `json:"name,omitempty"`
+	Symbol   string `json:"symbol,omitempty"`
+	Decimals *int   `json:"decimals,omitempty"`
+}
+
+type cachedMetadata struct {
+	metadata  *TokenMetadata
+	fetchedAt time.Time
+}
+
+// TokenMetadataService reads ERC20 metadata from the chain and caches it
+type TokenMetadataService struct {
+	DB     *gorm.DB
+	Wallet *Wallet
+	// TTL is how long metadata is cached. Defaults to an hour.
+	TTL time.Duration
+
+	mu    sync.Mutex
+	cache map[common.Address]cachedMetadata
+}
+
+// Lookup returns the metadata of the contract at addr, from the cache when
+// it's fresh
+func (s *TokenMetadataService) Lookup(ctx context.Context, addr common.Address) (*TokenMetadata, error) {
+	ttl := s.TTL
+	if ttl == 0 {
+		ttl = time.Hour
+	}
+
+	s.mu.Lock()
+	cached, ok := s.cache[addr]
+	s.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < ttl {
+		return cached.metadata, nil
+	}
+
+	metadata, err := s.fetch(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.cache == nil {
+		s.cache = make(map[common.Address]cachedMetadata)
+	}
+	s.cache[addr] = cachedMetadata{metadata: metadata, fetchedAt: time.Now()}
+	s.mu.Unlock()
+
+	return metadata, nil
+}
+
+func (s *TokenMetadataService) fetch(ctx context.Context, addr common.Address) (*TokenMetadata, error) {
+	metadata := &TokenMetadata{Address: addr.Hex()}
+
+	codeCtx, done := traceRPC(ctx, "eth_getCode")
+	code, err := s.Wallet.client.CodeAt(codeCtx, addr, nil)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read code at %s: %w", addr.Hex(), err)
+	}
+	if len(code) == 0 {
+		return metadata, nil
+	}
+	metadata.HasCode = true
+
+	ret, err := s.call(ctx, addr, nameSelector)
+	if err != nil {
+		return nil, err
+	}
+	metadata.Name = decodeStringResult(ret)
+
+	if ret, err = s.call(ctx, addr, symbolSelector); err != nil {
+		return nil, err
+	}
+	metadata.Symbol = decodeStringResult(ret)
+
+	if ret, err = s.call(ctx, addr, decimalsSelector); err != nil {
+		return nil, err
+	}
+	if len(ret) == 32 {
+		if value := new(big.Int).SetBytes(ret); value.IsUint64() && value.Uint64() <= 255 {
+			decimals := int(value.Uint64())
+			metadata.Decimals = &decimals
+		}
+	}
+
+	return metadata, nil
+}
+
+// call runs a read-only call. A call the contract reverts, as it does for a
+// missing function, returns no data rather than an error so optional
+// metadata doesn't fail the lookup. Any other node error is returned.
+func (s *TokenMetadataService) call(ctx context.Context, addr common.Address, data []byte) ([]byte, error) {
+	ctx, done := traceRPC(ctx, "eth_call")
+	ret, err := s.Wallet.client.CallContract(ctx, ethereum.CallMsg{To: &addr, Data: data}, nil)
+	if isRevert(err) {
+		done(nil)
+		return nil, nil
+	}
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", addr.Hex(), err)
+	}
+	return ret, nil
+}
+
+// isRevert reports whether a call failed because the contract reverted, as
+// opposed to the node failing to run it. Nodes answer reverts with error code
+// 3, or without it when the revert carries no data.
+func isRevert(err error) bool {
+	if err == nil {
+		return false
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == 3 {
+		return true
+	}
+	return strings.Contains(err.Error(), "execution reverted")
+}
+
+// decodeStringResult decodes an ABI string return value, or a bytes32 one
+// as returned by older tokens such as MKR. It returns "" for anything else.
+func decodeStringResult(ret []byte) string {
+	if len(ret) == 32 {
+		return cleanString(bytes.TrimRight(ret, "\x00"))
+	}
+	if len(ret) < 64 {
+		return ""
+	}
+
+	offset := new(big.Int).SetBytes(ret[:32])
+	if !offset.IsUint64() || offset.Uint64() > uint64(len(ret)-32) {
+		return ""
+	}
+	start := offset.Uint64() + 32
+	length := new(big.Int).SetBytes(ret[start-32 : start])
+	if !length.IsUint64() || length.Uint64() > uint64(len(ret))-start {
+		return ""
+	}
+	return cleanString(ret[start : start+length.Uint64()])
+}
+
+func cleanString(b []byte) string {
+	if !utf8.Valid(b) {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// ErrCannotMint is returned by VerifyMinter when the faucet wallet's mint
+// call reverts
+var ErrCannotMint = errors.New("faucet wallet can't mint")
+
+// VerifyMinter simulates the faucet wallet minting one base unit of a mint
+// token to itself. It returns ErrCannotMint when the call reverts, usually
+// because the wallet lacks the minter role or the selector is wrong.
+func (s *TokenMetadataService) VerifyMinter(ctx context.Context, token *models.Token) error {
+	addr := common.HexToAddress(token.Address)
+	from := s.Wallet.address
+
+	callCtx, done := traceRPC(ctx, "eth_call")
+	_, err := s.Wallet.client.CallContract(callCtx, ethereum.CallMsg{
+		From: from,
+		To:   &addr,
+		Data: mintCalldata(token, from, big.NewInt(1)),
+	}, nil)
+	if isRevert(err) {
+		done(nil)
+		return fmt.Errorf("%w: %v", ErrCannotMint, err)
+	}
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", addr.Hex(), err)
+	}
+	return nil
+}
+
+// MintPauseReason is the pause reason of a mint token the faucet wallet
+// can't mint
+const MintPauseReason = "faucet wallet can't mint; grant it the minter role or fix mintSelector"
+
+// ValidateTokens compares every active ERC20 token's configured name, symbol
+// and decimals with what its contract reports, and warns about mismatches.
+// Mint tokens the faucet wallet can't mint are paused until it can. It
+// returns the number of tokens with problems.
+func (s *TokenMetadataService) ValidateTokens(ctx context.Context) (int, error) {
+	var tokens []models.Token
+	if err := s.DB.WithContext(ctx).Where("is_active = true AND address <> ''").Find(&tokens).Error; err != nil {
+		return 0, fmt.Errorf("failed to load tokens: %w", err)
+	}
+
+	mismatched := 0
+	for _, token := range tokens {
+		problems, err := s.validate(ctx, &token)
+		if err != nil {
+			slog.Warn("token metadata check failed", "token", token.ID, "address", token.Address, "error", err)
+			continue
+		}
+		for _, problem := range problems {
+			slog.Warn("token metadata mismatch", "token", token.ID, "address", token.Address, "problem", problem)
+		}
+		if len(problems) > 0 {
+			mismatched++
+		}
+	}
+	return mismatched, nil
+}
+
+// pauseUnmintable pauses a mint token whose minter check failed, or resumes
+// one paused by an earlier check that now passes
+func (s *TokenMetadataService) pauseUnmintable(ctx context.Context, token *models.Token, cannotMint bool) error {
+	db := s.DB.WithContext(ctx).Model(token)
+	switch {
+	case cannotMint && token.PausedAt == nil:
+		slog.Error("pausing mint token", "token", token.ID, "reason", MintPauseReason)
+		return db.Updates(map[string]interface{}{
+			"paused_at":     time.Now(),
+			"paused_reason": MintPauseReason,
+		}).Error
+	case !cannotMint && token.PausedAt != nil && token.PausedReason == MintPauseReason:
+		slog.Info("resuming mint token", "token", token.ID)
+		return db.Updates(map[string]interface{}{
+			"paused_at":     nil,
+			"paused_reason": "",
+		}).Error
+	}
+	return nil
+}
+
+func (s *TokenMetadataService) validate(ctx context.Context, token *models.Token) ([]string, error) {
+	if !common.IsHexAddress(token.Address) {
+		return []string{fmt.Sprintf("%q is not an address", token.Address)}, nil
+	}
+
+	metadata, err := s.Lookup(ctx, common.HexToAddress(token.Address))
+	if err != nil {
+		return nil, err
+	}
+	if !metadata.HasCode {
+		return []string{"no contract deployed at address"}, nil
+	}
+
+	var problems []string
+	switch {
+	case token.IsNFT():
+		// NFT contracts have no decimals
+	case metadata.Decimals == nil:
+		problems = append(problems, "contract has no decimals()")
+	case *metadata.Decimals != token.Decimals:
+		problems = append(problems, fmt.Sprintf("decimals configured as %d, contract reports %d", token.Decimals, *metadata.Decimals))
+	}
+	if metadata.Symbol != "" && !strings.EqualFold(metadata.Symbol, token.Symbol) {
+		problems = append(problems, fmt.Sprintf("symbol configured as %s, contract reports %s", token.Symbol, metadata.Symbol))
+	}
+	if metadata.Name != "" && !strings.EqualFold(metadata.Name, token.Name) {
+		problems = append(problems, fmt.Sprintf("name configured as %q, contract reports %q", token.Name, metadata.Name))
+	}
+
+	if token.DistributionMode() == models.DistributeMint {
+		err := s.VerifyMinter(ctx, token)
+		cannotMint := errors.Is(err, ErrCannotMint)
+		if err != nil && !cannotMint {
+			return nil, err
+		}
+		if cannotMint {
+			problems = append(problems, err.Error())
+		}
+		if err := s.pauseUnmintable(ctx, token, cannotMint); err != nil {
+			return nil, fmt.Errorf("failed to update pause: %w", err)
+		}
+	}
+	return problems, nil
+}
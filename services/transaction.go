@@ -2,76 +2,288 @@ package services
 
 import (
 	"context"
-	"faucet-backend/database"
+	"faucet-backend/events"
+	"faucet-backend/logging"
+	"faucet-backend/metrics"
 	"faucet-backend/models"
-	"log"
+	"faucet-backend/telemetry"
+	"faucet-backend/units"
+	"fmt"
+	"log/slog"
 	"math/big"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
 )
 
-func ExecuteDrip(recipient, tokenID string, dripID uint) {
+// ChainSender sends drips from the faucet wallet and tracks their receipts
+type ChainSender struct {
+	DB     *gorm.DB
+	Wallet *Wallet
+	Events *events.Bus
+	// PollInterval is how often the receipt is polled; the drip is given
+	// up on after 60 polls. Defaults to 5s.
+	PollInterval time.Duration
+
+	mu       sync.Mutex
+	draining bool
+	stop     chan struct{}
+	stopOnce sync.Once
+	// sends covers broadcasting a drip and recording its tx hash; trackers
+	// covers polling for receipts
+	sends    sync.WaitGroup
+	trackers sync.WaitGroup
+}
+
+// Send starts sending the drip in the background. Once the sender is
+// draining, new drips are failed without being broadcast.
+func (s *ChainSender) Send(ctx context.Context, drip *models.Drip) {
+	s.SendBatch(ctx, []*models.Drip{drip})
+}
+
+// SendBatch starts sending the drips of one batch in the background, back to
+// back on one goroutine so their transactions take consecutive nonces
+// without waiting for each other's receipts. The first drip that fails to
+// broadcast stops the batch: the wallet's nonce is re-read from the node and
+// the rest are failed unsent. Once the sender is draining, they are all
+// failed without being broadcast.
+func (s *ChainSender) SendBatch(ctx context.Context, drips []*models.Drip) {
+	s.mu.Lock()
+	if s.draining {
+		s.mu.Unlock()
+		s.failUnsent(ctx, drips, "shutdown", "Faucet is shutting down")
+		return
+	}
+	s.sends.Add(1)
+	s.mu.Unlock()
+
+	go func() {
+		defer s.sends.Done()
+		for i, drip := range drips {
+			if err := s.execute(ctx, drip); err != nil {
+				// A failed send may leave a gap at the nonce it took
+				s.Wallet.ResetNonce()
+				s.failUnsent(ctx, drips[i+1:], "batch_aborted", "Not sent: an earlier drip in the batch failed to send")
+				return
+			}
+		}
+	}()
+}
+
+// failUnsent fails drips that were never broadcast
+func (s *ChainSender) failUnsent(ctx context.Context, drips []*models.Drip, reason, message string) {
+	for _, drip := range drips {
+		logging.FromContext(ctx).Warn("drip not sent", "drip_id", drip.ID, "reason", reason)
+		metrics.Drips.WithLabelValues(drip.TokenID, "failed", reason).Inc()
+		s.DB.WithContext(ctx).Model(&models.Drip{}).Where("id = ?", drip.ID).Updates(map[string]interface{}{
+			"status": "failed",
+			"error":  message,
+		})
+		s.publishDripEvent(ctx, events.DripFailed, drip.ID)
+	}
+}
+
+// Resume restarts receipt tracking for drips that were broadcast but not yet
+// confirmed when the previous process stopped
+func (s *ChainSender) Resume(ctx context.Context) error {
+	var drips []models.Drip
+	if err := s.DB.WithContext(ctx).Where("status = ? AND tx_hash <> ''", "pending").Find(&drips).Error; err != nil {
+		return fmt.Errorf("failed to load pending drips: %w", err)
+	}
+
+	for _, drip := range drips {
+		ctx, _ := logging.With(context.WithoutCancel(ctx),
+			"drip_id", drip.ID, "token", drip.TokenID, "recipient", drip.Recipient, "tx_hash", drip.TxHash)
+		s.track(ctx, drip.TokenID, drip.ID, drip.TxHash)
+	}
+	if len(drips) > 0 {
+		slog.Info("resumed receipt tracking", "drips", len(drips))
+	}
+	return nil
+}
+
+// Drain stops accepting drips, waits for in-flight sends to be broadcast and
+// recorded, then stops receipt tracking. Drips still awaiting a receipt stay
+// pending with their tx hash and are picked up again by Resume.
+func (s *ChainSender) Drain(ctx context.Context) error {
+	s.mu.Lock()
+	s.draining = true
+	s.mu.Unlock()
+
+	if err := wait(ctx, &s.sends); err != nil {
+		return fmt.Errorf("drips still sending at shutdown: %w", err)
+	}
+
+	s.stopOnce.Do(func() { close(s.stopped()) })
+	if err := wait(ctx, &s.trackers); err != nil {
+		return fmt.Errorf("receipt tracking did not stop: %w", err)
+	}
+	return nil
+}
+
+func (s *ChainSender) stopped() chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop == nil {
+		s.stop = make(chan struct{})
+	}
+	return s.stop
+}
+
+// wait waits for wg or until ctx is done
+func wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// execute sends the drip and tracks its receipt. ctx carries the request's
+// logger and span; it is detached from the request's lifetime and traced as
+// a new root span linked to the request. It returns the error of a
+// transaction that failed to broadcast.
+func (s *ChainSender) execute(ctx context.Context, drip *models.Drip) error {
+	recipient, tokenID, dripID := drip.Recipient, drip.TokenID, drip.ID
+
+	ctx, span := telemetry.Tracer().Start(context.WithoutCancel(ctx), "drip.execute",
+		trace.WithNewRoot(),
+		trace.WithLinks(trace.LinkFromContext(ctx)),
+		trace.WithAttributes(
+			attribute.Int64("drip.id", int64(dripID)),
+			attribute.String("drip.token", tokenID),
+			attribute.String("drip.recipient", recipient),
+		),
+	)
+	defer span.End()
+
+	ctx, logger := logging.With(ctx, "drip_id", dripID, "token", tokenID, "recipient", recipient)
+	db := s.DB.WithContext(ctx)
+
 	// Get token info
 	var token models.Token
-	if err := database.DB.Where("id = ? AND is_active = true", tokenID).First(&token).Error; err != nil {
-		log.Printf("❌ Token %s not found or inactive", tokenID)
-		database.DB.Model(&models.Drip{}).Where("id = ?", dripID).Updates(map[string]interface{}{
+	if err := db.Where("id = ? AND is_active = true", tokenID).First(&token).Error; err != nil {
+		logger.Error("token not found or inactive", "tx_hash", "")
+		metrics.Drips.WithLabelValues(tokenID, "failed", "token_inactive").Inc()
+		db.Model(&models.Drip{}).Where("id = ?", dripID).Updates(map[string]interface{}{
 			"status": "failed",
 			"error":  "Token not found or inactive",
 		})
-		return
+		s.publishDripEvent(ctx, events.DripFailed, dripID)
+		return nil
 	}
 
-	// Parse amount
-	amount := new(big.Float)
-	amount.SetString(token.DripAmount)
-
-	// Convert to smallest unit based on decimals
-	decimals := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(token.Decimals)), nil)
-	amountFloat := new(big.Float).Mul(amount, new(big.Float).SetInt(decimals))
-	amountInt := new(big.Int)
-	amountFloat.Int(amountInt)
+	amountInt, ok := new(big.Int).SetString(drip.AmountBaseUnits, 10)
+	if !ok {
+		logger.Error("drip has no valid base-unit amount", "tx_hash", "", "amount", drip.AmountBaseUnits)
+		metrics.Drips.WithLabelValues(tokenID, "failed", "invalid_amount").Inc()
+		db.Model(&models.Drip{}).Where("id = ?", dripID).Updates(map[string]interface{}{
+			"status": "failed",
+			"error":  "Invalid drip amount",
+		})
+		s.publishDripEvent(ctx, events.DripFailed, dripID)
+		return nil
+	}
 
 	recipientAddr := common.HexToAddress(recipient)
-	var txHash string
+	var txHash, nftIDs string
 	var err error
 
-	// Send transaction based on token type
-	if token.Address == "" {
-		// Native ETH transfer
-		txHash, err = SendTransaction(recipientAddr, amountInt)
-	} else {
-		// ERC20 transfer
+	// Send transaction based on how the token is distributed. The IDs an
+	// ERC-721 mint creates are only known from its receipt.
+	switch mode := token.DistributionMode(); {
+	case mode == models.DistributeNative:
+		txHash, err = s.Wallet.SendTransaction(ctx, recipientAddr, amountInt)
+	case mode == models.DistributeMint:
+		txHash, err = s.Wallet.Mint(ctx, &token, recipientAddr, amountInt)
+		if token.Standard == models.StandardERC1155 {
+			nftIDs = token.NFTID
+		}
+	case token.IsNFT():
+		txHash, nftIDs, err = s.sendNFT(ctx, &token, dripID, recipientAddr, amountInt)
+	default:
 		tokenAddr := common.HexToAddress(token.Address)
-		txHash, err = SendERC20(tokenAddr, recipientAddr, amountInt)
+		txHash, err = s.Wallet.SendERC20(ctx, tokenAddr, recipientAddr, amountInt)
 	}
 
 	if err != nil {
-		log.Printf("❌ Failed to send %s to %s: %v", token.Symbol, recipient, err)
-		database.DB.Model(&models.Drip{}).Where("id = ?", dripID).Updates(map[string]interface{}{
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "send failed")
+		logger.Error("failed to send drip", "tx_hash", "", "error", err)
+		metrics.Drips.WithLabelValues(token.ID, "failed", "send_error").Inc()
+		db.Model(&models.Drip{}).Where("id = ?", dripID).Updates(map[string]interface{}{
 			"status": "failed",
 			"error":  err.Error(),
 		})
-		return
+		s.publishDripEvent(ctx, events.DripFailed, dripID)
+		return err
 	}
 
-	log.Printf("💧 %s drip sent: %s to %s", token.Symbol, txHash, recipient)
+	span.SetAttributes(attribute.String("drip.tx_hash", txHash))
+	ctx, logger = logging.With(ctx, "tx_hash", txHash)
+	db = db.WithContext(ctx)
+	logger.Info("drip sent", "amount", drip.Amount, "amount_base_units", drip.AmountBaseUnits)
+	metrics.Drips.WithLabelValues(token.ID, "sent", "").Inc()
 
 	// Update with tx hash
-	database.DB.Model(&models.Drip{}).Where("id = ?", dripID).Updates(map[string]interface{}{
+	updates := map[string]interface{}{
 		"tx_hash": txHash,
 		"status":  "pending",
-	})
+	}
+	if nftIDs != "" {
+		updates["nft_ids"] = nftIDs
+	}
+	db.Model(&models.Drip{}).Where("id = ?", dripID).Updates(updates)
+	s.publishDripEvent(ctx, events.DripBroadcast, dripID)
+
+	s.track(ctx, token.ID, dripID, txHash)
+	return nil
+}
+
+// track polls for the drip's receipt in the background until it's mined, the
+// polls run out or the sender is drained
+func (s *ChainSender) track(ctx context.Context, tokenID string, dripID uint, txHash string) {
+	s.trackers.Add(1)
+	stop := s.stopped()
 
-	// Wait for confirmation in background
 	go func() {
-		ctx := context.Background()
+		defer s.trackers.Done()
+
+		ctx, span := telemetry.Tracer().Start(ctx, "drip.await_receipt")
+		defer span.End()
+
+		logger := logging.FromContext(ctx)
+		db := s.DB.WithContext(ctx)
+
+		interval := s.PollInterval
+		if interval == 0 {
+			interval = 5 * time.Second
+		}
 
 		for i := 0; i < 60; i++ {
-			time.Sleep(5 * time.Second)
+			select {
+			case <-stop:
+				// The drip stays pending with its tx hash for Resume
+				logger.Info("receipt tracking checkpointed")
+				return
+			case <-time.After(interval):
+			}
 
-			receipt, err := client.TransactionReceipt(ctx, common.HexToHash(txHash))
+			rpcCtx, done := traceRPC(ctx, "eth_getTransactionReceipt")
+			receipt, err := s.Wallet.client.TransactionReceipt(rpcCtx, common.HexToHash(txHash))
+			done(err)
 			if err != nil {
 				continue
 			}
@@ -81,20 +293,48 @@ func ExecuteDrip(recipient, tokenID string, dripID uint) {
 				status = "failed"
 			}
 
-			database.DB.Model(&models.Drip{}).Where("id = ?", dripID).Updates(map[string]interface{}{
+			updates := map[string]interface{}{
 				"status":       status,
 				"completed_at": time.Now(),
-			})
+				"block_number": receipt.BlockNumber.Uint64(),
+				"gas_used":     receipt.GasUsed,
+			}
+			if receipt.EffectiveGasPrice != nil {
+				fee := new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), receipt.EffectiveGasPrice)
+				updates["effective_gas_price"] = receipt.EffectiveGasPrice.String()
+				updates["gas_cost"] = fee.String()
+				metrics.GasSpent.WithLabelValues(tokenID).Add(units.Float64(fee, 18))
+			}
+			db.Model(&models.Drip{}).Where("id = ?", dripID).Updates(updates)
+			s.settleNFTs(ctx, tokenID, dripID, receipt)
 
 			if status == "completed" {
-				log.Printf("✅ %s drip confirmed: %s", token.Symbol, txHash)
+				logger.Info("drip confirmed", "block", receipt.BlockNumber.Uint64())
+				metrics.Drips.WithLabelValues(tokenID, "confirmed", "").Inc()
+				s.publishDripEvent(ctx, events.DripConfirmed, dripID)
 			} else {
-				log.Printf("❌ %s drip failed: %s", token.Symbol, txHash)
+				logger.Error("drip reverted", "block", receipt.BlockNumber.Uint64())
+				span.SetStatus(codes.Error, "transaction reverted")
+				metrics.Drips.WithLabelValues(tokenID, "failed", "reverted").Inc()
+				s.publishDripEvent(ctx, events.DripFailed, dripID)
 			}
 
 			return
 		}
 
-		log.Printf("⚠️  Transaction %s not confirmed after 5 minutes", txHash)
+		logger.Warn("drip not confirmed", "waited", 60*interval)
+		span.SetStatus(codes.Error, "receipt timeout")
+		metrics.Drips.WithLabelValues(tokenID, "unconfirmed", "timeout").Inc()
 	}()
 }
+
+// publishDripEvent publishes the drip's current state as a lifecycle event
+func (s *ChainSender) publishDripEvent(ctx context.Context, eventType string, dripID uint) {
+	var drip models.Drip
+	if err := s.DB.WithContext(ctx).First(&drip, dripID).Error; err != nil {
+		logging.FromContext(ctx).Error("failed to load drip for event", "event", eventType, "error", err)
+		return
+	}
+
+	s.Events.Publish(ctx, events.NewDripEvent(eventType, &drip))
+}
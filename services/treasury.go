@@ -0,0 +1,384 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"faucet-backend/metrics"
+	"faucet-backend/models"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+)
+
+// Treasury transfer kinds
+const (
+	TransferRefill = "refill"
+	TransferMint   = "mint"
+	TransferSweep  = "sweep"
+)
+
+// RefillPolicy keeps one token's faucet balance between two marks, in base
+// units
+type RefillPolicy struct {
+	TokenID string
+	// Mint tops the wallet up by minting instead of from the treasury
+	Mint                bool
+	LowWater, HighWater *big.Int
+	// SweepAbove is nil when excess isn't swept back
+	SweepAbove *big.Int
+}
+
+// treasuryLockKey namespaces the per-token Postgres advisory locks a
+// replica holds while it rebalances a token
+const treasuryLockKey = 0x74726561 // "trea"
+
+type treasuryConfig struct {
+	sweepTo  common.Address
+	interval time.Duration
+	policies []RefillPolicy
+}
+
+// Treasury tops the faucet wallet up from the treasury, or by minting, when
+// a token runs low and sweeps excess back. Every movement is recorded as a
+// TreasuryTransfer.
+type Treasury struct {
+	DB     *gorm.DB
+	Wallet *Wallet
+	// Signer is the treasury account; refills from the treasury fail
+	// without one
+	Signer *Wallet
+	// ReceiptTimeout is how long a transfer can stay unmined before settle
+	// checks whether it was dropped. Defaults to 30 minutes.
+	ReceiptTimeout time.Duration
+
+	mu     sync.Mutex
+	config atomic.Pointer[treasuryConfig]
+	// running tracks the loop started by Start
+	running sync.WaitGroup
+}
+
+// Configure swaps in the refill policies, the sweep destination and the
+// check interval. A zero sweepTo sweeps to the treasury signer.
+func (t *Treasury) Configure(sweepTo common.Address, interval time.Duration, policies []RefillPolicy) {
+	t.config.Store(&treasuryConfig{sweepTo: sweepTo, interval: interval, policies: policies})
+}
+
+// Start checks balances every configured interval until ctx is cancelled
+func (t *Treasury) Start(ctx context.Context) {
+	t.running.Add(1)
+	go func() {
+		defer t.running.Done()
+		for {
+			if err := t.Check(ctx); err != nil {
+				slog.Error("treasury check failed", "error", err)
+			}
+
+			interval := 5 * time.Minute
+			if cfg := t.config.Load(); cfg != nil && cfg.interval > 0 {
+				interval = cfg.interval
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+// Wait waits, until ctx is done, for the loop started by Start to stop
+// after its ctx is cancelled, including any transfer it is sending
+func (t *Treasury) Wait(ctx context.Context) error {
+	if err := wait(ctx, &t.running); err != nil {
+		return fmt.Errorf("treasury still running at shutdown: %w", err)
+	}
+	return nil
+}
+
+// Check settles transfers sent by earlier checks, then refills or sweeps
+// every token with a policy and no transfer still in flight
+func (t *Treasury) Check(ctx context.Context) error {
+	cfg := t.config.Load()
+	if cfg == nil || len(cfg.policies) == 0 {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.settle(ctx); err != nil {
+		return err
+	}
+
+	sweepTo := cfg.sweepTo
+	if sweepTo == (common.Address{}) && t.Signer != nil {
+		sweepTo = t.Signer.address
+	}
+
+	for _, policy := range cfg.policies {
+		if ctx.Err() != nil {
+			break
+		}
+		locked, err := t.withTokenLock(ctx, policy.TokenID, func() error {
+			return t.rebalance(ctx, policy, sweepTo)
+		})
+		if err != nil {
+			slog.Error("treasury rebalance failed", "token", policy.TokenID, "error", err)
+		} else if !locked {
+			slog.Debug("treasury rebalance skipped, another replica holds the token", "token", policy.TokenID)
+		}
+	}
+	return nil
+}
+
+// withTokenLock runs fn holding the token's advisory lock, so replicas don't
+// refill the same token together. It returns false without running fn when
+// another replica holds the lock. Other databases rely on the in-process
+// mutex.
+func (t *Treasury) withTokenLock(ctx context.Context, tokenID string, fn func() error) (bool, error) {
+	if t.DB.Dialector.Name() != "postgres" {
+		return true, fn()
+	}
+
+	sqlDB, err := t.DB.DB()
+	if err != nil {
+		return false, err
+	}
+
+	// Advisory locks belong to a session, so take and release it on one
+	// connection
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1, hashtext($2))", treasuryLockKey, tokenID).Scan(&locked); err != nil {
+		return false, fmt.Errorf("failed to acquire treasury lock: %w", err)
+	}
+	if !locked {
+		return false, nil
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1, hashtext($2))", treasuryLockKey, tokenID); err != nil {
+			slog.Error("failed to release treasury lock", "token", tokenID, "error", err)
+		}
+	}()
+
+	return true, fn()
+}
+
+// rebalance sends at most one transfer for the policy's token, and none
+// while an earlier one is still in flight. Callers hold the token's lock.
+func (t *Treasury) rebalance(ctx context.Context, policy RefillPolicy, sweepTo common.Address) error {
+	var inFlight int64
+	if err := t.DB.WithContext(ctx).Model(&models.TreasuryTransfer{}).
+		Where("token_id = ? AND status = ?", policy.TokenID, "pending").
+		Count(&inFlight).Error; err != nil {
+		return fmt.Errorf("failed to load pending transfers: %w", err)
+	}
+	if inFlight > 0 {
+		return nil
+	}
+
+	var token models.Token
+	found := t.DB.WithContext(ctx).Where("id = ? AND is_active = true", policy.TokenID).Limit(1).Find(&token)
+	if found.Error != nil {
+		return fmt.Errorf("failed to load token: %w", found.Error)
+	}
+	if found.RowsAffected == 0 {
+		return nil
+	}
+
+	balance, err := t.Wallet.TokenBalance(ctx, &token)
+	if err != nil {
+		return fmt.Errorf("failed to read balance: %w", err)
+	}
+
+	hot := t.Wallet.address
+	transfer := models.TreasuryTransfer{TokenID: token.ID}
+	var send func() (string, error)
+
+	switch {
+	case balance.Cmp(policy.LowWater) < 0:
+		amount := new(big.Int).Sub(policy.HighWater, balance)
+		transfer.AmountBaseUnits = amount.String()
+		transfer.ToAddress = hot.Hex()
+
+		if policy.Mint {
+			transfer.Kind = TransferMint
+			transfer.FromAddress = hot.Hex()
+			send = func() (string, error) {
+				return t.Wallet.Mint(ctx, &token, hot, amount)
+			}
+			break
+		}
+
+		if t.Signer == nil {
+			return errors.New("balance is below the low-water mark but no treasury signer is configured")
+		}
+		transfer.Kind = TransferRefill
+		transfer.FromAddress = t.Signer.address.Hex()
+		send = func() (string, error) {
+			return sendToken(ctx, t.Signer, &token, hot, amount)
+		}
+
+	case policy.SweepAbove != nil && balance.Cmp(policy.SweepAbove) > 0:
+		if sweepTo == (common.Address{}) {
+			return errors.New("balance is above the sweep mark but there is no treasury address to sweep to")
+		}
+		amount := new(big.Int).Sub(balance, policy.HighWater)
+		transfer.Kind = TransferSweep
+		transfer.FromAddress = hot.Hex()
+		transfer.ToAddress = sweepTo.Hex()
+		transfer.AmountBaseUnits = amount.String()
+		send = func() (string, error) {
+			return sendToken(ctx, t.Wallet, &token, sweepTo, amount)
+		}
+
+	default:
+		return nil
+	}
+
+	// Once recorded, the transfer is sent and its tx hash saved even if
+	// shutdown starts, so it isn't left pending without one. send reads ctx.
+	ctx = context.WithoutCancel(ctx)
+	db := t.DB.WithContext(ctx)
+	if err := db.Create(&transfer).Error; err != nil {
+		return fmt.Errorf("failed to record %s: %w", transfer.Kind, err)
+	}
+
+	txHash, err := send()
+	if err != nil {
+		metrics.TreasuryTransfers.WithLabelValues(token.ID, transfer.Kind, "failed").Inc()
+		db.Model(&transfer).Updates(map[string]interface{}{
+			"status": "failed",
+			"error":  err.Error(),
+		})
+		return fmt.Errorf("failed to send %s: %w", transfer.Kind, err)
+	}
+
+	metrics.TreasuryTransfers.WithLabelValues(token.ID, transfer.Kind, "sent").Inc()
+	slog.Info("treasury transfer sent",
+		"token", token.ID,
+		"kind", transfer.Kind,
+		"balance", balance.String(),
+		"amount_base_units", transfer.AmountBaseUnits,
+		"to", transfer.ToAddress,
+		"tx_hash", txHash,
+	)
+	return db.Model(&transfer).Update("tx_hash", txHash).Error
+}
+
+// settle records the outcome of every pending transfer whose receipt is
+// available. A transfer unmined after ReceiptTimeout stays pending while its
+// sender still has transactions queued, since it may yet be mined, and is
+// failed as dropped once nothing is queued and it still has no receipt.
+func (t *Treasury) settle(ctx context.Context) error {
+	db := t.DB.WithContext(ctx)
+
+	var transfers []models.TreasuryTransfer
+	if err := db.Where("status = ?", "pending").Find(&transfers).Error; err != nil {
+		return fmt.Errorf("failed to load pending transfers: %w", err)
+	}
+
+	timeout := t.ReceiptTimeout
+	if timeout == 0 {
+		timeout = 30 * time.Minute
+	}
+
+	for _, transfer := range transfers {
+		if t.recordReceipt(ctx, &transfer) || time.Since(transfer.CreatedAt) <= timeout {
+			continue
+		}
+
+		queued, err := t.senderQueued(ctx, common.HexToAddress(transfer.FromAddress))
+		if err != nil {
+			slog.Warn("failed to check treasury transfer sender", "token", transfer.TokenID, "kind", transfer.Kind, "error", err)
+			continue
+		}
+		// The receipt may have landed since it was first checked
+		if queued || t.recordReceipt(ctx, &transfer) {
+			if queued {
+				slog.Warn("treasury transfer not mined yet", "token", transfer.TokenID, "kind", transfer.Kind, "tx_hash", transfer.TxHash, "waited", time.Since(transfer.CreatedAt))
+			}
+			continue
+		}
+
+		db.Model(&transfer).Updates(map[string]interface{}{
+			"status": "failed",
+			"error":  fmt.Sprintf("dropped: not mined within %s and no longer queued", timeout),
+		})
+		metrics.TreasuryTransfers.WithLabelValues(transfer.TokenID, transfer.Kind, "timeout").Inc()
+		slog.Warn("treasury transfer dropped", "token", transfer.TokenID, "kind", transfer.Kind, "tx_hash", transfer.TxHash)
+	}
+	return nil
+}
+
+// recordReceipt settles the transfer if its receipt is available
+func (t *Treasury) recordReceipt(ctx context.Context, transfer *models.TreasuryTransfer) bool {
+	if transfer.TxHash == "" {
+		return false
+	}
+
+	rpcCtx, done := traceRPC(ctx, "eth_getTransactionReceipt")
+	receipt, err := t.Wallet.client.TransactionReceipt(rpcCtx, common.HexToHash(transfer.TxHash))
+	done(err)
+	if err != nil {
+		return false
+	}
+
+	status := "completed"
+	if receipt.Status == 0 {
+		status = "failed"
+	}
+	updates := map[string]interface{}{
+		"status":       status,
+		"completed_at": time.Now(),
+		"block_number": receipt.BlockNumber.Uint64(),
+	}
+	if receipt.EffectiveGasPrice != nil {
+		fee := new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), receipt.EffectiveGasPrice)
+		updates["gas_cost"] = fee.String()
+	}
+	t.DB.WithContext(ctx).Model(transfer).Updates(updates)
+
+	metrics.TreasuryTransfers.WithLabelValues(transfer.TokenID, transfer.Kind, status).Inc()
+	slog.Info("treasury transfer settled", "token", transfer.TokenID, "kind", transfer.Kind, "status", status, "tx_hash", transfer.TxHash)
+	return true
+}
+
+// senderQueued reports whether the node holds unmined transactions from
+// sender, that is its pending nonce is ahead of its mined one
+func (t *Treasury) senderQueued(ctx context.Context, sender common.Address) (bool, error) {
+	rpcCtx, done := traceRPC(ctx, "eth_getTransactionCount")
+	mined, err := t.Wallet.client.NonceAt(rpcCtx, sender, nil)
+	done(err)
+	if err != nil {
+		return false, err
+	}
+
+	rpcCtx, done = traceRPC(ctx, "eth_getTransactionCount")
+	pending, err := t.Wallet.client.PendingNonceAt(rpcCtx, sender)
+	done(err)
+	if err != nil {
+		return false, err
+	}
+	return pending > mined, nil
+}
+
+// sendToken sends amount of token from wallet to to
+func sendToken(ctx context.Context, wallet *Wallet, token *models.Token, to common.Address, amount *big.Int) (string, error) {
+	if token.Address == "" {
+		return wallet.SendTransaction(ctx, to, amount)
+	}
+	return wallet.SendERC20(ctx, common.HexToAddress(token.Address), to, amount)
+}
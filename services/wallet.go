@@ -1,114 +1,155 @@
-
 package services
 
 import (
 	"context"
 	"crypto/ecdsa"
-	"log"
+	"fmt"
+	"log/slog"
 	"math/big"
-	"os"
+	"net/http"
 	"sync"
 
+	"faucet-backend/metrics"
+	"faucet-backend/units"
+
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
-var (
-	client       *ethclient.Client
+// ChainClient is the subset of *ethclient.Client the faucet uses
+type ChainClient interface {
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+	ChainID(ctx context.Context) (*big.Int, error)
+	NetworkID(ctx context.Context) (*big.Int, error)
+	SyncProgress(ctx context.Context) (*ethereum.SyncProgress, error)
+	Close()
+}
+
+// Wallet is the faucet's signing account on one chain
+type Wallet struct {
+	client       ChainClient
 	privateKey   *ecdsa.PrivateKey
-	publicKey    *ecdsa.PublicKey
 	address      common.Address
 	nonceMutex   sync.Mutex
 	currentNonce *uint64
-)
-
-func InitWallet() {
-	var err error
-
-	// Connect to RPC
-	rpcURL := os.Getenv("RPC_URL")
-	if rpcURL == "" {
-		log.Fatal("RPC_URL not set")
-	}
+}
 
-	client, err = ethclient.Dial(rpcURL)
+// NewWallet connects to rpcURL and loads the hex-encoded private key
+func NewWallet(ctx context.Context, rpcURL, privateKeyHex string) (*Wallet, error) {
+	// Propagate trace context to the node over HTTP
+	httpClient := &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+	rpcClient, err := rpc.DialOptions(ctx, rpcURL, rpc.WithHTTPClient(httpClient))
 	if err != nil {
-		log.Fatalf("Failed to connect to Ethereum client: %v", err)
+		return nil, fmt.Errorf("failed to connect to Ethereum client: %w", err)
 	}
 
-	// Load private key
-	privateKeyHex := os.Getenv("FAUCET_PRIVATE_KEY")
-	if privateKeyHex == "" {
-		log.Fatal("FAUCET_PRIVATE_KEY not set")
+	w, err := NewWalletWithClient(ctx, ethclient.NewClient(rpcClient), privateKeyHex)
+	if err != nil {
+		rpcClient.Close()
+		return nil, err
 	}
+	return w, nil
+}
 
+// NewWalletWithClient loads the hex-encoded private key and signs through an
+// already connected client
+func NewWalletWithClient(ctx context.Context, client ChainClient, privateKeyHex string) (*Wallet, error) {
 	// Remove 0x prefix if present
 	if len(privateKeyHex) > 2 && privateKeyHex[:2] == "0x" {
 		privateKeyHex = privateKeyHex[2:]
 	}
 
-	privateKey, err = crypto.HexToECDSA(privateKeyHex)
+	privateKey, err := crypto.HexToECDSA(privateKeyHex)
 	if err != nil {
-		log.Fatalf("Invalid private key: %v", err)
+		return nil, fmt.Errorf("invalid private key: %w", err)
 	}
 
-	publicKey = privateKey.Public().(*ecdsa.PublicKey)
-	address = crypto.PubkeyToAddress(*publicKey)
+	w := &Wallet{
+		client:     client,
+		privateKey: privateKey,
+		address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+	}
 
-	log.Printf("✅ Faucet wallet initialized: %s", address.Hex())
+	slog.Info("faucet wallet initialized", "address", w.address.Hex())
 
 	// Check balance
-	balance, err := GetFaucetBalance()
+	balance, err := w.FaucetBalance(ctx)
 	if err == nil {
-		log.Printf("💰 Current balance: %s ETH", balance)
+		slog.Info("faucet wallet balance", "token", "eth", "balance", balance)
 	}
+
+	return w, nil
+}
+
+// Close disconnects from the RPC node
+func (w *Wallet) Close() {
+	w.client.Close()
 }
 
-func GetWalletAddress() string {
-	return address.Hex()
+func (w *Wallet) Address() string {
+	return w.address.Hex()
 }
 
-func GetFaucetBalance() (string, error) {
-	ctx := context.Background()
-	balance, err := client.BalanceAt(ctx, address, nil)
+func (w *Wallet) FaucetBalance(ctx context.Context) (string, error) {
+	balance, err := w.NativeBalance(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	// Convert to ETH
-	balanceFloat := new(big.Float).SetInt(balance)
-	ethValue := new(big.Float).Quo(balanceFloat, big.NewFloat(1e18))
-
-	return ethValue.Text('f', 6), nil
+	return units.Format(balance, 18), nil
 }
 
-func GetNextNonce() (uint64, error) {
-	nonceMutex.Lock()
-	defer nonceMutex.Unlock()
+func (w *Wallet) NativeBalance(ctx context.Context) (*big.Int, error) {
+	ctx, done := traceRPC(ctx, "eth_getBalance")
+	balance, err := w.client.BalanceAt(ctx, w.address, nil)
+	done(err)
+	return balance, err
+}
 
-	ctx := context.Background()
+func (w *Wallet) NextNonce(ctx context.Context) (uint64, error) {
+	w.nonceMutex.Lock()
+	defer w.nonceMutex.Unlock()
 
-	if currentNonce == nil {
-		nonce, err := client.PendingNonceAt(ctx, address)
+	if w.currentNonce == nil {
+		ctx, done := traceRPC(ctx, "eth_getTransactionCount")
+		nonce, err := w.client.PendingNonceAt(ctx, w.address)
+		done(err)
 		if err != nil {
 			return 0, err
 		}
-		currentNonce = &nonce
+		w.currentNonce = &nonce
 	}
 
-	nonce := *currentNonce
-	*currentNonce++
+	nonce := *w.currentNonce
+	*w.currentNonce++
+	metrics.WalletNonce.Set(float64(*w.currentNonce))
 
 	return nonce, nil
 }
 
-func SendTransaction(to common.Address, amount *big.Int) (string, error) {
-	ctx := context.Background()
+// ResetNonce forgets the local nonce so the next transaction reads the
+// pending nonce from the node again
+func (w *Wallet) ResetNonce() {
+	w.nonceMutex.Lock()
+	defer w.nonceMutex.Unlock()
+	w.currentNonce = nil
+}
 
-	nonce, err := GetNextNonce()
+func (w *Wallet) SendTransaction(ctx context.Context, to common.Address, amount *big.Int) (string, error) {
+	nonce, err := w.NextNonce(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -116,48 +157,53 @@ func SendTransaction(to common.Address, amount *big.Int) (string, error) {
 	gasLimit := uint64(21000)
 
 	// Get gas price
-	gasPrice, err := client.SuggestGasPrice(ctx)
+	gasCtx, done := traceRPC(ctx, "eth_gasPrice")
+	gasPrice, err := w.client.SuggestGasPrice(gasCtx)
+	done(err)
 	if err != nil {
 		return "", err
 	}
 
 	// Get chain ID
-	chainID, err := client.NetworkID(ctx)
+	chainCtx, done := traceRPC(ctx, "net_version")
+	chainID, err := w.client.NetworkID(chainCtx)
+	done(err)
 	if err != nil {
 		return "", err
 	}
 
 	tx := types.NewTransaction(nonce, to, amount, gasLimit, gasPrice, nil)
 
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), privateKey)
+	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), w.privateKey)
 	if err != nil {
 		return "", err
 	}
 
-	err = client.SendTransaction(ctx, signedTx)
+	sendCtx, done := traceRPC(ctx, "eth_sendRawTransaction")
+	err = w.client.SendTransaction(sendCtx, signedTx)
+	done(err)
 	if err != nil {
 		// Rollback nonce on failure
-		nonceMutex.Lock()
-		if currentNonce != nil && *currentNonce > 0 {
-			*currentNonce--
+		w.nonceMutex.Lock()
+		if w.currentNonce != nil && *w.currentNonce > 0 {
+			*w.currentNonce--
+			metrics.WalletNonce.Set(float64(*w.currentNonce))
 		}
-		nonceMutex.Unlock()
+		w.nonceMutex.Unlock()
 		return "", err
 	}
 
 	return signedTx.Hash().Hex(), nil
 }
 
-func GetERC20Balance(tokenAddress common.Address) (*big.Int, error) {
-	ctx := context.Background()
-
+func (w *Wallet) ERC20Balance(ctx context.Context, tokenAddress common.Address) (*big.Int, error) {
 	// balanceOf(address) function signature
 	balanceOfSignature := []byte("balanceOf(address)")
 	hash := crypto.Keccak256Hash(balanceOfSignature)
 	methodID := hash[:4]
 
 	// Pad address to 32 bytes
-	paddedAddress := common.LeftPadBytes(address.Bytes(), 32)
+	paddedAddress := common.LeftPadBytes(w.address.Bytes(), 32)
 
 	// Combine method ID and padded address
 	data := append(methodID, paddedAddress...)
@@ -168,7 +214,9 @@ func GetERC20Balance(tokenAddress common.Address) (*big.Int, error) {
 		Data: data,
 	}
 
-	result, err := client.CallContract(ctx, msg, nil)
+	ctx, done := traceRPC(ctx, "eth_call")
+	result, err := w.client.CallContract(ctx, msg, nil)
+	done(err)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,92 @@
+package units
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// MaxDecimals is the most decimals a uint256 token amount can use
+const MaxDecimals = 77
+
+var ErrInvalidAmount = errors.New("invalid amount")
+
+var maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// Parse converts a decimal string such as "0.1" to base units with the given
+// decimals, exactly. It accepts plain non-negative decimals only: no sign,
+// exponent or thousands separators, and no more significant fractional
+// digits than decimals allows.
+func Parse(amount string, decimals int) (*big.Int, error) {
+	if decimals < 0 || decimals > MaxDecimals {
+		return nil, fmt.Errorf("%w: unsupported decimals %d", ErrInvalidAmount, decimals)
+	}
+
+	whole, fraction, hasPoint := strings.Cut(amount, ".")
+	if !isDigits(whole) || (hasPoint && !isDigits(fraction)) {
+		return nil, fmt.Errorf("%w: %q is not a decimal number", ErrInvalidAmount, amount)
+	}
+
+	// Zeros past the token's precision don't change the value
+	trimmed := strings.TrimRight(fraction, "0")
+	if len(trimmed) > decimals {
+		return nil, fmt.Errorf("%w: %q has more than %d decimal places", ErrInvalidAmount, amount, decimals)
+	}
+
+	digits := whole + trimmed + strings.Repeat("0", decimals-len(trimmed))
+	value, _ := new(big.Int).SetString(digits, 10)
+	if value.Cmp(maxUint256) > 0 {
+		return nil, fmt.Errorf("%w: %q overflows uint256", ErrInvalidAmount, amount)
+	}
+	return value, nil
+}
+
+// Format renders base units as an exact decimal string with trailing
+// fractional zeros removed, e.g. 1500000 with 6 decimals is "1.5"
+func Format(value *big.Int, decimals int) string {
+	if value == nil {
+		return "0"
+	}
+
+	sign := ""
+	digits := value.String()
+	if value.Sign() < 0 {
+		sign = "-"
+		digits = digits[1:]
+	}
+	if decimals <= 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	whole := digits[:len(digits)-decimals]
+	fraction := strings.TrimRight(digits[len(digits)-decimals:], "0")
+
+	if fraction == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + fraction
+}
+
+// Float64 converts base units to whole tokens as the nearest float64, for
+// metrics and other places that don't need exact values
+func Float64(value *big.Int, decimals int) float64 {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	f, _ := new(big.Rat).SetFrac(value, scale).Float64()
+	return f
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
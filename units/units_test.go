@@ -0,0 +1,123 @@
+package units
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals int
+		want     string
+	}{
+		{"0.1", 18, "100000000000000000"},
+		{"1", 18, "1000000000000000000"},
+		{"1.5", 6, "1500000"},
+		{"0.000001", 6, "1"},
+		{"007", 2, "700"},
+		{"1.500", 2, "150"},
+		{"1.000000", 0, "1"},
+		{"42", 0, "42"},
+		{"0", 18, "0"},
+		{"0.0", 18, "0"},
+		{"1" + strings.Repeat("0", 59), 18, "1" + strings.Repeat("0", 77)},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.amount, tt.decimals)
+		if err != nil {
+			t.Errorf("Parse(%q, %d): %v", tt.amount, tt.decimals, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("Parse(%q, %d) = %s, want %s", tt.amount, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals int
+	}{
+		{"", 18},
+		{".", 18},
+		{".5", 18},
+		{"1.", 18},
+		{"-1", 18},
+		{"+1", 18},
+		{"1e18", 18},
+		{"1E3", 0},
+		{"1,000", 18},
+		{" 1", 18},
+		{"0x10", 18},
+		{"1.2.3", 18},
+		{"0.1234567", 6},
+		{"0.5", 0},
+		{"1.0000000000000000001", 18},
+		{"1", -1},
+		{"1", MaxDecimals + 1},
+		{"1" + strings.Repeat("0", 78), 0},
+	}
+	for _, tt := range tests {
+		if got, err := Parse(tt.amount, tt.decimals); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("Parse(%q, %d) = %v, %v, want ErrInvalidAmount", tt.amount, tt.decimals, got, err)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		value    string
+		decimals int
+		want     string
+	}{
+		{"100000000000000000", 18, "0.1"},
+		{"1000000000000000000", 18, "1"},
+		{"1500000", 6, "1.5"},
+		{"1", 6, "0.000001"},
+		{"1", 18, "0.000000000000000001"},
+		{"333333333333333333", 18, "0.333333333333333333"},
+		{"0", 18, "0"},
+		{"42", 0, "42"},
+		{"-1500000", 6, "-1.5"},
+		{"1230000", 2, "12300"},
+	}
+	for _, tt := range tests {
+		value, _ := new(big.Int).SetString(tt.value, 10)
+		if got := Format(value, tt.decimals); got != tt.want {
+			t.Errorf("Format(%s, %d) = %q, want %q", tt.value, tt.decimals, got, tt.want)
+		}
+	}
+
+	if got := Format(nil, 18); got != "0" {
+		t.Errorf("Format(nil, 18) = %q, want 0", got)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals int
+	}{
+		{"0.1", 18},
+		{"1", 18},
+		{"1000000", 18},
+		{"0.000000000000000001", 18},
+		{"123456.789", 6},
+		{"42", 0},
+		{"0", 18},
+	}
+	for _, tt := range tests {
+		value, err := Parse(tt.amount, tt.decimals)
+		if err != nil {
+			t.Errorf("Parse(%q, %d): %v", tt.amount, tt.decimals, err)
+			continue
+		}
+		if got := Format(value, tt.decimals); got != tt.amount {
+			t.Errorf("Format(Parse(%q, %d)) = %q", tt.amount, tt.decimals, got)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package webhooks
+
+import (
+	"context"
+
+	"faucet-backend/events"
+	"faucet-backend/notify"
+)
+
+// AlertNotifier turns critical balance alerts into balance.low events, so
+// partners subscribed to them hear about a paused token
+type AlertNotifier struct {
+	Events *events.Bus
+}
+
+func (n AlertNotifier) Notify(ctx context.Context, alert notify.Alert) error {
+	if alert.Level != notify.LevelCritical || alert.TokenID == "" {
+		return nil
+	}
+
+	n.Events.Publish(ctx, events.Event{
+		Type:    events.BalanceLow,
+		TokenID: alert.TokenID,
+		Time:    alert.Time,
+		Data:    alert,
+	})
+	return nil
+}
@@ -0,0 +1,295 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"faucet-backend/events"
+	"faucet-backend/models"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"gorm.io/gorm"
+)
+
+const (
+	SignatureHeader = "X-Faucet-Signature"
+	EventHeader     = "X-Faucet-Event"
+	DeliveryHeader  = "X-Faucet-Delivery"
+
+	maxAttempts  = 8
+	baseBackoff  = 30 * time.Second
+	maxBackoff   = 6 * time.Hour
+	pollInterval = 5 * time.Second
+	batchSize    = 20
+)
+
+var client = &http.Client{
+	Timeout:   10 * time.Second,
+	Transport: otelhttp.NewTransport(http.DefaultTransport),
+}
+
+// Dispatcher queues events for subscribed endpoints and delivers them
+type Dispatcher struct {
+	DB *gorm.DB
+
+	// running tracks the worker started by Start
+	running sync.WaitGroup
+}
+
+// Start subscribes to faucet events and runs the delivery worker until ctx
+// is cancelled
+func (d *Dispatcher) Start(ctx context.Context, bus *events.Bus) {
+	bus.Subscribe(d.Enqueue)
+
+	d.running.Add(1)
+	go func() {
+		defer d.running.Done()
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for {
+			d.deliverDue(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Wait waits, until ctx is done, for the worker started by Start to stop
+// after its ctx is cancelled, including any delivery it is sending
+func (d *Dispatcher) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		d.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("webhook deliveries still sending at shutdown: %w", ctx.Err())
+	}
+}
+
+// Enqueue persists a delivery for every active endpoint subscribed to event
+func (d *Dispatcher) Enqueue(ctx context.Context, event events.Event) {
+	var endpoints []models.WebhookEndpoint
+	if err := d.DB.WithContext(ctx).Where("is_active = true").Find(&endpoints).Error; err != nil {
+		slog.Error("failed to load webhook endpoints", "event", event.Type, "error", err)
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("failed to encode webhook payload", "event", event.Type, "error", err)
+		return
+	}
+
+	for _, endpoint := range endpoints {
+		if !matches(endpoint.Events, event.Type) || !matches(endpoint.TokenIDs, event.TokenID) {
+			continue
+		}
+
+		delivery := models.WebhookDelivery{
+			EndpointID:    endpoint.ID,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        "pending",
+			NextAttemptAt: time.Now(),
+		}
+		if err := d.DB.WithContext(ctx).Create(&delivery).Error; err != nil {
+			slog.Error("failed to queue webhook delivery", "endpoint_id", endpoint.ID, "event", event.Type, "error", err)
+		}
+	}
+}
+
+// Replay requeues a failed delivery for immediate retry
+func (d *Dispatcher) Replay(ctx context.Context, deliveryID uint) error {
+	result := d.DB.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ?", deliveryID, "failed").
+		Updates(map[string]interface{}{
+			"status":          "pending",
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("delivery %d not found or not failed", deliveryID)
+	}
+	return nil
+}
+
+// ReplayFailed requeues every failed delivery, optionally for one endpoint
+func (d *Dispatcher) ReplayFailed(ctx context.Context, endpointID uint) (int64, error) {
+	query := d.DB.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("status = ?", "failed")
+	if endpointID != 0 {
+		query = query.Where("endpoint_id = ?", endpointID)
+	}
+
+	result := query.Updates(map[string]interface{}{
+		"status":          "pending",
+		"attempts":        0,
+		"next_attempt_at": time.Now(),
+	})
+	return result.RowsAffected, result.Error
+}
+
+// Sign computes the signature header value for body: "t=<unix>,v1=<hex>"
+// where v1 is HMAC-SHA256 over "<unix>.<body>" keyed with the endpoint secret
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%s,v1=%s", ts, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	var due []models.WebhookDelivery
+	if err := d.DB.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", "pending", time.Now()).
+		Order("next_attempt_at").
+		Limit(batchSize).
+		Find(&due).Error; err != nil {
+		slog.Error("failed to load due webhook deliveries", "error", err)
+		return
+	}
+
+	for _, delivery := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		// Claim the delivery so concurrent replicas don't send it twice
+		claim := d.DB.WithContext(ctx).Model(&models.WebhookDelivery{}).
+			Where("id = ? AND status = ? AND attempts = ?", delivery.ID, "pending", delivery.Attempts).
+			Updates(map[string]interface{}{
+				"attempts":        delivery.Attempts + 1,
+				"next_attempt_at": time.Now().Add(backoff(delivery.Attempts + 1)),
+			})
+		if claim.Error != nil || claim.RowsAffected == 0 {
+			continue
+		}
+		delivery.Attempts++
+
+		// A claimed delivery is sent and recorded even if shutdown starts;
+		// the client timeout bounds how long that takes
+		d.deliver(context.WithoutCancel(ctx), &delivery)
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	var endpoint models.WebhookEndpoint
+	if err := d.DB.WithContext(ctx).First(&endpoint, delivery.EndpointID).Error; err != nil {
+		d.DB.WithContext(ctx).Model(delivery).Updates(map[string]interface{}{
+			"status":     "failed",
+			"last_error": "endpoint not found",
+		})
+		return
+	}
+
+	start := time.Now()
+	code, err := post(ctx, &endpoint, delivery)
+	d.DB.WithContext(ctx).Create(&models.WebhookAttempt{
+		DeliveryID:   delivery.ID,
+		ResponseCode: code,
+		Error:        errString(err),
+		DurationMs:   time.Since(start).Milliseconds(),
+	})
+
+	logger := slog.With("delivery_id", delivery.ID, "endpoint_id", endpoint.ID, "event", delivery.EventType, "attempt", delivery.Attempts)
+
+	if err == nil {
+		now := time.Now()
+		d.DB.WithContext(ctx).Model(delivery).Updates(map[string]interface{}{
+			"status":       "delivered",
+			"delivered_at": now,
+			"last_error":   "",
+		})
+		logger.Info("webhook delivered", "status_code", code)
+		return
+	}
+
+	updates := map[string]interface{}{"last_error": err.Error()}
+	if delivery.Attempts >= maxAttempts {
+		updates["status"] = "failed"
+		logger.Error("webhook delivery failed permanently", "error", err)
+	} else {
+		logger.Warn("webhook delivery failed, will retry", "error", err)
+	}
+	d.DB.WithContext(ctx).Model(delivery).Updates(updates)
+}
+
+func post(ctx context.Context, endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "faucet-webhooks/1")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, time.Now(), body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff is the delay before the attempt after the given one: 30s, 1m, 2m...
+func backoff(attempt int) time.Duration {
+	delay := baseBackoff << (attempt - 1)
+	if delay > maxBackoff || delay <= 0 {
+		return maxBackoff
+	}
+	return delay
+}
+
+// matches reports whether value is in a comma-separated filter; an empty
+// filter matches everything
+func matches(filter, value string) bool {
+	if filter == "" {
+		return true
+	}
+	for _, item := range strings.Split(filter, ",") {
+		if strings.TrimSpace(item) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}